@@ -0,0 +1,125 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AllocationPolicy names the algorithm used to select a preferred set of devices.
+type AllocationPolicy string
+
+// Constants representing the supported allocation policies.
+const (
+	// AllocationPolicyBestEffort selects NVLink-aligned full GPUs where
+	// possible and spreads replicas across GPUs otherwise.
+	AllocationPolicyBestEffort = AllocationPolicy("best-effort")
+	// AllocationPolicyPack fills the replicas of one GPU before moving on to
+	// the next, keeping as many GPUs as possible free.
+	AllocationPolicyPack = AllocationPolicy("pack")
+	// AllocationPolicySpread distributes replicas evenly across GPUs.
+	AllocationPolicySpread = AllocationPolicy("spread")
+	// AllocationPolicyNUMAFirst keeps a multi-device request within a single
+	// NUMA node where possible.
+	AllocationPolicyNUMAFirst = AllocationPolicy("numa-first")
+	// AllocationPolicySimple selects the devices with the lowest indices.
+	AllocationPolicySimple = AllocationPolicy("simple")
+)
+
+// AllocationPolicies configures the allocation policy per resource.
+type AllocationPolicies struct {
+	// Default is the policy applied to resources without an explicit policy.
+	// If unset, the best-effort policy is used.
+	Default AllocationPolicy `json:"default,omitempty"   yaml:"default,omitempty"`
+	// Resources maps fully-qualified resource names (after any renaming) to
+	// the policy used for that resource.
+	Resources map[ResourceName]AllocationPolicy `json:"resources,omitempty" yaml:"resources,omitempty"`
+	// AlignReplicas indicates that the best-effort policy should select the
+	// replicas of a multi-replica request from distinct, NVLink-connected GPUs
+	// where possible. This only applies to time-sliced resources for which
+	// failRequestsGreaterThanOne is false.
+	AlignReplicas bool `json:"alignReplicas,omitempty" yaml:"alignReplicas,omitempty"`
+}
+
+// UnmarshalJSON unmarshals raw bytes into an 'AllocationPolicies' struct.
+// The resource names used as keys are not unmarshalled as ResourceNames and
+// are therefore checked for templates here.
+func (a *AllocationPolicies) UnmarshalJSON(b []byte) error {
+	type allocationPolicies AllocationPolicies
+	var policies allocationPolicies
+	if err := json.Unmarshal(b, &policies); err != nil {
+		return err
+	}
+	for name := range policies.Resources {
+		if err := name.assertNotTemplate(); err != nil {
+			return err
+		}
+	}
+	*a = AllocationPolicies(policies)
+	return nil
+}
+
+// ForResource returns the allocation policy configured for the specified resource.
+func (a *AllocationPolicies) ForResource(name ResourceName) AllocationPolicy {
+	if a == nil {
+		return AllocationPolicyBestEffort
+	}
+	if p, exists := a.Resources[name]; exists && p != "" {
+		return p
+	}
+	if a.Default != "" {
+		return a.Default
+	}
+	return AllocationPolicyBestEffort
+}
+
+// ReplicaAlignmentEnabled returns whether replicas should be aligned to the
+// topology of the underlying GPUs.
+func (a *AllocationPolicies) ReplicaAlignmentEnabled() bool {
+	if a == nil {
+		return false
+	}
+	return a.AlignReplicas
+}
+
+// IsValid checks whether the allocation policy is a known policy.
+func (p AllocationPolicy) IsValid() bool {
+	switch p {
+	case AllocationPolicyBestEffort,
+		AllocationPolicyPack,
+		AllocationPolicySpread,
+		AllocationPolicyNUMAFirst,
+		AllocationPolicySimple:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON unmarshals raw bytes into an 'AllocationPolicy' type.
+func (p *AllocationPolicy) UnmarshalJSON(b []byte) error {
+	var raw string
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	policy := AllocationPolicy(raw)
+	if !policy.IsValid() {
+		return fmt.Errorf("unknown allocation policy: %q", raw)
+	}
+	*p = policy
+	return nil
+}
@@ -0,0 +1,94 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocationPolicies(t *testing.T) {
+	testCases := []struct {
+		description string
+		input       string
+		expectedErr bool
+		expected    map[ResourceName]AllocationPolicy
+		// expectedAlignReplicas is the expected replica alignment setting.
+		expectedAlignReplicas bool
+	}{
+		{
+			description: "no allocation policy",
+			input:       `version: v1`,
+			expected: map[ResourceName]AllocationPolicy{
+				"nvidia.com/gpu": AllocationPolicyBestEffort,
+			},
+		},
+		{
+			description: "default and per-resource policies",
+			input: `
+version: v1
+allocationPolicy:
+  default: spread
+  resources:
+    nvidia.com/gpu.shared: pack
+`,
+			expected: map[ResourceName]AllocationPolicy{
+				"nvidia.com/gpu":        AllocationPolicySpread,
+				"nvidia.com/gpu.shared": AllocationPolicyPack,
+			},
+		},
+		{
+			description: "replica alignment",
+			input: `
+version: v1
+allocationPolicy:
+  alignReplicas: true
+`,
+			expected: map[ResourceName]AllocationPolicy{
+				"nvidia.com/gpu": AllocationPolicyBestEffort,
+			},
+			expectedAlignReplicas: true,
+		},
+		{
+			description: "unknown policy",
+			input: `
+version: v1
+allocationPolicy:
+  resources:
+    nvidia.com/gpu: fastest
+`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			config, err := parseConfigFrom(strings.NewReader(tc.input))
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			for name, policy := range tc.expected {
+				require.Equal(t, policy, config.AllocationPolicy.ForResource(name))
+			}
+			require.Equal(t, tc.expectedAlignReplicas, config.AllocationPolicy.ReplicaAlignmentEnabled())
+		})
+	}
+}
@@ -22,7 +22,6 @@ import (
 	"os"
 
 	cli "github.com/urfave/cli/v2"
-	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/yaml"
 )
@@ -37,6 +36,12 @@ type Config struct {
 	Resources Resources `json:"resources,omitempty" yaml:"resources,omitempty"`
 	Sharing   Sharing   `json:"sharing,omitempty"   yaml:"sharing,omitempty"`
 	Imex      Imex      `json:"imex,omitempty"      yaml:"imex,omitempty"`
+	// AllocationPolicy configures the algorithm used by GetPreferredAllocation per resource.
+	AllocationPolicy *AllocationPolicies `json:"allocationPolicy,omitempty" yaml:"allocationPolicy,omitempty"`
+	// MemoryResource configures advertising GPU memory as a countable resource.
+	MemoryResource *MemoryResource `json:"memoryResource,omitempty" yaml:"memoryResource,omitempty"`
+	// ContainerEdits defines additional edits per resource for containers that are allocated devices.
+	ContainerEdits []ContainerEdits `json:"containerEdits,omitempty" yaml:"containerEdits,omitempty"`
 }
 
 // GetResourceNamePrefix returns the configured resource name prefix.
@@ -82,7 +87,8 @@ func NewConfig(c *cli.Context, flags []cli.Flag) (*Config, error) {
 	// We explicitly set sharing.mps.failRequestsGreaterThanOne = true
 	// This can be relaxed in certain cases -- such as a single GPU -- but
 	// requires additional logic around when it's OK to combine requests and
-	// makes the semantics of a request unclear.
+	// makes the semantics of a request unclear. Requests for replicas on
+	// distinct GPUs are instead allowed with sharing.mps.allowMultiGPURequests.
 	if config.Sharing.MPS != nil {
 		config.Sharing.MPS.FailRequestsGreaterThanOne = true
 	}
@@ -90,22 +96,6 @@ func NewConfig(c *cli.Context, flags []cli.Flag) (*Config, error) {
 	return config, nil
 }
 
-// DisableResourceNamingInConfig temporarily disable the resource renaming feature of the plugin.
-// This may be reenabled in a future release.
-func DisableResourceNamingInConfig(config *Config) {
-	// Disable resource renaming through config.Resource
-	if len(config.Resources.GPUs) > 0 || len(config.Resources.MIGs) > 0 {
-		klog.Warning("Customizing the 'resources' field is not yet supported in the config. Ignoring...")
-	}
-	config.Resources.GPUs = nil
-	config.Resources.MIGs = nil
-
-	// Disable renaming / device selection in Sharing.TimeSlicing.Resources
-	config.Sharing.TimeSlicing.disableResoureRenaming("timeSlicing")
-	// Disable renaming / device selection in Sharing.MPS.Resources
-	config.Sharing.MPS.disableResoureRenaming("mps")
-}
-
 // parseConfig parses a config file as either YAML of JSON and unmarshals it into a Config struct.
 func parseConfig(configFile string) (*Config, error) {
 	reader, err := os.Open(configFile)
@@ -45,8 +45,9 @@ const (
 
 // Constants to represent the various device id strategies
 const (
-	DeviceIDStrategyUUID  = "uuid"
-	DeviceIDStrategyIndex = "index"
+	DeviceIDStrategyUUID     = "uuid"
+	DeviceIDStrategyIndex    = "index"
+	DeviceIDStrategyPCIBusID = "pci-bus-id"
 )
 
 // Constants related to generating CDI specifications
@@ -0,0 +1,213 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// driverCapabilities lists the supported values of NVIDIA_DRIVER_CAPABILITIES.
+var driverCapabilities = map[string]bool{
+	"all":      true,
+	"compat32": true,
+	"compute":  true,
+	"display":  true,
+	"graphics": true,
+	"ngx":      true,
+	"utility":  true,
+	"video":    true,
+}
+
+var requirementNamePattern = regexp.MustCompile("^[A-Za-z0-9_]+$")
+
+// containerEditsTemplateFuncs are the functions available in container edit
+// templates.
+var containerEditsTemplateFuncs = template.FuncMap{
+	"join":  func(sep string, s []string) string { return strings.Join(s, sep) },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// ContainerEdits defines additional edits that are made to containers that are
+// allocated devices of a resource. All values except driver capabilities are
+// templates that are evaluated against ContainerEditsData.
+type ContainerEdits struct {
+	// Name is the resource that the edits apply to.
+	Name ResourceName `json:"name"                         yaml:"name"`
+	// Envs maps envvar names to values.
+	Envs map[string]string `json:"envs,omitempty"               yaml:"envs,omitempty"`
+	// Annotations maps container annotations to values.
+	Annotations map[string]string `json:"annotations,omitempty"        yaml:"annotations,omitempty"`
+	// Mounts lists additional host paths to mount into the container.
+	Mounts []ContainerMount `json:"mounts,omitempty"             yaml:"mounts,omitempty"`
+	// DriverCapabilities sets NVIDIA_DRIVER_CAPABILITIES.
+	DriverCapabilities []string `json:"driverCapabilities,omitempty" yaml:"driverCapabilities,omitempty"`
+	// Require maps constraints to values and sets NVIDIA_REQUIRE_<constraint>,
+	// e.g. cuda: ">=12.0" sets NVIDIA_REQUIRE_CUDA.
+	Require map[string]string `json:"require,omitempty"            yaml:"require,omitempty"`
+}
+
+// ContainerMount defines a host path that is mounted into a container.
+type ContainerMount struct {
+	HostPath      string `json:"hostPath"           yaml:"hostPath"`
+	ContainerPath string `json:"containerPath"      yaml:"containerPath"`
+	ReadOnly      bool   `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+}
+
+// ContainerEditsData holds the information about an allocation that container
+// edit templates are evaluated against.
+type ContainerEditsData struct {
+	// Resource is the name of the allocated resource.
+	Resource string
+	// DeviceIDs lists the allocated device IDs, including replica
+	// annotations.
+	DeviceIDs []string
+	// UUIDs lists the UUIDs of the allocated GPUs or MIG devices.
+	UUIDs []string
+	// Indices lists the indices of the allocated GPUs or MIG devices.
+	Indices []string
+	// Replicas is the number of replicas each allocated device is split into
+	// or 0 if the devices are not shared.
+	Replicas int
+	// MigProfiles lists the profiles of the allocated MIG devices.
+	MigProfiles []string
+}
+
+// UnmarshalJSON unmarshals raw bytes into a 'ContainerEdits' struct.
+func (e *ContainerEdits) UnmarshalJSON(b []byte) error {
+	type containerEdits ContainerEdits
+	var raw ContainerEdits
+	if err := json.Unmarshal(b, (*containerEdits)(&raw)); err != nil {
+		return err
+	}
+	if raw.Name == "" {
+		return fmt.Errorf("no resource name specified for container edits")
+	}
+	for _, capability := range raw.DriverCapabilities {
+		if !driverCapabilities[capability] {
+			return fmt.Errorf("unknown driver capability: %q", capability)
+		}
+	}
+	for name := range raw.Require {
+		if !requirementNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid requirement name: %q", name)
+		}
+	}
+	for _, mount := range raw.Mounts {
+		if mount.HostPath == "" || mount.ContainerPath == "" {
+			return fmt.Errorf("mounts require both a hostPath and a containerPath")
+		}
+	}
+	for _, value := range raw.templates() {
+		if _, err := newContainerEditsTemplate(value); err != nil {
+			return err
+		}
+	}
+	*e = raw
+	return nil
+}
+
+// templates returns all templated values of the container edits.
+func (e *ContainerEdits) templates() []string {
+	var templates []string
+	for _, values := range []map[string]string{e.Envs, e.Require, e.Annotations} {
+		for _, value := range values {
+			templates = append(templates, value)
+		}
+	}
+	for _, mount := range e.Mounts {
+		templates = append(templates, mount.HostPath, mount.ContainerPath)
+	}
+	return templates
+}
+
+// Evaluate returns the container edits with all templates evaluated for the
+// specified allocation. Driver capabilities and requirements are returned as
+// envvars.
+func (e *ContainerEdits) Evaluate(data ContainerEditsData) (*ContainerEdits, error) {
+	evaluated := &ContainerEdits{
+		Name:        e.Name,
+		Envs:        make(map[string]string),
+		Annotations: make(map[string]string),
+	}
+	for name, value := range e.Envs {
+		v, err := evaluateContainerEditsTemplate(value, data)
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating envvar %v: %w", name, err)
+		}
+		evaluated.Envs[name] = v
+	}
+	if len(e.DriverCapabilities) > 0 {
+		evaluated.Envs["NVIDIA_DRIVER_CAPABILITIES"] = strings.Join(e.DriverCapabilities, ",")
+	}
+	for name, value := range e.Require {
+		v, err := evaluateContainerEditsTemplate(value, data)
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating requirement %v: %w", name, err)
+		}
+		evaluated.Envs["NVIDIA_REQUIRE_"+strings.ToUpper(name)] = v
+	}
+	for name, value := range e.Annotations {
+		v, err := evaluateContainerEditsTemplate(value, data)
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating annotation %v: %w", name, err)
+		}
+		evaluated.Annotations[name] = v
+	}
+	for _, mount := range e.Mounts {
+		hostPath, err := evaluateContainerEditsTemplate(mount.HostPath, data)
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating mount host path: %w", err)
+		}
+		containerPath, err := evaluateContainerEditsTemplate(mount.ContainerPath, data)
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating mount container path: %w", err)
+		}
+		evaluated.Mounts = append(evaluated.Mounts, ContainerMount{
+			HostPath:      hostPath,
+			ContainerPath: containerPath,
+			ReadOnly:      mount.ReadOnly,
+		})
+	}
+	return evaluated, nil
+}
+
+// newContainerEditsTemplate parses a container edit template.
+func newContainerEditsTemplate(value string) (*template.Template, error) {
+	t, err := template.New("container-edits").Funcs(containerEditsTemplateFuncs).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid container edits template %q: %w", value, err)
+	}
+	return t, nil
+}
+
+// evaluateContainerEditsTemplate evaluates a single container edit template.
+func evaluateContainerEditsTemplate(value string, data ContainerEditsData) (string, error) {
+	t, err := newContainerEditsTemplate(value)
+	if err != nil {
+		return "", err
+	}
+	var evaluated strings.Builder
+	if err := t.Execute(&evaluated, data); err != nil {
+		return "", err
+	}
+	return evaluated.String(), nil
+}
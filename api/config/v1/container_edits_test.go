@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerEdits(t *testing.T) {
+	config, err := parseConfigFrom(strings.NewReader(`
+version: v1
+containerEdits:
+- name: gpu.shared
+  envs:
+    CUDA_DEVICE_ORDER: PCI_BUS_ID
+    GPU_UUIDS: '{{ join "," .UUIDs }}'
+  annotations:
+    example.com/replicas: "{{ .Replicas }}"
+  mounts:
+  - hostPath: /opt/models
+    containerPath: "/models/{{ index .Indices 0 }}"
+    readOnly: true
+  driverCapabilities: [compute, utility]
+  require:
+    cuda: ">=12.0"
+`))
+	require.NoError(t, err)
+	require.Len(t, config.ContainerEdits, 1)
+	require.Equal(t, ResourceName("nvidia.com/gpu.shared"), config.ContainerEdits[0].Name)
+
+	evaluated, err := config.ContainerEdits[0].Evaluate(ContainerEditsData{
+		Resource:  "nvidia.com/gpu.shared",
+		DeviceIDs: []string{"GPU-0::1"},
+		UUIDs:     []string{"GPU-0"},
+		Indices:   []string{"0"},
+		Replicas:  4,
+	})
+	require.NoError(t, err)
+	require.Equal(t,
+		map[string]string{
+			"CUDA_DEVICE_ORDER":          "PCI_BUS_ID",
+			"GPU_UUIDS":                  "GPU-0",
+			"NVIDIA_DRIVER_CAPABILITIES": "compute,utility",
+			"NVIDIA_REQUIRE_CUDA":        ">=12.0",
+		},
+		evaluated.Envs,
+	)
+	require.Equal(t, map[string]string{"example.com/replicas": "4"}, evaluated.Annotations)
+	require.Equal(t, []ContainerMount{{HostPath: "/opt/models", ContainerPath: "/models/0", ReadOnly: true}}, evaluated.Mounts)
+}
+
+func TestInvalidContainerEdits(t *testing.T) {
+	testCases := []struct {
+		description string
+		edits       string
+	}{
+		{
+			description: "no resource name",
+			edits:       "envs:\n    FOO: bar",
+		},
+		{
+			description: "unknown driver capability",
+			edits:       "name: gpu\n  driverCapabilities: [compute, gpu]",
+		},
+		{
+			description: "invalid requirement name",
+			edits:       "name: gpu\n  require:\n    cuda-version: \">=12.0\"",
+		},
+		{
+			description: "mount without container path",
+			edits:       "name: gpu\n  mounts:\n  - hostPath: /opt",
+		},
+		{
+			description: "invalid template",
+			edits:       "name: gpu\n  envs:\n    FOO: \"{{ .UUIDs\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := parseConfigFrom(strings.NewReader("version: v1\ncontainerEdits:\n- " + tc.edits + "\n"))
+			require.Error(t, err)
+		})
+	}
+}
@@ -38,6 +38,8 @@ func updateFromCLIFlag[T any](pflag **T, c *cli.Context, flagName string) {
 			*flag = ptr(c.StringSlice(flagName))
 		case **bool:
 			*flag = ptr(c.Bool(flagName))
+		case **int:
+			*flag = ptr(c.Int(flagName))
 		case **Duration:
 			*flag = ptr(Duration(c.Duration(flagName)))
 		case **deviceListStrategyFlag:
@@ -59,6 +61,7 @@ type CommandLineFlags struct {
 	FailOnInitError         *bool                   `json:"failOnInitError"            yaml:"failOnInitError"`
 	ResourceNamePrefix      *string                 `json:"resourceNamePrefix,omitempty" yaml:"resourceNamePrefix,omitempty"`
 	MpsRoot                 *string                 `json:"mpsRoot,omitempty"          yaml:"mpsRoot,omitempty"`
+	ManageMpsDaemons        *bool                   `json:"manageMpsDaemons,omitempty" yaml:"manageMpsDaemons,omitempty"`
 	NvidiaDriverRoot        *string                 `json:"nvidiaDriverRoot,omitempty" yaml:"nvidiaDriverRoot,omitempty"`
 	NvidiaDevRoot           *string                 `json:"nvidiaDevRoot,omitempty"    yaml:"nvidiaDevRoot,omitempty"`
 	GDRCopyEnabled          *bool                   `json:"gdrcopyEnabled"             yaml:"gdrcopyEnabled"`
@@ -78,6 +81,36 @@ type PluginCommandLineFlags struct {
 	CDIAnnotationPrefix *string                 `json:"cdiAnnotationPrefix" yaml:"cdiAnnotationPrefix"`
 	NvidiaCTKPath       *string                 `json:"nvidiaCTKPath"       yaml:"nvidiaCTKPath"`
 	ContainerDriverRoot *string                 `json:"containerDriverRoot" yaml:"containerDriverRoot"`
+	// AuditLog configures the optional log of allocation decisions.
+	AuditLog *AuditLogFlags `json:"auditLog,omitempty" yaml:"auditLog,omitempty"`
+	// NCCLTopology configures the optional generation of NCCL topology files.
+	NCCLTopology *NCCLTopologyFlags `json:"ncclTopology,omitempty" yaml:"ncclTopology,omitempty"`
+}
+
+// NCCLTopologyFlags holds the command line flags used to configure the generation of NCCL topology files.
+type NCCLTopologyFlags struct {
+	// Dir is the directory in which a topology file is written per allocation. The directory must be
+	// mounted at the same path in the plugin container and on the host. An empty path disables the feature.
+	Dir *string `json:"dir"                          yaml:"dir"`
+	// PodResourcesSocket is the kubelet PodResources socket used to determine which topology files
+	// are no longer in use. If empty, topology files are not removed.
+	PodResourcesSocket *string `json:"podResourcesSocket,omitempty" yaml:"podResourcesSocket,omitempty"`
+}
+
+// AuditLogFlags holds the command line flags used to configure the allocation audit log.
+type AuditLogFlags struct {
+	// Path is the file to which audit entries are appended. An empty path disables the audit log.
+	Path *string `json:"path"                         yaml:"path"`
+	// MaxSizeMiB is the size at which the audit log is rotated.
+	MaxSizeMiB *int `json:"maxSizeMiB"                   yaml:"maxSizeMiB"`
+	// MaxBackups is the number of rotated audit logs to retain.
+	MaxBackups *int `json:"maxBackups"                   yaml:"maxBackups"`
+	// RedactEnvvars lists the names of injected envvars whose values are not recorded.
+	// The special value '*' redacts the values of all envvars.
+	RedactEnvvars *[]string `json:"redactEnvvars"                yaml:"redactEnvvars"`
+	// PodResourcesSocket is the kubelet PodResources socket used to resolve the pod that owns
+	// a set of devices. If empty, no pod identity is recorded.
+	PodResourcesSocket *string `json:"podResourcesSocket,omitempty" yaml:"podResourcesSocket,omitempty"`
 }
 
 // deviceListStrategyFlag is a custom type for parsing the deviceListStrategy flag.
@@ -126,6 +159,8 @@ func (f *Flags) UpdateFromCLIFlags(c *cli.Context, flags []cli.Flag) {
 				updateFromCLIFlag(&f.ResourceNamePrefix, c, n)
 			case "mps-root":
 				updateFromCLIFlag(&f.MpsRoot, c, n)
+			case "manage-mps-daemons":
+				updateFromCLIFlag(&f.ManageMpsDaemons, c, n)
 			case "driver-root", "nvidia-driver-root":
 				updateFromCLIFlag(&f.NvidiaDriverRoot, c, n)
 			case "dev-root", "nvidia-dev-root":
@@ -159,6 +194,38 @@ func (f *Flags) UpdateFromCLIFlags(c *cli.Context, flags []cli.Flag) {
 			case "container-driver-root":
 				updateFromCLIFlag(&f.Plugin.ContainerDriverRoot, c, n)
 			}
+			// Plugin audit log flags
+			switch n {
+			case "audit-log", "audit-log-max-size", "audit-log-max-backups", "audit-log-redact-envvars", "pod-resources-socket":
+				if f.Plugin.AuditLog == nil {
+					f.Plugin.AuditLog = &AuditLogFlags{}
+				}
+			}
+			switch n {
+			case "audit-log":
+				updateFromCLIFlag(&f.Plugin.AuditLog.Path, c, n)
+			case "audit-log-max-size":
+				updateFromCLIFlag(&f.Plugin.AuditLog.MaxSizeMiB, c, n)
+			case "audit-log-max-backups":
+				updateFromCLIFlag(&f.Plugin.AuditLog.MaxBackups, c, n)
+			case "audit-log-redact-envvars":
+				updateFromCLIFlag(&f.Plugin.AuditLog.RedactEnvvars, c, n)
+			case "pod-resources-socket":
+				updateFromCLIFlag(&f.Plugin.AuditLog.PodResourcesSocket, c, n)
+			}
+			// Plugin NCCL topology flags
+			switch n {
+			case "nccl-topology-dir", "pod-resources-socket":
+				if f.Plugin.NCCLTopology == nil {
+					f.Plugin.NCCLTopology = &NCCLTopologyFlags{}
+				}
+			}
+			switch n {
+			case "nccl-topology-dir":
+				updateFromCLIFlag(&f.Plugin.NCCLTopology.Dir, c, n)
+			case "pod-resources-socket":
+				updateFromCLIFlag(&f.Plugin.NCCLTopology.PodResourcesSocket, c, n)
+			}
 			// GFD specific flags
 			if f.GFD == nil {
 				f.GFD = &GFDCommandLineFlags{}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// DefaultMemoryResourceName is the name that GPU memory is advertised
+	// under if no name is configured.
+	DefaultMemoryResourceName = "gpumem"
+	// DefaultMemoryUnit is the amount of memory represented by a single
+	// device of the memory resource if no unit is configured.
+	DefaultMemoryUnit = "1Gi"
+)
+
+// MemoryResource configures advertising the memory of full GPUs as a countable
+// resource. Each selected GPU is advertised as TotalMemory / Unit devices
+// instead of a single device.
+type MemoryResource struct {
+	// Name is the resource that memory is advertised under. Defaults to
+	// <prefix>/gpumem.
+	Name ResourceName `json:"name,omitempty"      yaml:"name,omitempty"`
+	// Unit is the amount of memory represented by a single device. Defaults
+	// to 1Gi.
+	Unit *resource.Quantity `json:"unit,omitempty"      yaml:"unit,omitempty"`
+	// Resources lists the GPU resources whose devices are advertised as
+	// memory. If empty, all full GPUs that are not shared are advertised as
+	// memory.
+	Resources []ResourceName `json:"resources,omitempty" yaml:"resources,omitempty"`
+}
+
+// UnmarshalJSON unmarshals raw bytes into a 'MemoryResource' struct.
+func (m *MemoryResource) UnmarshalJSON(b []byte) error {
+	type memoryResource MemoryResource
+	var raw memoryResource
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if raw.Unit != nil && raw.Unit.Sign() <= 0 {
+		return fmt.Errorf("memory resource unit must be > 0")
+	}
+	*m = MemoryResource(raw)
+	return nil
+}
+
+// ResourceName returns the name that memory is advertised under.
+func (m *MemoryResource) ResourceName(prefix string) ResourceName {
+	if m.Name != "" {
+		return m.Name
+	}
+	return ResourceName(prefix + "/" + DefaultMemoryResourceName)
+}
+
+// UnitBytes returns the amount of memory in bytes represented by a single
+// device.
+func (m *MemoryResource) UnitBytes() uint64 {
+	if m.Unit == nil {
+		unit := resource.MustParse(DefaultMemoryUnit)
+		return uint64(unit.Value())
+	}
+	return uint64(m.Unit.Value())
+}
+
+// Includes checks whether the devices of the specified resource are advertised
+// as memory.
+func (m *MemoryResource) Includes(name ResourceName) bool {
+	return len(m.Resources) == 0 || slices.Contains(m.Resources, name)
+}
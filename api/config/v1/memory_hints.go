@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// MemoryHintPreset selects a predefined set of memory hint envvars.
+type MemoryHintPreset string
+
+// These constants represent the supported memory hint presets.
+const (
+	// MemoryHintPresetGeneric sets NVIDIA_GPU_MEMORY_LIMIT_MIB and
+	// NVIDIA_GPU_MEMORY_FRACTION.
+	MemoryHintPresetGeneric = MemoryHintPreset("generic")
+	// MemoryHintPresetPyTorch sets the garbage_collection_threshold of
+	// PYTORCH_CUDA_ALLOC_CONF so that the caching allocator starts releasing
+	// cached memory once the usage exceeds the fraction of the GPU. This does
+	// not cap the memory that PyTorch allocates.
+	MemoryHintPresetPyTorch = MemoryHintPreset("pytorch")
+	// MemoryHintPresetTensorFlow sets TF_FORCE_GPU_ALLOW_GROWTH so that
+	// TensorFlow does not reserve all GPU memory up front. This does not cap
+	// the memory that TensorFlow allocates.
+	MemoryHintPresetTensorFlow = MemoryHintPreset("tensorflow")
+)
+
+// MemoryHints configures envvars that tell applications how much GPU memory
+// they should use. Time-slicing does not isolate memory, so these are hints
+// that applications are expected to respect.
+type MemoryHints struct {
+	// Presets lists predefined sets of envvars to inject.
+	Presets []MemoryHintPreset `json:"presets,omitempty" yaml:"presets,omitempty"`
+	// Envs maps envvar names to templates that are evaluated against
+	// MemoryHintData. These take precedence over the envvars of presets.
+	Envs map[string]string `json:"envs,omitempty"    yaml:"envs,omitempty"`
+}
+
+// MemoryHintData holds the values that memory hint templates are evaluated
+// against.
+type MemoryHintData struct {
+	// MemoryMiB is the memory in MiB that the container should use per GPU.
+	MemoryMiB uint64
+	// Fraction is the fraction of the memory of a GPU that the container
+	// should use, rounded down to two decimals.
+	Fraction string
+	// Replicas is the number of replicas of a GPU that the container was
+	// allocated.
+	Replicas int
+}
+
+// NewMemoryHintData returns the memory hint data for a container that was
+// allocated the specified number of replicas of a GPU with the specified
+// total memory and number of replicas. If replicaMemory is non-zero, each
+// replica has that much memory, e.g. for replica classes. Otherwise, the
+// memory of the GPU is split evenly over all of its replicas.
+func NewMemoryHintData(totalMemory uint64, replicaMemory uint64, replicas int, totalReplicas int) MemoryHintData {
+	if replicaMemory != 0 {
+		memory := replicaMemory * uint64(replicas)
+		fraction := math.Floor(float64(memory)*100/float64(totalMemory)) / 100
+		return MemoryHintData{
+			MemoryMiB: memory / (1024 * 1024),
+			Fraction:  strconv.FormatFloat(fraction, 'f', 2, 64),
+			Replicas:  replicas,
+		}
+	}
+	fraction := math.Floor(float64(replicas)*100/float64(totalReplicas)) / 100
+	return MemoryHintData{
+		MemoryMiB: totalMemory * uint64(replicas) / uint64(totalReplicas) / (1024 * 1024),
+		Fraction:  strconv.FormatFloat(fraction, 'f', 2, 64),
+		Replicas:  replicas,
+	}
+}
+
+// UnmarshalJSON unmarshals raw bytes into a 'MemoryHints' struct.
+func (h *MemoryHints) UnmarshalJSON(b []byte) error {
+	type memoryHints MemoryHints
+	var raw memoryHints
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	for _, preset := range raw.Presets {
+		switch preset {
+		case MemoryHintPresetGeneric, MemoryHintPresetPyTorch, MemoryHintPresetTensorFlow:
+		default:
+			return fmt.Errorf("unknown memory hint preset: %q", preset)
+		}
+	}
+	for name, value := range raw.Envs {
+		if name == "" {
+			return fmt.Errorf("memory hint envvar names must not be empty")
+		}
+		if _, err := newMemoryHintTemplate(value); err != nil {
+			return fmt.Errorf("invalid memory hint for %v: %w", name, err)
+		}
+	}
+	*h = MemoryHints(raw)
+	return nil
+}
+
+// Evaluate returns the envvars for the specified memory hint data.
+func (h *MemoryHints) Evaluate(data MemoryHintData) (map[string]string, error) {
+	envs := make(map[string]string)
+	for _, preset := range h.Presets {
+		switch preset {
+		case MemoryHintPresetGeneric:
+			envs["NVIDIA_GPU_MEMORY_LIMIT_MIB"] = strconv.FormatUint(data.MemoryMiB, 10)
+			envs["NVIDIA_GPU_MEMORY_FRACTION"] = data.Fraction
+		case MemoryHintPresetPyTorch:
+			// PyTorch rejects thresholds outside of (0.0, 1.0).
+			if fraction, _ := strconv.ParseFloat(data.Fraction, 64); fraction > 0 && fraction < 1 {
+				envs["PYTORCH_CUDA_ALLOC_CONF"] = "garbage_collection_threshold:" + data.Fraction
+			}
+		case MemoryHintPresetTensorFlow:
+			envs["TF_FORCE_GPU_ALLOW_GROWTH"] = "true"
+		}
+	}
+	for name, value := range h.Envs {
+		t, err := newMemoryHintTemplate(value)
+		if err != nil {
+			return nil, err
+		}
+		var evaluated strings.Builder
+		if err := t.Execute(&evaluated, data); err != nil {
+			return nil, fmt.Errorf("error evaluating memory hint for %v: %w", name, err)
+		}
+		envs[name] = evaluated.String()
+	}
+	return envs, nil
+}
+
+// newMemoryHintTemplate parses a memory hint template.
+func newMemoryHintTemplate(value string) (*template.Template, error) {
+	return template.New("memory-hint").Option("missingkey=error").Parse(value)
+}
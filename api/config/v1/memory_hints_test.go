@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryHints(t *testing.T) {
+	testCases := []struct {
+		description   string
+		memoryHints   string
+		data          MemoryHintData
+		expectedEnvs  map[string]string
+		expectedError bool
+	}{
+		{
+			description: "tensorflow preset",
+			memoryHints: "presets: [tensorflow]",
+			data:        NewMemoryHintData(40*1024*1024*1024, 0, 1, 4),
+			expectedEnvs: map[string]string{
+				"TF_FORCE_GPU_ALLOW_GROWTH": "true",
+			},
+		},
+		{
+			description: "pytorch preset",
+			memoryHints: "presets: [pytorch]",
+			data:        NewMemoryHintData(40*1024*1024*1024, 0, 1, 4),
+			expectedEnvs: map[string]string{
+				"PYTORCH_CUDA_ALLOC_CONF": "garbage_collection_threshold:0.25",
+			},
+		},
+		{
+			description:  "pytorch preset skips threshold for whole GPU",
+			memoryHints:  "presets: [pytorch]",
+			data:         NewMemoryHintData(40*1024*1024*1024, 0, 4, 4),
+			expectedEnvs: map[string]string{},
+		},
+		{
+			description: "replica memory",
+			memoryHints: "presets: [generic]",
+			data:        NewMemoryHintData(40*1024*1024*1024, 4*1024*1024*1024, 2, 6),
+			expectedEnvs: map[string]string{
+				"NVIDIA_GPU_MEMORY_LIMIT_MIB": "8192",
+				"NVIDIA_GPU_MEMORY_FRACTION":  "0.20",
+			},
+		},
+		{
+			description: "templates override presets",
+			memoryHints: "presets: [generic]\n      envs:\n        NVIDIA_GPU_MEMORY_FRACTION: \"{{ .Fraction }} of {{ .Replicas }}\"",
+			data:        NewMemoryHintData(24*1024*1024*1024, 0, 1, 3),
+			expectedEnvs: map[string]string{
+				"NVIDIA_GPU_MEMORY_LIMIT_MIB": "8192",
+				"NVIDIA_GPU_MEMORY_FRACTION":  "0.33 of 1",
+			},
+		},
+		{
+			description:   "unknown preset",
+			memoryHints:   "presets: [jax]",
+			expectedError: true,
+		},
+		{
+			description:   "invalid template",
+			memoryHints:   "envs:\n        LIMIT: \"{{ .MemoryMiB\"",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			config, err := parseConfigFrom(strings.NewReader(`
+version: v1
+sharing:
+  timeSlicing:
+    resources:
+    - name: nvidia.com/gpu
+      replicas: 4
+    memoryHints:
+      ` + tc.memoryHints + "\n"))
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, config.Sharing.TimeSlicing.MemoryHints)
+
+			envs, err := config.Sharing.TimeSlicing.MemoryHints.Evaluate(tc.data)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedEnvs, envs)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryResource(t *testing.T) {
+	testCases := []struct {
+		description      string
+		config           string
+		expectedName     ResourceName
+		expectedUnit     uint64
+		expectedIncludes map[ResourceName]bool
+		expectedError    bool
+		expectNil        bool
+	}{
+		{
+			description: "no memory resource",
+			config:      "version: v1\n",
+			expectNil:   true,
+		},
+		{
+			description:  "defaults",
+			config:       "version: v1\nmemoryResource: {}\n",
+			expectedName: "nvidia.com/gpumem",
+			expectedUnit: 1024 * 1024 * 1024,
+			expectedIncludes: map[ResourceName]bool{
+				"nvidia.com/gpu": true,
+			},
+		},
+		{
+			description: "custom name, unit, and resources",
+			config: `
+version: v1
+memoryResource:
+  name: memory
+  unit: 512Mi
+  resources: ["nvidia.com/a100"]
+`,
+			expectedName: "nvidia.com/memory",
+			expectedUnit: 512 * 1024 * 1024,
+			expectedIncludes: map[ResourceName]bool{
+				"nvidia.com/a100": true,
+				"nvidia.com/gpu":  false,
+			},
+		},
+		{
+			description:   "zero unit",
+			config:        "version: v1\nmemoryResource:\n  unit: \"0\"\n",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			config, err := parseConfigFrom(strings.NewReader(tc.config))
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expectNil {
+				require.Nil(t, config.MemoryResource)
+				return
+			}
+			require.NotNil(t, config.MemoryResource)
+			require.Equal(t, tc.expectedName, config.MemoryResource.ResourceName(config.GetResourceNamePrefix()))
+			require.Equal(t, tc.expectedUnit, config.MemoryResource.UnitBytes())
+			for name, included := range tc.expectedIncludes {
+				require.Equal(t, included, config.MemoryResource.Includes(name))
+			}
+		})
+	}
+}
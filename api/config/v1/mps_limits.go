@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// MPSLimits defines explicit MPS limits for the replicas of the devices of a
+// shared resource. Without limits, the memory and the threads of a device are
+// divided evenly between its replicas.
+type MPSLimits struct {
+	// Name is the resource that the limits apply to as specified in the
+	// shared resources (i.e. before it is renamed).
+	Name ResourceName `json:"name"                             yaml:"name"`
+	// Devices selects the devices that the limits apply to. Limits for a list
+	// of devices take precedence over limits for all devices of the resource.
+	Devices ReplicatedDevices `json:"devices"                          yaml:"devices,flow"`
+	// PinnedMemoryLimit is the pinned device memory limit of each replica.
+	PinnedMemoryLimit *resource.Quantity `json:"pinnedMemoryLimit,omitempty"      yaml:"pinnedMemoryLimit,omitempty"`
+	// ActiveThreadPercentage is the active thread percentage of each replica.
+	ActiveThreadPercentage int `json:"activeThreadPercentage,omitempty" yaml:"activeThreadPercentage,omitempty"`
+	// OvercommitFactor scales the evenly divided active thread percentage of
+	// each replica, e.g. a factor of 1.6 gives each of 4 replicas 40% of the
+	// threads of a device. It is ignored if ActiveThreadPercentage is set.
+	OvercommitFactor float64 `json:"overcommitFactor,omitempty"       yaml:"overcommitFactor,omitempty"`
+}
+
+// UnmarshalJSON unmarshals raw bytes into an 'MPSLimits' struct.
+func (l *MPSLimits) UnmarshalJSON(b []byte) error {
+	type mpsLimits MPSLimits
+	var raw MPSLimits
+	if err := json.Unmarshal(b, (*mpsLimits)(&raw)); err != nil {
+		return err
+	}
+	if !raw.Devices.All && raw.Devices.Count == 0 && len(raw.Devices.List) == 0 {
+		raw.Devices.All = true
+	}
+	if raw.Name == "" {
+		return fmt.Errorf("no resource name specified for MPS limits")
+	}
+	if raw.Devices.Count > 0 {
+		return fmt.Errorf("devices for MPS limits must be 'all' or a list of devices")
+	}
+	if raw.PinnedMemoryLimit != nil && raw.PinnedMemoryLimit.Sign() <= 0 {
+		return fmt.Errorf("pinned memory limit for %v must be > 0", raw.Name)
+	}
+	if raw.ActiveThreadPercentage < 0 || raw.ActiveThreadPercentage > 100 {
+		return fmt.Errorf("active thread percentage for %v must be between 0 and 100", raw.Name)
+	}
+	if raw.OvercommitFactor != 0 && raw.OvercommitFactor < 1 {
+		return fmt.Errorf("overcommit factor for %v must be >= 1", raw.Name)
+	}
+	if raw.PinnedMemoryLimit == nil && raw.ActiveThreadPercentage == 0 && raw.OvercommitFactor == 0 {
+		return fmt.Errorf("no limits specified for %v", raw.Name)
+	}
+	*l = raw
+	return nil
+}
+
+// PinnedMemoryLimitBytes returns the pinned memory limit in bytes or 0 if no
+// limit is set.
+func (l *MPSLimits) PinnedMemoryLimitBytes() uint64 {
+	if l == nil || l.PinnedMemoryLimit == nil {
+		return 0
+	}
+	return uint64(l.PinnedMemoryLimit.Value())
+}
+
+// ActiveThreadPercentageFor returns the active thread percentage of each of
+// the specified number of replicas of a device or 0 if no explicit percentage
+// and no overcommit factor is set.
+func (l *MPSLimits) ActiveThreadPercentageFor(replicas int) int {
+	if l == nil || replicas < 1 {
+		return 0
+	}
+	if l.ActiveThreadPercentage != 0 {
+		return l.ActiveThreadPercentage
+	}
+	if l.OvercommitFactor == 0 {
+		return 0
+	}
+	percentage := int(100 * l.OvercommitFactor / float64(replicas))
+	if percentage > 100 {
+		return 100
+	}
+	return percentage
+}
+
+// validateLimits checks that the MPS limits refer to shared resources that are
+// not split into replica classes. These define their own limits. Since MPS
+// only supports a single default active thread percentage per control daemon,
+// the limits must also give all devices of a resource the same percentage.
+func (s *ReplicatedResources) validateLimits() error {
+	resources := make(map[ResourceName]*ReplicatedResource)
+	for i := range s.Resources {
+		resources[s.Resources[i].Name] = &s.Resources[i]
+	}
+	for _, l := range s.Limits {
+		r, exists := resources[l.Name]
+		if !exists {
+			return fmt.Errorf("MPS limits specified for %v which is not shared", l.Name)
+		}
+		if len(r.Classes) > 0 {
+			return fmt.Errorf("MPS limits cannot be specified for %v since it is split into replica classes", l.Name)
+		}
+	}
+	for _, r := range s.Resources {
+		if err := s.validateActiveThreadPercentages(&r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateActiveThreadPercentages checks that the MPS limits for the specified
+// resource give all of its devices the same active thread percentage. Devices
+// that are not listed in the limits use the limits for all devices or, without
+// these, an even share of the threads.
+func (s *ReplicatedResources) validateActiveThreadPercentages(r *ReplicatedResource) error {
+	percentageFor := func(l *MPSLimits) int {
+		if p := l.ActiveThreadPercentageFor(r.Replicas); p != 0 {
+			return p
+		}
+		return 100 / max(r.Replicas, 1)
+	}
+
+	var all *MPSLimits
+	var listed []*MPSLimits
+	for i := range s.Limits {
+		l := &s.Limits[i]
+		if l.Name != r.Name {
+			continue
+		}
+		if l.Devices.All {
+			if all == nil {
+				all = l
+			}
+			continue
+		}
+		listed = append(listed, l)
+	}
+	if len(listed) == 0 {
+		return nil
+	}
+
+	percentage := percentageFor(all)
+	for _, l := range listed {
+		if p := percentageFor(l); p != percentage {
+			return fmt.Errorf("MPS limits for %v give devices %v an active thread percentage of %d while other devices get %d; MPS only supports a single default active thread percentage per resource", r.Name, l.Devices.List, p, percentage)
+		}
+	}
+	return nil
+}
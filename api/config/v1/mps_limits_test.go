@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMPSLimits(t *testing.T) {
+	testCases := []struct {
+		description              string
+		limits                   string
+		expectedError            bool
+		expectedDevices          ReplicatedDevices
+		expectedMemory           uint64
+		expectedThreadPercentage int
+	}{
+		{
+			description:              "overcommit factor",
+			limits:                   "{name: nvidia.com/gpu, overcommitFactor: 1.6}",
+			expectedDevices:          ReplicatedDevices{All: true},
+			expectedThreadPercentage: 40,
+		},
+		{
+			description:              "explicit limits for devices",
+			limits:                   "{name: nvidia.com/gpu, devices: [0, GPU-8dcd427f-483b-b48f-d7e5-75fb19a52b76], pinnedMemoryLimit: 10Gi, activeThreadPercentage: 25, overcommitFactor: 2}",
+			expectedDevices:          ReplicatedDevices{List: []ReplicatedDeviceRef{"0", "GPU-8dcd427f-483b-b48f-d7e5-75fb19a52b76"}},
+			expectedMemory:           10 * 1024 * 1024 * 1024,
+			expectedThreadPercentage: 25,
+		},
+		{
+			description:   "no limits",
+			limits:        "{name: nvidia.com/gpu}",
+			expectedError: true,
+		},
+		{
+			description:   "count of devices",
+			limits:        "{name: nvidia.com/gpu, devices: 2, activeThreadPercentage: 30}",
+			expectedError: true,
+		},
+		{
+			description:   "overcommit factor below one",
+			limits:        "{name: nvidia.com/gpu, overcommitFactor: 0.5}",
+			expectedError: true,
+		},
+		{
+			description:   "active thread percentage above 100",
+			limits:        "{name: nvidia.com/gpu, activeThreadPercentage: 120}",
+			expectedError: true,
+		},
+		{
+			description:   "resource that is not shared",
+			limits:        "{name: nvidia.com/other, activeThreadPercentage: 30}",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			config, err := parseConfigFrom(strings.NewReader(`
+version: v1
+sharing:
+  mps:
+    resources:
+    - name: nvidia.com/gpu
+      replicas: 4
+    limits:
+    - ` + tc.limits + "\n"))
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, config.Sharing.MPS.Limits, 1)
+
+			limits := &config.Sharing.MPS.Limits[0]
+			require.Equal(t, tc.expectedDevices, limits.Devices)
+			require.Equal(t, tc.expectedMemory, limits.PinnedMemoryLimitBytes())
+			require.Equal(t, tc.expectedThreadPercentage, limits.ActiveThreadPercentageFor(4))
+		})
+	}
+}
+
+func TestMPSLimitsActiveThreadPercentages(t *testing.T) {
+	testCases := []struct {
+		description   string
+		limits        string
+		expectedError bool
+	}{
+		{
+			description: "same percentage for listed and other devices",
+			limits: `
+    - {name: nvidia.com/gpu, overcommitFactor: 1.6}
+    - {name: nvidia.com/gpu, devices: [0], pinnedMemoryLimit: 8Gi, activeThreadPercentage: 40}`,
+		},
+		{
+			description: "listed devices with the default percentage",
+			limits: `
+    - {name: nvidia.com/gpu, devices: [0], pinnedMemoryLimit: 8Gi}`,
+		},
+		{
+			description: "listed devices differ from all devices",
+			limits: `
+    - {name: nvidia.com/gpu, overcommitFactor: 1.6}
+    - {name: nvidia.com/gpu, devices: [0], activeThreadPercentage: 30}`,
+			expectedError: true,
+		},
+		{
+			description: "listed devices differ from the default percentage",
+			limits: `
+    - {name: nvidia.com/gpu, devices: [0], activeThreadPercentage: 30}`,
+			expectedError: true,
+		},
+		{
+			description: "lists of devices differ",
+			limits: `
+    - {name: nvidia.com/gpu, activeThreadPercentage: 30}
+    - {name: nvidia.com/gpu, devices: [0], activeThreadPercentage: 30}
+    - {name: nvidia.com/gpu, devices: [1], activeThreadPercentage: 40}`,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := parseConfigFrom(strings.NewReader(`
+version: v1
+sharing:
+  mps:
+    resources:
+    - name: nvidia.com/gpu
+      replicas: 4
+    limits:` + tc.limits + "\n"))
+			if tc.expectedError {
+				require.ErrorContains(t, err, "single default active thread percentage")
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestMPSLimitsForReplicaClasses(t *testing.T) {
+	_, err := parseConfigFrom(strings.NewReader(`
+version: v1
+sharing:
+  mps:
+    resources:
+    - name: nvidia.com/gpu
+      classes:
+      - {name: nvidia.com/gpu-10g, replicas: 2, memory: 10Gi}
+    limits:
+    - {name: nvidia.com/gpu, activeThreadPercentage: 30}
+`))
+	require.Error(t, err)
+}
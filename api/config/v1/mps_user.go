@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MPSUser defines the user that the MPS control daemon of a shared resource is
+// started as. MPS clients must run as the same user to connect to the daemon.
+type MPSUser struct {
+	UID uint32 `json:"uid" yaml:"uid"`
+	GID uint32 `json:"gid" yaml:"gid"`
+}
+
+// UnmarshalJSON unmarshals raw bytes into an 'MPSUser' struct. Both the UID
+// and the GID must be specified.
+func (u *MPSUser) UnmarshalJSON(b []byte) error {
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	uid, exists := raw["uid"]
+	if !exists {
+		return fmt.Errorf("no uid specified for MPS user")
+	}
+	if err := json.Unmarshal(uid, &u.UID); err != nil {
+		return fmt.Errorf("invalid uid for MPS user: %w", err)
+	}
+	gid, exists := raw["gid"]
+	if !exists {
+		return fmt.Errorf("no gid specified for MPS user")
+	}
+	if err := json.Unmarshal(gid, &u.GID); err != nil {
+		return fmt.Errorf("invalid gid for MPS user: %w", err)
+	}
+	return nil
+}
+
+// Equal checks whether two, possibly nil, MPS users are the same.
+func (u *MPSUser) Equal(o *MPSUser) bool {
+	if u == nil || o == nil {
+		return u == o
+	}
+	return *u == *o
+}
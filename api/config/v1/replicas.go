@@ -23,7 +23,7 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
-	"k8s.io/klog/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 // ReplicatedResources defines generic options for replicating devices.
@@ -31,38 +31,16 @@ type ReplicatedResources struct {
 	RenameByDefault            bool                 `json:"renameByDefault,omitempty"            yaml:"renameByDefault,omitempty"`
 	FailRequestsGreaterThanOne bool                 `json:"failRequestsGreaterThanOne,omitempty" yaml:"failRequestsGreaterThanOne,omitempty"`
 	Resources                  []ReplicatedResource `json:"resources,omitempty"                  yaml:"resources,omitempty"`
-}
-
-func (rrs *ReplicatedResources) disableResoureRenaming(id string) {
-	if rrs == nil {
-		return
-	}
-	renameByDefault := rrs.RenameByDefault
-	setsNonDefaultRename := false
-	setsDevices := false
-	for i, r := range rrs.Resources {
-		if !renameByDefault && r.Rename != "" {
-			setsNonDefaultRename = true
-			rrs.Resources[i].Rename = ""
-		}
-		if renameByDefault && r.Rename != r.Name.DefaultSharedRename() {
-			setsNonDefaultRename = true
-			rrs.Resources[i].Rename = r.Name.DefaultSharedRename()
-		}
-		if !r.Devices.All {
-			setsDevices = true
-			rrs.Resources[i].Devices.All = true
-			rrs.Resources[i].Devices.Count = 0
-			rrs.Resources[i].Devices.List = nil
-		}
-	}
-	if setsNonDefaultRename {
-		klog.Warningf("Setting the 'rename' field in sharing.%s.resources is not yet supported in the config. Ignoring...", id)
-	}
-	if setsDevices {
-		klog.Warningf("Customizing the 'devices' field in sharing.%s.resources is not yet supported in the config. Ignoring...", id)
-	}
-
+	// MemoryHints configures the memory hints that are passed to containers
+	// that are allocated time-sliced replicas.
+	MemoryHints *MemoryHints `json:"memoryHints,omitempty" yaml:"memoryHints,omitempty"`
+	// Limits overrides the MPS memory and thread limits of the replicas of
+	// shared resources. These are only supported when sharing with MPS.
+	Limits []MPSLimits `json:"limits,omitempty" yaml:"limits,omitempty"`
+	// AllowMultiGPURequests allows a container to request more than one
+	// replica of a shared resource as long as each replica is on a distinct
+	// GPU. This is only supported when sharing with MPS.
+	AllowMultiGPURequests bool `json:"allowMultiGPURequests,omitempty" yaml:"allowMultiGPURequests,omitempty"`
 }
 
 func (rrs *ReplicatedResources) isReplicated() bool {
@@ -70,7 +48,7 @@ func (rrs *ReplicatedResources) isReplicated() bool {
 		return false
 	}
 	for _, rr := range rrs.Resources {
-		if rr.Replicas > 1 {
+		if rr.TotalReplicas() > 1 {
 			return true
 		}
 	}
@@ -78,17 +56,51 @@ func (rrs *ReplicatedResources) isReplicated() bool {
 }
 
 // ReplicatedResource represents a resource to be replicated.
+// A device is either replicated Replicas times under a single resource name,
+// or carved into the replica classes listed in Classes.
 type ReplicatedResource struct {
-	Name     ResourceName      `json:"name"             yaml:"name"`
-	Rename   ResourceName      `json:"rename,omitempty" yaml:"rename,omitempty"`
-	Devices  ReplicatedDevices `json:"devices"          yaml:"devices,flow"`
-	Replicas int               `json:"replicas"         yaml:"replicas"`
+	Name     ResourceName      `json:"name"              yaml:"name"`
+	Rename   ResourceName      `json:"rename,omitempty"  yaml:"rename,omitempty"`
+	Devices  ReplicatedDevices `json:"devices"           yaml:"devices,flow"`
+	Replicas int               `json:"replicas"          yaml:"replicas"`
+	Classes  []ReplicaClass    `json:"classes,omitempty" yaml:"classes,omitempty"`
+	// User is the user that the MPS control daemon of the resource is
+	// started as. If unset, the daemon runs as the user of its container.
+	// This is only supported when sharing with MPS.
+	User *MPSUser `json:"user,omitempty" yaml:"user,omitempty"`
+}
+
+// ReplicaClass defines a set of equally sized replicas of a device that are
+// advertised under their own resource name.
+type ReplicaClass struct {
+	Name     ResourceName      `json:"name"     yaml:"name"`
+	Replicas int               `json:"replicas" yaml:"replicas"`
+	Memory   resource.Quantity `json:"memory"   yaml:"memory"`
+	// ActiveThreadPercentage is the MPS active thread percentage of each
+	// replica. If unset, it is derived from the share of device memory of a
+	// replica.
+	ActiveThreadPercentage int `json:"activeThreadPercentage,omitempty" yaml:"activeThreadPercentage,omitempty"`
+}
+
+// TotalReplicas returns the number of replicas each selected device is split
+// into, summed over all replica classes if these are defined.
+func (r *ReplicatedResource) TotalReplicas() int {
+	if len(r.Classes) == 0 {
+		return r.Replicas
+	}
+	total := 0
+	for _, c := range r.Classes {
+		total += c.Replicas
+	}
+	return total
 }
 
 // ReplicatedDevices encapsulates the set of devices that should be replicated for a given resource.
 // This struct should be treated as a 'union' and only one of the fields in this struct should be set at any given time.
 type ReplicatedDevices struct {
-	All   bool
+	All bool
+	// Count selects the first Count devices of the resource ordered by index.
+	// MIG devices are ordered numerically by their GPU and MIG index (i:j).
 	Count int
 	List  []ReplicatedDeviceRef
 }
@@ -204,8 +216,29 @@ func (s *ReplicatedResources) UnmarshalJSON(b []byte) error {
 		return fmt.Errorf("no resources specified")
 	}
 
+	if memoryHints, exists := ts["memoryHints"]; exists {
+		if err := json.Unmarshal(memoryHints, &s.MemoryHints); err != nil {
+			return err
+		}
+	}
+
+	if limits, exists := ts["limits"]; exists {
+		if err := json.Unmarshal(limits, &s.Limits); err != nil {
+			return err
+		}
+		if err := s.validateLimits(); err != nil {
+			return err
+		}
+	}
+
+	if allowMultiGPURequests, exists := ts["allowMultiGPURequests"]; exists {
+		if err := json.Unmarshal(allowMultiGPURequests, &s.AllowMultiGPURequests); err != nil {
+			return err
+		}
+	}
+
 	for i, r := range s.Resources {
-		if s.RenameByDefault && r.Rename == "" {
+		if s.RenameByDefault && r.Rename == "" && len(r.Classes) == 0 {
 			s.Resources[i].Rename = r.Name.DefaultSharedRename()
 		}
 	}
@@ -241,8 +274,30 @@ func (s *ReplicatedResource) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	replicas, exists := rr["replicas"]
-	if !exists {
+	if user, exists := rr["user"]; exists {
+		if err := json.Unmarshal(user, &s.User); err != nil {
+			return err
+		}
+	}
+
+	replicas, replicasExist := rr["replicas"]
+	classes, classesExist := rr["classes"]
+	switch {
+	case replicasExist && classesExist:
+		return fmt.Errorf("only one of replicas and classes can be specified")
+	case classesExist:
+		err = json.Unmarshal(classes, &s.Classes)
+		if err != nil {
+			return err
+		}
+		if err := s.validateClasses(); err != nil {
+			return err
+		}
+		if _, exists := rr["rename"]; exists {
+			return fmt.Errorf("rename cannot be specified with classes; each class is advertised under its own name")
+		}
+		return nil
+	case !replicasExist:
 		return fmt.Errorf("no replicas specified")
 	}
 
@@ -268,6 +323,36 @@ func (s *ReplicatedResource) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// validateClasses checks that the replica classes of a resource are valid.
+func (s *ReplicatedResource) validateClasses() error {
+	if len(s.Classes) == 0 {
+		return fmt.Errorf("no classes specified")
+	}
+	names := make(map[ResourceName]bool)
+	for _, c := range s.Classes {
+		if c.Name == "" {
+			return fmt.Errorf("no name specified for replica class")
+		}
+		if c.Name == s.Name || names[c.Name] {
+			return fmt.Errorf("replica class name %v must be unique", c.Name)
+		}
+		names[c.Name] = true
+		if c.Replicas < 1 {
+			return fmt.Errorf("number of replicas for class %v must be >= 1", c.Name)
+		}
+		if c.Memory.Sign() <= 0 {
+			return fmt.Errorf("memory for class %v must be > 0", c.Name)
+		}
+		if c.ActiveThreadPercentage < 0 || c.ActiveThreadPercentage > 100 {
+			return fmt.Errorf("active thread percentage for class %v must be between 0 and 100", c.Name)
+		}
+	}
+	if s.TotalReplicas() < 2 {
+		return fmt.Errorf("total number of replicas over all classes must be >= 2")
+	}
+	return nil
+}
+
 // UnmarshalJSON unmarshals raw bytes into a 'ReplicatedDevices' struct.
 func (s *ReplicatedDevices) UnmarshalJSON(b []byte) error {
 	// Match the string 'all'
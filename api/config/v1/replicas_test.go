@@ -339,6 +339,38 @@ func TestUnmarshalReplicatedResource(t *testing.T) {
 				Rename:   NoErrorNewResourceName("valid-shared"),
 			},
 		},
+		{
+			input: `{
+				"name": "valid",
+				"devices": "all",
+				"replicas": 2,
+				"user": {"uid": 1000, "gid": 2000}
+			}`,
+			output: ReplicatedResource{
+				Name:     NoErrorNewResourceName("valid"),
+				Devices:  ReplicatedDevices{All: true},
+				Replicas: 2,
+				User:     &MPSUser{UID: 1000, GID: 2000},
+			},
+		},
+		{
+			input: `{
+				"name": "valid",
+				"devices": "all",
+				"replicas": 2,
+				"user": {"uid": 1000}
+			}`,
+			err: true,
+		},
+		{
+			input: `{
+				"name": "valid",
+				"devices": "all",
+				"replicas": 2,
+				"user": {"uid": -1, "gid": 1000}
+			}`,
+			err: true,
+		},
 		{
 			input: `{
 				"name": "$invalid$",
@@ -438,6 +470,27 @@ func TestUnmarshalReplicatedResources(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: `{
+				"allowMultiGPURequests": true,
+				"resources": [
+					{
+						"name": "valid",
+						"replicas": 2
+					}
+				]
+			}`,
+			output: ReplicatedResources{
+				AllowMultiGPURequests: true,
+				Resources: []ReplicatedResource{
+					{
+						Name:     NoErrorNewResourceName("valid"),
+						Devices:  ReplicatedDevices{All: true},
+						Replicas: 2,
+					},
+				},
+			},
+		},
 		{
 			input: `{
 				"resources": [
@@ -464,3 +517,95 @@ func TestUnmarshalReplicatedResources(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalReplicaClasses(t *testing.T) {
+	testCases := []struct {
+		description string
+		input       string
+		err         bool
+	}{
+		{
+			description: "valid classes",
+			input: `{
+				"name": "gpu",
+				"classes": [
+					{"name": "gpu-20g", "replicas": 2, "memory": "20Gi", "activeThreadPercentage": 25},
+					{"name": "gpu-10g", "replicas": 4, "memory": "10Gi"}
+				]
+			}`,
+		},
+		{
+			description: "single class with multiple replicas",
+			input:       `{"name": "gpu", "classes": [{"name": "gpu-10g", "replicas": 2, "memory": "10Gi"}]}`,
+		},
+		{
+			description: "replicas and classes",
+			input:       `{"name": "gpu", "replicas": 2, "classes": [{"name": "gpu-10g", "replicas": 2, "memory": "10Gi"}]}`,
+			err:         true,
+		},
+		{
+			description: "rename with classes",
+			input:       `{"name": "gpu", "rename": "gpu-shared", "classes": [{"name": "gpu-10g", "replicas": 2, "memory": "10Gi"}]}`,
+			err:         true,
+		},
+		{
+			description: "empty classes",
+			input:       `{"name": "gpu", "classes": []}`,
+			err:         true,
+		},
+		{
+			description: "single replica in total",
+			input:       `{"name": "gpu", "classes": [{"name": "gpu-10g", "replicas": 1, "memory": "10Gi"}]}`,
+			err:         true,
+		},
+		{
+			description: "missing memory",
+			input:       `{"name": "gpu", "classes": [{"name": "gpu-10g", "replicas": 2}]}`,
+			err:         true,
+		},
+		{
+			description: "duplicate class names",
+			input:       `{"name": "gpu", "classes": [{"name": "gpu-10g", "replicas": 1, "memory": "10Gi"}, {"name": "gpu-10g", "replicas": 1, "memory": "10Gi"}]}`,
+			err:         true,
+		},
+		{
+			description: "class named after resource",
+			input:       `{"name": "gpu", "classes": [{"name": "gpu", "replicas": 2, "memory": "10Gi"}]}`,
+			err:         true,
+		},
+		{
+			description: "invalid active thread percentage",
+			input:       `{"name": "gpu", "classes": [{"name": "gpu-10g", "replicas": 2, "memory": "10Gi", "activeThreadPercentage": 101}]}`,
+			err:         true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var output ReplicatedResource
+			err := output.UnmarshalJSON([]byte(tc.input))
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, output.Devices.All)
+		})
+	}
+
+	var output ReplicatedResource
+	err := output.UnmarshalJSON([]byte(`{
+		"name": "gpu",
+		"classes": [
+			{"name": "gpu-20g", "replicas": 2, "memory": "20Gi", "activeThreadPercentage": 25},
+			{"name": "example.com/gpu-10g", "replicas": 4, "memory": "10Gi"}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, 6, output.TotalReplicas())
+	require.Len(t, output.Classes, 2)
+	require.Equal(t, ResourceName("nvidia.com/gpu-20g"), output.Classes[0].Name)
+	require.Equal(t, int64(20*1024*1024*1024), output.Classes[0].Memory.Value())
+	require.Equal(t, 25, output.Classes[0].ActiveThreadPercentage)
+	require.Equal(t, ResourceName("example.com/gpu-10g"), output.Classes[1].Name)
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// resourceNameTemplateFuncs are the functions available in templated
+// resource names.
+var resourceNameTemplateFuncs = template.FuncMap{
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"replace":  func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"sanitize": sanitizeResourceName,
+}
+
+// resourceNameTemplateData holds the device attributes that a templated
+// resource name is evaluated against. These match the attributes that GPU
+// Feature Discovery exposes as labels.
+type resourceNameTemplateData struct {
+	// Product is the product name of the GPU (or the parent GPU of a MIG
+	// device) with spaces replaced by '-', as in the product label.
+	Product string
+	// Profile is the MIG profile of a MIG device and empty for full GPUs.
+	Profile string
+	// Memory is the total memory of the device in MiB.
+	Memory uint64
+	// Family is the architecture family of the GPU, e.g. ampere.
+	Family string
+	// ComputeCapability is the CUDA compute capability, e.g. 8.0.
+	ComputeCapability string
+}
+
+// IsTemplate checks whether the resource name is a template that is evaluated
+// for each device.
+func (r ResourceName) IsTemplate() bool {
+	return strings.Contains(string(r), "{{")
+}
+
+// assertNotTemplate returns an error if the resource name is a template.
+// Templates are only supported for the names of resources in resources.gpus
+// and resources.mig since other resource names are never evaluated.
+func (r ResourceName) assertNotTemplate() error {
+	if r.IsTemplate() {
+		return fmt.Errorf("resource name %q must not be a template; templates are only supported in resources.gpus and resources.mig", r)
+	}
+	return nil
+}
+
+// Evaluate evaluates a templated resource name for a device with the specified
+// attributes and validates the result. Resource names that are not templates
+// are returned as is.
+func (r ResourceName) Evaluate(attrs DeviceAttributes) (ResourceName, error) {
+	if !r.IsTemplate() {
+		return r, nil
+	}
+	t, err := newResourceNameTemplate(string(r))
+	if err != nil {
+		return "", err
+	}
+
+	data := resourceNameTemplateData{
+		Product:           sanitizeProductName(attrs.Product),
+		Profile:           attrs.Profile,
+		Memory:            attrs.TotalMemory / (1024 * 1024),
+		ComputeCapability: attrs.ComputeCapability,
+	}
+	if major, minor, err := parseComputeCapability(attrs.ComputeCapability); err == nil {
+		data.Family = ArchFamily(major, minor)
+	}
+
+	var name strings.Builder
+	if err := t.Execute(&name, data); err != nil {
+		return "", fmt.Errorf("error evaluating resource name template %q: %w", r, err)
+	}
+	evaluated, err := NewResourceName(name.String())
+	if err != nil {
+		return "", fmt.Errorf("resource name template %q evaluated to an invalid name: %w", r, err)
+	}
+	return evaluated, nil
+}
+
+// newResourceNameTemplate parses a templated resource name.
+func newResourceNameTemplate(name string) (*template.Template, error) {
+	t, err := template.New("resource-name").Funcs(resourceNameTemplateFuncs).Option("missingkey=error").Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource name template %q: %w", name, err)
+	}
+	return t, nil
+}
+
+// ArchFamily returns the architecture family of a GPU with the specified CUDA
+// compute capability.
+// TODO: This should a function in go-nvlib
+func ArchFamily(computeMajor, computeMinor int) string {
+	switch computeMajor {
+	case 1:
+		return "tesla"
+	case 2:
+		return "fermi"
+	case 3:
+		return "kepler"
+	case 5:
+		return "maxwell"
+	case 6:
+		return "pascal"
+	case 7:
+		if computeMinor < 5 {
+			return "volta"
+		}
+		return "turing"
+	case 8:
+		if computeMinor < 9 {
+			return "ampere"
+		}
+		return "ada-lovelace"
+	case 9:
+		return "hopper"
+	// The Blackwell GPU family is bifurcated into two cuda compute capabilities 10.0 and 12.0
+	case 10, 12:
+		return "blackwell"
+	}
+	return "undefined"
+}
+
+var (
+	invalidProductNameChars  = regexp.MustCompile("[^A-Za-z0-9-_. ]")
+	invalidResourceNameChars = regexp.MustCompile("[^a-z0-9-.]+")
+)
+
+// sanitizeProductName removes characters that are not valid in a label value
+// from a product name and replaces spaces with '-'.
+func sanitizeProductName(name string) string {
+	name = invalidProductNameChars.ReplaceAllString(name, "")
+	return strings.Join(strings.Fields(name), "-")
+}
+
+// sanitizeResourceName lowercases a string and replaces each sequence of
+// characters that are not valid in a resource name with '-'.
+func sanitizeResourceName(s string) string {
+	return strings.Trim(invalidResourceNameChars.ReplaceAllString(strings.ToLower(s), "-"), "-.")
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceNameTemplates(t *testing.T) {
+	a100 := DeviceAttributes{
+		Name:              "NVIDIA A100-SXM4-40GB",
+		Product:           "NVIDIA A100-SXM4-40GB",
+		TotalMemory:       40960 * 1024 * 1024,
+		ComputeCapability: "8.0",
+	}
+	mig := DeviceAttributes{
+		Name:              "1g.5gb+me",
+		Product:           "NVIDIA A100-SXM4-40GB",
+		Profile:           "1g.5gb+me",
+		TotalMemory:       4864 * 1024 * 1024,
+		ComputeCapability: "8.0",
+	}
+
+	testCases := []struct {
+		description   string
+		name          string
+		attrs         DeviceAttributes
+		expectedName  ResourceName
+		expectedError bool
+	}{
+		{
+			description:  "lowercase product",
+			name:         "nvidia.com/{{ .Product | lower }}",
+			attrs:        a100,
+			expectedName: "nvidia.com/nvidia-a100-sxm4-40gb",
+		},
+		{
+			description:  "default prefix is added",
+			name:         "{{ .Family }}-{{ .Memory }}",
+			attrs:        a100,
+			expectedName: "nvidia.com/ampere-40960",
+		},
+		{
+			description:  "MIG profile",
+			name:         `example.com/mig-{{ .Profile | replace "+" "." }}`,
+			attrs:        mig,
+			expectedName: "example.com/mig-1g.5gb.me",
+		},
+		{
+			description:  "sanitized compute capability",
+			name:         "gpu-cc{{ .ComputeCapability | sanitize }}",
+			attrs:        a100,
+			expectedName: "nvidia.com/gpu-cc8.0",
+		},
+		{
+			description:   "invalid evaluated name",
+			name:          "nvidia.com/{{ .Product }}",
+			attrs:         a100,
+			expectedError: true,
+		},
+		{
+			description:   "unknown attribute",
+			name:          "nvidia.com/{{ .Brand }}",
+			attrs:         a100,
+			expectedError: true,
+		},
+		{
+			description:  "not a template",
+			name:         "nvidia.com/gpu",
+			attrs:        a100,
+			expectedName: "nvidia.com/gpu",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			name, err := ResourceName(tc.name).Evaluate(tc.attrs)
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedName, name)
+		})
+	}
+}
+
+func TestResourceNameTemplatesInConfig(t *testing.T) {
+	config, err := parseConfigFrom(strings.NewReader(`
+version: v1
+resources:
+  gpus:
+  - pattern: "*"
+    name: "{{ .Product | lower }}"
+  mig:
+  - pattern: "*"
+    name: "mig-{{ .Profile }}"
+`))
+	require.NoError(t, err)
+	require.True(t, config.Resources.HasTemplates())
+
+	name, matched, err := config.Resources.GPUResourceName(DeviceAttributes{Name: "Tesla T4", Product: "Tesla T4"})
+	require.NoError(t, err)
+	require.True(t, matched)
+	require.Equal(t, ResourceName("nvidia.com/tesla-t4"), name)
+
+	name, matched, err = config.Resources.MIGResourceName(DeviceAttributes{Name: "2g.10gb", Profile: "2g.10gb"})
+	require.NoError(t, err)
+	require.True(t, matched)
+	require.Equal(t, ResourceName("nvidia.com/mig-2g.10gb"), name)
+
+	_, err = parseConfigFrom(strings.NewReader(`
+version: v1
+resources:
+  gpus:
+  - pattern: "*"
+    name: "{{ .Product | lower"
+`))
+	require.Error(t, err)
+}
+
+func TestResourceNameTemplatesRejectedOutsideResources(t *testing.T) {
+	testCases := []struct {
+		description string
+		config      string
+	}{
+		{
+			description: "shared resource name",
+			config: `
+version: v1
+sharing:
+  timeSlicing:
+    resources:
+    - name: "{{ .Product }}"
+      replicas: 2
+`,
+		},
+		{
+			description: "shared resource rename",
+			config: `
+version: v1
+sharing:
+  timeSlicing:
+    resources:
+    - name: nvidia.com/gpu
+      rename: "{{ .Product }}-shared"
+      replicas: 2
+`,
+		},
+		{
+			description: "allocation policy resource",
+			config: `
+version: v1
+allocationPolicy:
+  resources:
+    "nvidia.com/{{ .Product }}": pack
+`,
+		},
+		{
+			description: "container edits resource",
+			config: `
+version: v1
+containerEdits:
+- name: "{{ .Product }}"
+  envs:
+    FOO: bar
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := parseConfigFrom(strings.NewReader(tc.config))
+			require.ErrorContains(t, err, "must not be a template")
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 
 	k8s "k8s.io/apimachinery/pkg/api/validation"
@@ -32,10 +33,13 @@ type ResourcePattern string
 // ResourceName represents a valid resource name in Kubernetes
 type ResourceName string
 
-// Resource pairs a pattern matcher with a resource name.
+// Resource pairs a pattern matcher and an optional device selector with a
+// resource name. A device matches a resource if it matches both the pattern
+// and the selector; at least one of the two must be set.
 type Resource struct {
-	Pattern ResourcePattern `json:"pattern" yaml:"pattern"`
-	Name    ResourceName    `json:"name"    yaml:"name"`
+	Pattern  ResourcePattern `json:"pattern,omitempty"  yaml:"pattern,omitempty"`
+	Selector *DeviceSelector `json:"selector,omitempty" yaml:"selector,omitempty"`
+	Name     ResourceName    `json:"name"               yaml:"name"`
 }
 
 // Resources lists full GPUs and MIG devices separately.
@@ -102,24 +106,56 @@ func (r *Resource) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	// Verify both fields set in the resource JSON
+	// Verify that a name and at least one matcher are set in the resource JSON
 	pattern, patternExists := res["pattern"]
+	selector, selectorExists := res["selector"]
 	name, nameExists := res["name"]
-	if !patternExists {
-		return fmt.Errorf("resources must have a 'pattern' field set")
+	if !patternExists && !selectorExists {
+		return fmt.Errorf("resources must have a 'pattern' or a 'selector' field set")
 	}
 	if !nameExists {
 		return fmt.Errorf("resources must have a 'name' field set")
 	}
 
 	// Set r.Pattern from the resource JSON
-	err = json.Unmarshal(pattern, &r.Pattern)
+	if patternExists {
+		err = json.Unmarshal(pattern, &r.Pattern)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Set r.Selector from the resource JSON
+	if selectorExists {
+		err = json.Unmarshal(selector, &r.Selector)
+		if err != nil {
+			return err
+		}
+		if r.Selector == nil {
+			return fmt.Errorf("resource selector must not be empty")
+		}
+		err = r.Selector.validate()
+		if err != nil {
+			return fmt.Errorf("invalid resource selector: %w", err)
+		}
+	}
+
+	// Set r.Name from the resource JSON. Only the names of GPU and MIG
+	// resources may be templates; these are validated once they are
+	// evaluated for a device.
+	var rawName string
+	err = json.Unmarshal(name, &rawName)
 	if err != nil {
 		return err
 	}
-
-	// Set r.Name from the resource JSON
-	err = json.Unmarshal(name, &r.Name)
+	if ResourceName(rawName).IsTemplate() {
+		if _, err := newResourceNameTemplate(rawName); err != nil {
+			return err
+		}
+		r.Name = ResourceName(rawName)
+		return nil
+	}
+	r.Name, err = NewResourceName(rawName)
 	if err != nil {
 		return err
 	}
@@ -135,6 +171,12 @@ func (r *ResourceName) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	// Templated names are only supported for GPU and MIG resources and are
+	// handled when unmarshalling a Resource.
+	if err := ResourceName(raw).assertNotTemplate(); err != nil {
+		return err
+	}
+
 	*r, err = NewResourceName(raw)
 	if err != nil {
 		return err
@@ -163,6 +205,67 @@ func (r *Resources) AddMIGResource(pattern, name string) error {
 	return nil
 }
 
+// GPUResourceName returns the name of the first GPU resource matching a full
+// GPU with the specified attributes. Templated names are evaluated for the GPU.
+func (r *Resources) GPUResourceName(attrs DeviceAttributes) (ResourceName, bool, error) {
+	return findResourceName(r.GPUs, attrs)
+}
+
+// MIGResourceName returns the name of the first MIG resource matching a MIG
+// device with the specified attributes. Templated names are evaluated for the
+// MIG device.
+func (r *Resources) MIGResourceName(attrs DeviceAttributes) (ResourceName, bool, error) {
+	return findResourceName(r.MIGs, attrs)
+}
+
+// HasSelectors checks whether any GPU or MIG resource uses a device selector.
+// Callers use this to skip querying device attributes that only selectors
+// depend on.
+func (r *Resources) HasSelectors() bool {
+	for _, resource := range slices.Concat(r.GPUs, r.MIGs) {
+		if resource.Selector != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTemplates checks whether any GPU or MIG resource has a templated name.
+func (r *Resources) HasTemplates() bool {
+	for _, resource := range slices.Concat(r.GPUs, r.MIGs) {
+		if resource.Name.IsTemplate() {
+			return true
+		}
+	}
+	return false
+}
+
+func findResourceName(resources []Resource, attrs DeviceAttributes) (ResourceName, bool, error) {
+	for _, resource := range resources {
+		if !resource.Matches(attrs) {
+			continue
+		}
+		name, err := resource.Name.Evaluate(attrs)
+		if err != nil {
+			return "", true, err
+		}
+		return name, true, nil
+	}
+	return "", false, nil
+}
+
+// Matches checks if a device with the specified attributes matches both the
+// pattern and the selector of the resource.
+func (r Resource) Matches(attrs DeviceAttributes) bool {
+	if r.Pattern != "" && !r.Pattern.Matches(attrs.Name) {
+		return false
+	}
+	if r.Selector != nil && !r.Selector.Matches(attrs) {
+		return false
+	}
+	return true
+}
+
 // Matches checks if the provided string matches the ResourcePattern or not.
 func (p ResourcePattern) Matches(s string) bool {
 	result, _ := regexp.MatchString(wildCardToRegexp(string(p)), s)
@@ -0,0 +1,63 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceNames(t *testing.T) {
+	config, err := parseConfigFrom(strings.NewReader(`
+version: v1
+resources:
+  gpus:
+  - pattern: "*A100*"
+    name: a100
+  - pattern: "Tesla T4"
+    name: example.com/t4
+  mig:
+  - pattern: "1g.*"
+    name: mig-small
+`))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		description     string
+		lookup          func(DeviceAttributes) (ResourceName, bool, error)
+		input           string
+		expectedName    ResourceName
+		expectedMatched bool
+	}{
+		{"GPU matching wildcard", config.Resources.GPUResourceName, "NVIDIA A100-SXM4-40GB", "nvidia.com/a100", true},
+		{"GPU matching exactly with custom prefix", config.Resources.GPUResourceName, "Tesla T4", "example.com/t4", true},
+		{"GPU without match", config.Resources.GPUResourceName, "NVIDIA H100", "", false},
+		{"MIG matching wildcard", config.Resources.MIGResourceName, "1g.5gb", "nvidia.com/mig-small", true},
+		{"MIG without match", config.Resources.MIGResourceName, "2g.10gb", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			name, matched, err := tc.lookup(DeviceAttributes{Name: tc.input})
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedMatched, matched)
+			require.Equal(t, tc.expectedName, name)
+		})
+	}
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DeviceSelector selects devices by their attributes. All fields that are set
+// must match for a device to be selected.
+type DeviceSelector struct {
+	// PCIDeviceIDs lists PCI device IDs in hexadecimal, e.g. "0x20B0".
+	PCIDeviceIDs []string `json:"pciDeviceIDs,omitempty"      yaml:"pciDeviceIDs,omitempty"`
+	// UUIDs lists GPU or MIG device UUIDs.
+	UUIDs []string `json:"uuids,omitempty"             yaml:"uuids,omitempty"`
+	// Indices lists GPU indices (e.g. 0) or MIG indices (e.g. "0:1").
+	Indices []DeviceIndex `json:"indices,omitempty"           yaml:"indices,omitempty"`
+	// Memory selects devices by the amount of total memory they have.
+	Memory *MemoryRange `json:"memory,omitempty"            yaml:"memory,omitempty"`
+	// ComputeCapability selects devices by their CUDA compute capability.
+	ComputeCapability *ComputeCapabilityRange `json:"computeCapability,omitempty" yaml:"computeCapability,omitempty"`
+	// NUMANode selects devices attached to the specified NUMA node.
+	NUMANode *int `json:"numaNode,omitempty"          yaml:"numaNode,omitempty"`
+	// FabricAttached selects devices by whether they are attached to an
+	// NVLink fabric.
+	FabricAttached *bool `json:"fabricAttached,omitempty"    yaml:"fabricAttached,omitempty"`
+}
+
+// DeviceIndex is a GPU index or a MIG index of the form <gpu>:<mig>.
+type DeviceIndex string
+
+// MemoryRange is an inclusive range of memory sizes. Unset bounds are open.
+type MemoryRange struct {
+	Min *resource.Quantity `json:"min,omitempty" yaml:"min,omitempty"`
+	Max *resource.Quantity `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// ComputeCapabilityRange is an inclusive range of CUDA compute capabilities
+// of the form <major>.<minor>. Unset bounds are open.
+type ComputeCapabilityRange struct {
+	Min string `json:"min,omitempty" yaml:"min,omitempty"`
+	Max string `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// DeviceAttributes holds the attributes of a device that resources are
+// matched against. Zero values indicate that an attribute is unknown, and a
+// selector that requires an unknown attribute does not match.
+type DeviceAttributes struct {
+	// Name is the product name of a full GPU or the profile of a MIG device.
+	// This is what resource patterns are matched against.
+	Name string
+	// Product is the product name of a full GPU or of the parent GPU of a MIG
+	// device.
+	Product string
+	// Profile is the profile of a MIG device and empty for full GPUs.
+	Profile           string
+	UUID              string
+	Index             string
+	PCIDeviceID       uint16
+	TotalMemory       uint64
+	ComputeCapability string
+	NUMANode          *int
+	FabricAttached    *bool
+}
+
+// UnmarshalJSON unmarshals a GPU index given as either a number or a string.
+func (i *DeviceIndex) UnmarshalJSON(b []byte) error {
+	var index uint64
+	if err := json.Unmarshal(b, &index); err == nil {
+		*i = DeviceIndex(strconv.FormatUint(index, 10))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if !ReplicatedDeviceRef(s).IsGPUIndex() && !ReplicatedDeviceRef(s).IsMigIndex() {
+		return fmt.Errorf("invalid device index: %v", s)
+	}
+	*i = DeviceIndex(s)
+	return nil
+}
+
+// validate checks that all fields of the selector are well formed.
+func (s *DeviceSelector) validate() error {
+	for _, id := range s.PCIDeviceIDs {
+		if _, err := parsePCIDeviceID(id); err != nil {
+			return err
+		}
+	}
+	if s.Memory != nil && s.Memory.Min != nil && s.Memory.Max != nil && s.Memory.Min.Cmp(*s.Memory.Max) > 0 {
+		return fmt.Errorf("memory.min must not be greater than memory.max")
+	}
+	if s.ComputeCapability != nil {
+		for _, cc := range []string{s.ComputeCapability.Min, s.ComputeCapability.Max} {
+			if cc == "" {
+				continue
+			}
+			if _, _, err := parseComputeCapability(cc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Matches checks whether a device with the specified attributes is selected.
+func (s *DeviceSelector) Matches(attrs DeviceAttributes) bool {
+	if len(s.PCIDeviceIDs) > 0 && !s.matchesPCIDeviceID(attrs.PCIDeviceID) {
+		return false
+	}
+	if len(s.UUIDs) > 0 && !slices.Contains(s.UUIDs, attrs.UUID) {
+		return false
+	}
+	if len(s.Indices) > 0 && !slices.Contains(s.Indices, DeviceIndex(attrs.Index)) {
+		return false
+	}
+	if s.Memory != nil && !s.Memory.contains(attrs.TotalMemory) {
+		return false
+	}
+	if s.ComputeCapability != nil && !s.ComputeCapability.contains(attrs.ComputeCapability) {
+		return false
+	}
+	if s.NUMANode != nil && (attrs.NUMANode == nil || *attrs.NUMANode != *s.NUMANode) {
+		return false
+	}
+	if s.FabricAttached != nil && (attrs.FabricAttached == nil || *attrs.FabricAttached != *s.FabricAttached) {
+		return false
+	}
+	return true
+}
+
+func (s *DeviceSelector) matchesPCIDeviceID(id uint16) bool {
+	if id == 0 {
+		return false
+	}
+	for _, raw := range s.PCIDeviceIDs {
+		if selected, err := parsePCIDeviceID(raw); err == nil && selected == id {
+			return true
+		}
+	}
+	return false
+}
+
+// contains checks whether the specified amount of memory in bytes is in range.
+func (r *MemoryRange) contains(total uint64) bool {
+	if total == 0 {
+		return false
+	}
+	if r.Min != nil && r.Min.CmpInt64(int64(total)) > 0 {
+		return false
+	}
+	if r.Max != nil && r.Max.CmpInt64(int64(total)) < 0 {
+		return false
+	}
+	return true
+}
+
+// contains checks whether the specified compute capability is in range.
+func (r *ComputeCapabilityRange) contains(cc string) bool {
+	major, minor, err := parseComputeCapability(cc)
+	if err != nil {
+		return false
+	}
+	if r.Min != "" {
+		minMajor, minMinor, _ := parseComputeCapability(r.Min)
+		if major < minMajor || (major == minMajor && minor < minMinor) {
+			return false
+		}
+	}
+	if r.Max != "" {
+		maxMajor, maxMinor, _ := parseComputeCapability(r.Max)
+		if major > maxMajor || (major == maxMajor && minor > maxMinor) {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePCIDeviceID parses a hexadecimal PCI device ID with an optional 0x
+// prefix.
+func parsePCIDeviceID(id string) (uint16, error) {
+	trimmed := strings.TrimPrefix(strings.ToLower(id), "0x")
+	value, err := strconv.ParseUint(trimmed, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid PCI device ID '%v': %w", id, err)
+	}
+	return uint16(value), nil
+}
+
+// parseComputeCapability parses a compute capability of the form
+// <major>.<minor>.
+func parseComputeCapability(cc string) (int, int, error) {
+	parts := strings.SplitN(cc, ".", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid compute capability '%v': expected <major>.<minor>", cc)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid compute capability '%v': %w", cc, err)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid compute capability '%v': %w", cc, err)
+	}
+	return major, minor, nil
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2025, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceSelectors(t *testing.T) {
+	config, err := parseConfigFrom(strings.NewReader(`
+version: v1
+resources:
+  gpus:
+  - selector:
+      memory:
+        min: 80Gi
+    name: gpu-large
+  - selector:
+      numaNode: 1
+    name: gpu-numa1
+  - pattern: "*H100*"
+    selector:
+      fabricAttached: true
+    name: h100-fabric
+  - selector:
+      pciDeviceIDs: ["0x20B0", "20b5"]
+      computeCapability:
+        min: "8.0"
+        max: "8.9"
+    name: a100
+  - selector:
+      uuids: ["GPU-b1028956-cfa2-0990-bf4a-5da9abb51763"]
+    name: gpu-by-uuid
+  - pattern: "*"
+    name: gpu
+  mig:
+  - selector:
+      indices: ["0:1", 2]
+    name: mig-selected
+`))
+	require.NoError(t, err)
+
+	numa0 := 0
+	numa1 := 1
+	attached := true
+
+	testCases := []struct {
+		description  string
+		lookup       func(DeviceAttributes) (ResourceName, bool, error)
+		attrs        DeviceAttributes
+		expectedName ResourceName
+	}{
+		{
+			description:  "memory at lower bound",
+			lookup:       config.Resources.GPUResourceName,
+			attrs:        DeviceAttributes{Name: "NVIDIA H100 80GB HBM3", TotalMemory: 80 * 1024 * 1024 * 1024},
+			expectedName: "nvidia.com/gpu-large",
+		},
+		{
+			description:  "memory below lower bound falls through",
+			lookup:       config.Resources.GPUResourceName,
+			attrs:        DeviceAttributes{Name: "NVIDIA A100-SXM4-40GB", TotalMemory: 40 * 1024 * 1024 * 1024, NUMANode: &numa1},
+			expectedName: "nvidia.com/gpu-numa1",
+		},
+		{
+			description:  "unknown NUMA node does not match",
+			lookup:       config.Resources.GPUResourceName,
+			attrs:        DeviceAttributes{Name: "Tesla T4"},
+			expectedName: "nvidia.com/gpu",
+		},
+		{
+			description:  "pattern and selector must both match",
+			lookup:       config.Resources.GPUResourceName,
+			attrs:        DeviceAttributes{Name: "NVIDIA H100 PCIe", NUMANode: &numa0, FabricAttached: &attached},
+			expectedName: "nvidia.com/h100-fabric",
+		},
+		{
+			description:  "pattern matches but selector does not",
+			lookup:       config.Resources.GPUResourceName,
+			attrs:        DeviceAttributes{Name: "NVIDIA A100 PCIe", FabricAttached: &attached},
+			expectedName: "nvidia.com/gpu",
+		},
+		{
+			description:  "PCI device ID and compute capability",
+			lookup:       config.Resources.GPUResourceName,
+			attrs:        DeviceAttributes{Name: "NVIDIA A100-SXM4-40GB", PCIDeviceID: 0x20b5, ComputeCapability: "8.0"},
+			expectedName: "nvidia.com/a100",
+		},
+		{
+			description:  "compute capability above upper bound",
+			lookup:       config.Resources.GPUResourceName,
+			attrs:        DeviceAttributes{Name: "NVIDIA A100-SXM4-40GB", PCIDeviceID: 0x20b5, ComputeCapability: "9.0"},
+			expectedName: "nvidia.com/gpu",
+		},
+		{
+			description:  "UUID",
+			lookup:       config.Resources.GPUResourceName,
+			attrs:        DeviceAttributes{Name: "Tesla T4", UUID: "GPU-b1028956-cfa2-0990-bf4a-5da9abb51763"},
+			expectedName: "nvidia.com/gpu-by-uuid",
+		},
+		{
+			description:  "MIG index",
+			lookup:       config.Resources.MIGResourceName,
+			attrs:        DeviceAttributes{Name: "1g.5gb", Index: "0:1"},
+			expectedName: "nvidia.com/mig-selected",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			name, matched, err := tc.lookup(tc.attrs)
+			require.NoError(t, err)
+			require.True(t, matched)
+			require.Equal(t, tc.expectedName, name)
+		})
+	}
+
+	_, matched, err := config.Resources.MIGResourceName(DeviceAttributes{Name: "1g.5gb", Index: "1:0"})
+	require.NoError(t, err)
+	require.False(t, matched)
+	require.True(t, config.Resources.HasSelectors())
+}
+
+func TestInvalidResourceSelectors(t *testing.T) {
+	testCases := []struct {
+		description string
+		resource    string
+	}{
+		{
+			description: "neither pattern nor selector",
+			resource:    `name: gpu`,
+		},
+		{
+			description: "empty selector",
+			resource:    "selector:\n    name: gpu",
+		},
+		{
+			description: "invalid PCI device ID",
+			resource:    "selector:\n      pciDeviceIDs: [\"0xZZ\"]\n    name: gpu",
+		},
+		{
+			description: "invalid compute capability",
+			resource:    "selector:\n      computeCapability:\n        min: \"8\"\n    name: gpu",
+		},
+		{
+			description: "invalid memory range",
+			resource:    "selector:\n      memory:\n        min: 80Gi\n        max: 40Gi\n    name: gpu",
+		},
+		{
+			description: "invalid index",
+			resource:    "selector:\n      indices: [\"GPU-0\"]\n    name: gpu",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := parseConfigFrom(strings.NewReader("version: v1\nresources:\n  gpus:\n  - " + tc.resource + "\n"))
+			require.Error(t, err)
+		})
+	}
+}
@@ -28,8 +28,6 @@ import (
 
 // Config represents a collection of config options for GFD.
 type Config struct {
-	configFile string
-
 	kubeClientConfig flags.KubeClientConfig
 	nodeConfig       flags.NodeConfig
 
@@ -48,25 +46,8 @@ func main() {
 		return start(ctx, config)
 	}
 
-	config.flags = []cli.Flag{
-		&cli.StringFlag{
-			Name:    "mig-strategy",
-			Value:   spec.MigStrategyNone,
-			Usage:   "the desired strategy for exposing MIG devices on GPUs that support it:\n\t\t[none | single | mixed]",
-			EnvVars: []string{"GFD_MIG_STRATEGY", "MIG_STRATEGY"},
-		},
-		&cli.BoolFlag{
-			Name:    "fail-on-init-error",
-			Value:   true,
-			Usage:   "fail the plugin if an error is encountered during initialization, otherwise block indefinitely",
-			EnvVars: []string{"GFD_FAIL_ON_INIT_ERROR", "FAIL_ON_INIT_ERROR"},
-		},
-		&cli.StringFlag{
-			Name:    "resource-name-prefix",
-			Value:   "nvidia.com",
-			Usage:   "the prefix to use for resource names (e.g., 'nvidia.com' for nvidia.com/gpu)",
-			EnvVars: []string{"GFD_RESOURCE_NAME_PREFIX", "RESOURCE_NAME_PREFIX"},
-		},
+	config.flags = flags.CommonFlags("GFD_", flags.GFDDeviceDiscoveryStrategies)
+	config.flags = append(config.flags,
 		&cli.BoolFlag{
 			Name:    "oneshot",
 			Value:   false,
@@ -97,32 +78,13 @@ func main() {
 			Usage:   "a path to a file that contains the DMI (SMBIOS) information for the node",
 			EnvVars: []string{"GFD_MACHINE_TYPE_FILE"},
 		},
-		&cli.StringFlag{
-			Name:        "config-file",
-			Usage:       "the path to a config file as an alternative to command line options or environment variables",
-			Destination: &config.configFile,
-			EnvVars:     []string{"GFD_CONFIG_FILE", "CONFIG_FILE"},
-		},
 		&cli.BoolFlag{
 			Name:    "use-node-feature-api",
 			Value:   true,
 			Usage:   "Use NFD NodeFeature API to publish labels",
 			EnvVars: []string{"GFD_USE_NODE_FEATURE_API", "USE_NODE_FEATURE_API"},
 		},
-		&cli.StringFlag{
-			Name:    "device-discovery-strategy",
-			Value:   "auto",
-			Usage:   "the strategy to use to discover devices: 'auto', 'nvml', 'tegra' or 'vfio'",
-			EnvVars: []string{"DEVICE_DISCOVERY_STRATEGY"},
-		},
-		&cli.StringFlag{
-			Name:    "driver-root-ctr-path",
-			Aliases: []string{"container-driver-root"},
-			Value:   spec.DefaultContainerDriverRoot,
-			Usage:   "the path where the NVIDIA driver root is mounted in the container",
-			EnvVars: []string{"DRIVER_ROOT_CTR_PATH", "CONTAINER_DRIVER_ROOT"},
-		},
-	}
+	)
 
 	config.flags = append(config.flags, config.kubeClientConfig.Flags()...)
 	config.flags = append(config.flags, config.nodeConfig.Flags()...)
@@ -135,35 +97,13 @@ func main() {
 	}
 }
 
-func validateFlags(config *spec.Config) error {
-	switch *config.Flags.DeviceDiscoveryStrategy {
-	case "auto":
-	case "nvml":
-	case "tegra":
-	case "vfio":
-	default:
-		return fmt.Errorf("invalid --device-discovery-strategy option %v", *config.Flags.DeviceDiscoveryStrategy)
-	}
-
-	// Validate resource name prefix format
-	if config.Flags.ResourceNamePrefix != nil && *config.Flags.ResourceNamePrefix != "" {
-		prefix := *config.Flags.ResourceNamePrefix
-		if prefix != "nvidia.com" {
-			klog.Warningf("Using custom resource name prefix: %s (default is nvidia.com)", prefix)
-			klog.Warning("All pods requesting GPU resources must be updated to use the new resource name format")
-		}
-	}
-
-	return nil
-}
-
 // loadConfig loads the config from the spec file.
 func (cfg *Config) loadConfig(c *cli.Context) (*spec.Config, error) {
 	config, err := spec.NewConfig(c, cfg.flags)
 	if err != nil {
 		return nil, fmt.Errorf("unable to finalize config: %v", err)
 	}
-	err = validateFlags(config)
+	err = flags.ValidateCommonFlags(config, flags.GFDDeviceDiscoveryStrategies)
 	if err != nil {
 		return nil, fmt.Errorf("unable to validate flags: %v", err)
 	}
@@ -186,7 +126,6 @@ func start(c *cli.Context, cfg *Config) error {
 		if err != nil {
 			return fmt.Errorf("unable to load config: %v", err)
 		}
-		spec.DisableResourceNamingInConfig(config)
 
 		// Print the config to the output.
 		configJSON, err := json.MarshalIndent(config, "", "  ")
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"syscall"
 	"time"
@@ -33,8 +34,10 @@ import (
 
 	"github.com/NVIDIA/k8s-device-plugin/cmd/mps-control-daemon/mount"
 	"github.com/NVIDIA/k8s-device-plugin/cmd/mps-control-daemon/mps"
+	"github.com/NVIDIA/k8s-device-plugin/internal/flags"
 	"github.com/NVIDIA/k8s-device-plugin/internal/info"
 	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
+	driverroot "github.com/NVIDIA/k8s-device-plugin/internal/root"
 	"github.com/NVIDIA/k8s-device-plugin/internal/watch"
 
 	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
@@ -42,7 +45,7 @@ import (
 
 // Config represents a collection of config options for the device plugin.
 type Config struct {
-	configFile string
+	metricsAddress string
 
 	// flags stores the CLI flags for later processing.
 	flags []cli.Flag
@@ -61,20 +64,16 @@ func main() {
 		mount.NewCommand(),
 	}
 
-	config.flags = []cli.Flag{
+	config.flags = flags.CommonFlags("", flags.PluginDeviceDiscoveryStrategies)
+	config.flags = append(config.flags, flags.MPSDaemonFlags()...)
+	config.flags = append(config.flags,
 		&cli.StringFlag{
-			Name:        "config-file",
-			Usage:       "the path to a config file as an alternative to command line options or environment variables",
-			Destination: &config.configFile,
-			EnvVars:     []string{"CONFIG_FILE"},
+			Name:        "metrics-address",
+			Usage:       "the address at which MPS metrics are served in the Prometheus format at /metrics; metrics are disabled if empty",
+			Destination: &config.metricsAddress,
+			EnvVars:     []string{"METRICS_ADDRESS"},
 		},
-		&cli.StringFlag{
-			Name:    "mig-strategy",
-			Value:   spec.MigStrategyNone,
-			Usage:   "the desired strategy for exposing MIG devices on GPUs that support it:\n\t\t[none | single | mixed]",
-			EnvVars: []string{"MIG_STRATEGY"},
-		},
-	}
+	)
 	c.Flags = config.flags
 
 	klog.InfoS(c.Name, "version", c.Version)
@@ -85,21 +84,12 @@ func main() {
 	}
 }
 
-// TODO: This needs to do similar validation to the plugin.
-func validateFlags(config *spec.Config) error {
-	return nil
-}
-
 // loadConfig loads the config from the spec file.
 func (cfg *Config) loadConfig(c *cli.Context) (*spec.Config, error) {
 	config, err := spec.NewConfig(c, cfg.flags)
 	if err != nil {
 		return nil, fmt.Errorf("unable to finalize config: %w", err)
 	}
-	err = validateFlags(config)
-	if err != nil {
-		return nil, fmt.Errorf("unable to validate flags: %w", err)
-	}
 	config.Flags.GFD = nil
 
 	return config, nil
@@ -111,17 +101,26 @@ func start(c *cli.Context, cfg *Config) error {
 	var started bool
 	var restartTimeout <-chan time.Time
 	var daemons []*mps.Daemon
+	var root mps.Root
+
+	metrics := mps.NewMetrics()
+	if cfg.metricsAddress != "" {
+		server := startMetricsServer(cfg.metricsAddress, metrics)
+		defer func() {
+			_ = server.Close()
+		}()
+	}
 restart:
 	// If we are restarting, stop daemons from previous run.
 	if started {
-		err := stopDaemons(daemons...)
+		err := mps.StopDaemons(root, daemons...)
 		if err != nil {
 			return fmt.Errorf("error stopping plugins from previous run: %v", err)
 		}
 	}
 
 	klog.Info("Starting Daemons.")
-	daemons, restartDaemons, err := startDaemons(c, cfg)
+	root, daemons, restartDaemons, err := startDaemons(c, cfg, metrics)
 	if err != nil {
 		return fmt.Errorf("error starting plugins: %v", err)
 	}
@@ -155,81 +154,106 @@ restart:
 		}
 	}
 exit:
-	if err := stopDaemons(daemons...); err != nil {
+	if err := mps.StopDaemons(root, daemons...); err != nil {
 		return fmt.Errorf("error stopping daemons: %v", err)
 	}
 	return nil
 }
 
-func startDaemons(c *cli.Context, cfg *Config) ([]*mps.Daemon, bool, error) {
+// startMetricsServer serves the metrics of the MPS daemons at the specified
+// address in the background.
+func startMetricsServer(address string, metrics *mps.Metrics) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		klog.InfoS("Serving MPS metrics", "address", address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.ErrorS(err, "Failed to serve MPS metrics", "address", address)
+		}
+	}()
+	return server
+}
+
+func startDaemons(c *cli.Context, cfg *Config, metrics *mps.Metrics) (mps.Root, []*mps.Daemon, bool, error) {
 	// Load the configuration file
 	klog.Info("Loading configuration.")
 	config, err := cfg.loadConfig(c)
 	if err != nil {
-		return nil, false, fmt.Errorf("unable to load config: %v", err)
+		return "", nil, false, fmt.Errorf("unable to load config: %v", err)
 	}
-	spec.DisableResourceNamingInConfig(config)
+	// If the device plugin manages the MPS daemons, no daemons are started.
+	// An empty root is returned so that the ready file of the daemons that are
+	// started by the device plugin is not removed.
+	if config.Flags.ManageMpsDaemons != nil && *config.Flags.ManageMpsDaemons {
+		klog.Info("MPS daemons are managed by the device plugin; Waiting indefinitely.")
+		return "", nil, false, nil
+	}
+	root := mps.ContainerRootFor(config)
 
-	nvmllib := nvml.New()
+	driverRoot := driverroot.Root(*config.Flags.Plugin.ContainerDriverRoot)
+	// We construct an NVML library specifying the path to libnvidia-ml.so.1
+	// explicitly so that we don't have to rely on the library path.
+	nvmllib := nvml.New(
+		nvml.WithLibraryPath(driverRoot.TryResolveLibrary("libnvidia-ml.so.1")),
+	)
 	devicelib := device.New(nvmllib)
 	infolib := nvinfo.New(
+		nvinfo.WithRoot(string(driverRoot)),
 		nvinfo.WithNvmlLib(nvmllib),
 		nvinfo.WithDeviceLib(devicelib),
 	)
 
+	err = flags.ValidateMPSDaemonFlags(config)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("unable to validate flags: %v", err)
+	}
+
 	// Update the configuration file with default resources.
 	klog.Info("Updating config with default resource matching patterns.")
 	err = rm.AddDefaultResourcesToConfig(infolib, nvmllib, devicelib, config)
 	if err != nil {
-		return nil, false, fmt.Errorf("unable to add default resources to config: %v", err)
+		return "", nil, false, fmt.Errorf("unable to add default resources to config: %v", err)
 	}
 
 	// Print the config to the output.
 	configJSON, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to marshal config to JSON: %v", err)
+		return "", nil, false, fmt.Errorf("failed to marshal config to JSON: %v", err)
 	}
 	klog.Infof("\nRunning with config:\n%v", string(configJSON))
 
+	// Restore the compute modes of GPUs of daemons that were not stopped,
+	// e.g. since a previous run crashed.
+	if err := mps.RestoreComputeModes(nvmllib, root); err != nil {
+		return root, nil, false, fmt.Errorf("error restoring compute modes: %v", err)
+	}
+
 	// Get the set of daemons.
 	// Note that a daemon is only created for resources with at least one device.
 	klog.Info("Retrieving MPS daemons.")
 	mpsDaemons, err := mps.NewDaemons(infolib, nvmllib, devicelib,
 		mps.WithConfig(config),
+		mps.WithMetrics(metrics),
 	)
 	if err != nil {
-		return nil, false, fmt.Errorf("error getting daemons: %v", err)
+		return "", nil, false, fmt.Errorf("error getting daemons: %v", err)
 	}
 
 	if len(mpsDaemons) == 0 {
 		klog.Info("No devices are configured for MPS sharing; Waiting indefinitely.")
 	}
 
-	// Loop through all MPS daemons and start them.
+	// Start all MPS daemons.
 	// If any daemon fails to start, all daemons are started again.
-	for _, mpsDaemon := range mpsDaemons {
-		if err := mpsDaemon.Start(); err != nil {
-			klog.Errorf("Failed to start MPS daemon: %v", err)
-			return mpsDaemons, true, nil
-		}
-	}
-	readyFile, err := os.Create("/mps/.ready")
-	if err != nil {
-		return mpsDaemons, true, fmt.Errorf("failed to create .ready file")
+	if err := mps.StartDaemons(root, mpsDaemons...); err != nil {
+		klog.Errorf("Failed to start MPS daemons: %v", err)
+		return root, mpsDaemons, true, nil
 	}
-	defer readyFile.Close()
 
-	return mpsDaemons, false, nil
-}
-
-func stopDaemons(mpsDaemons ...*mps.Daemon) error {
-	if err := os.Remove("/mps/.ready"); err != nil {
-		klog.Warningf("Failed to remove .ready file: %v", err)
-	}
-	klog.Info("Stopping MPS daemons.")
-	var errs error
-	for _, p := range mpsDaemons {
-		errs = errors.Join(errs, p.Stop())
-	}
-	return errs
+	return root, mpsDaemons, false, nil
 }
@@ -21,35 +21,53 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/urfave/cli/v2"
 	"k8s.io/klog/v2"
 	"k8s.io/mount-utils"
+
+	"github.com/NVIDIA/k8s-device-plugin/cmd/mps-control-daemon/mps"
 )
 
+const devShmDir = "/dev/shm"
+
 // NewCommand constructs a mount command.
 func NewCommand() *cli.Command {
 	c := cli.Command{
 		Name:   "mount-shm",
 		Usage:  "Set up the /dev/shm mount required by the MPS daemon",
 		Action: mountShm,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "mps-root",
+				Value:   string(mps.ContainerRoot),
+				Usage:   "the MPS root, which is mounted at the same path in the container; the tmpfs is mounted at shm in this path",
+				EnvVars: []string{"MPS_ROOT"},
+			},
+		},
 	}
 
 	return &c
 }
 
-// mountShm creates a tmpfs mount at /mps/shm to be used by the mps control daemon.
+// mountShm creates a tmpfs mount at shm in the MPS root to be used by the mps
+// control daemon.
 func mountShm(c *cli.Context) error {
-	mountExecutable, err := exec.LookPath("mount")
+	return Shm(c.String("mps-root"))
+}
+
+// Shm creates a tmpfs mount at shm in the specified MPS root. An existing
+// mount at this path is replaced.
+func Shm(mpsRoot string) error {
+	mounter, err := newMounter()
 	if err != nil {
-		return fmt.Errorf("error finding 'mount' executable: %w", err)
+		return err
 	}
-	mounter := mount.New(mountExecutable)
 
-	// TODO: /mps should be configurable.
-	shmDir := "/mps/shm"
+	shmDir := filepath.Join(mpsRoot, "shm")
 	err = mount.CleanupMountPoint(shmDir, mounter, true)
 	if err != nil {
 		return fmt.Errorf("error unmounting %v: %w", shmDir, err)
@@ -60,7 +78,10 @@ func mountShm(c *cli.Context) error {
 	}
 
 	sizeArg := fmt.Sprintf("size=%v", getDefaultShmSize())
-	mountOptions := []string{"rw", "nosuid", "nodev", "noexec", "relatime", sizeArg}
+	// The tmpfs is shared by all MPS daemons and their clients, which may run
+	// as different users, so it is world-writable with the sticky bit set as
+	// is the case for /dev/shm.
+	mountOptions := []string{"rw", "nosuid", "nodev", "noexec", "relatime", "mode=1777", sizeArg}
 	if err := mounter.Mount("shm", shmDir, "tmpfs", mountOptions); err != nil {
 		return fmt.Errorf("error mounting %v as tmpfs: %w", shmDir, err)
 	}
@@ -68,6 +89,32 @@ func mountShm(c *cli.Context) error {
 	return nil
 }
 
+// BindDevShm bind mounts the shm directory of the specified MPS root at
+// /dev/shm. MPS daemons that are started by the calling process then share
+// the tmpfs created by Shm with their clients. This is not required if the
+// directory is mounted at /dev/shm when the container is started.
+func BindDevShm(mpsRoot string) error {
+	mounter, err := newMounter()
+	if err != nil {
+		return err
+	}
+
+	shmDir := filepath.Join(mpsRoot, "shm")
+	if err := mounter.Mount(shmDir, devShmDir, "", []string{"bind"}); err != nil {
+		return fmt.Errorf("error bind mounting %v at %v: %w", shmDir, devShmDir, err)
+	}
+
+	return nil
+}
+
+func newMounter() (mount.Interface, error) {
+	mountExecutable, err := exec.LookPath("mount")
+	if err != nil {
+		return nil, fmt.Errorf("error finding 'mount' executable: %w", err)
+	}
+	return mount.New(mountExecutable), nil
+}
+
 // getDefaultShmSize returns the default size for the tmpfs to be created.
 // This reads /proc/meminfo to get the total memory to calculate this. If this
 // fails a fallback size of 65536k is used.
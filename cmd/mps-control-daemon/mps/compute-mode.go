@@ -0,0 +1,216 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"k8s.io/klog/v2"
+)
+
+// computeModeStateFileName is the name of the file in the directory of a
+// daemon that records the original compute modes of its GPUs.
+const computeModeStateFileName = ".compute-modes.json"
+
+// computeModes maps the UUIDs of GPUs to their compute modes.
+type computeModes map[string]nvml.ComputeMode
+
+func (d *Daemon) computeModeStateFile() string {
+	return d.root.daemonPath(d.resource, d.migUUID, computeModeStateFileName)
+}
+
+// setExclusiveProcessComputeMode records the compute modes of the GPUs
+// controlled by the daemon and sets them to EXCLUSIVE_PROCESS. The original
+// modes are recorded in a state file before any mode is changed so that they
+// can be restored even if the daemon crashes. The compute mode applies to a
+// full GPU and is therefore left unchanged for MIG devices since the other MIG
+// devices of the GPU may not be shared.
+func (d *Daemon) setExclusiveProcessComputeMode() error {
+	if d.migUUID != "" {
+		return nil
+	}
+	if err := d.initNVML(); err != nil {
+		return err
+	}
+	defer d.shutdownNVML()
+
+	// Modes recorded by a previous run are restored first so that they are
+	// not replaced by the modes set by that run.
+	if err := restoreComputeModes(d.nvmllib, d.computeModeStateFile()); err != nil {
+		return err
+	}
+
+	original := make(computeModes)
+	for _, uuid := range d.Devices().GetUUIDs() {
+		device, ret := d.nvmllib.DeviceGetHandleByUUID(uuid)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting device handle for %v: %v", uuid, ret)
+		}
+		mode, ret := device.GetComputeMode()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting compute mode of %v: %v", uuid, ret)
+		}
+		original[uuid] = mode
+	}
+	if err := writeComputeModes(d.computeModeStateFile(), original); err != nil {
+		return err
+	}
+
+	return setComputeModes(d.nvmllib, uniformComputeModes(original, nvml.COMPUTEMODE_EXCLUSIVE_PROCESS))
+}
+
+// restoreComputeMode restores the compute modes recorded for the GPUs of the
+// daemon and removes the state file.
+func (d *Daemon) restoreComputeMode() error {
+	if d.migUUID != "" {
+		return nil
+	}
+	if err := d.initNVML(); err != nil {
+		return err
+	}
+	defer d.shutdownNVML()
+
+	return restoreComputeModes(d.nvmllib, d.computeModeStateFile())
+}
+
+// RestoreComputeModes restores the compute modes recorded by daemons below
+// the specified root that were not stopped cleanly, e.g. since the MPS control
+// daemon crashed. This is expected to be called before any daemon is started.
+func RestoreComputeModes(nvmllib nvml.Interface, root Root) error {
+	var stateFiles []string
+	err := filepath.WalkDir(string(root), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entry.IsDir() && entry.Name() == computeModeStateFileName {
+			stateFiles = append(stateFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error finding compute mode state files: %w", err)
+	}
+	if len(stateFiles) == 0 {
+		return nil
+	}
+
+	if ret := nvmllib.Init(); ret != nvml.SUCCESS {
+		return fmt.Errorf("error initializing NVML: %v", ret)
+	}
+	defer func() {
+		_ = nvmllib.Shutdown()
+	}()
+
+	var errs error
+	for _, stateFile := range stateFiles {
+		klog.InfoS("Restoring compute modes of a daemon that was not stopped", "stateFile", stateFile)
+		errs = errors.Join(errs, restoreComputeModes(nvmllib, stateFile))
+	}
+	return errs
+}
+
+// restoreComputeModes sets the compute modes recorded in the specified state
+// file and removes the file once all modes were restored.
+func restoreComputeModes(nvmllib nvml.Interface, stateFile string) error {
+	modes, err := readComputeModes(stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := setComputeModes(nvmllib, modes); err != nil {
+		return err
+	}
+	if err := os.Remove(stateFile); err != nil {
+		return fmt.Errorf("error removing %v: %w", stateFile, err)
+	}
+	return nil
+}
+
+// setComputeModes sets the compute mode of each of the specified GPUs.
+func setComputeModes(nvmllib nvml.Interface, modes computeModes) error {
+	for uuid, mode := range modes {
+		device, ret := nvmllib.DeviceGetHandleByUUID(uuid)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting device handle for %v: %v", uuid, ret)
+		}
+		if ret := device.SetComputeMode(mode); ret != nvml.SUCCESS {
+			return fmt.Errorf("error setting compute mode of %v to %v: %v", uuid, mode, ret)
+		}
+		klog.InfoS("Set compute mode", "device", uuid, "mode", mode)
+	}
+	return nil
+}
+
+// uniformComputeModes returns the specified mode for each of the GPUs.
+func uniformComputeModes(modes computeModes, mode nvml.ComputeMode) computeModes {
+	uniform := make(computeModes)
+	for uuid := range modes {
+		uniform[uuid] = mode
+	}
+	return uniform
+}
+
+func readComputeModes(stateFile string) (computeModes, error) {
+	contents, err := os.ReadFile(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	var modes computeModes
+	if err := json.Unmarshal(contents, &modes); err != nil {
+		return nil, fmt.Errorf("error parsing %v: %w", stateFile, err)
+	}
+	return modes, nil
+}
+
+func writeComputeModes(stateFile string, modes computeModes) error {
+	contents, err := json.Marshal(modes)
+	if err != nil {
+		return fmt.Errorf("error marshalling compute modes: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %v: %w", stateFile, err)
+	}
+	if err := os.WriteFile(stateFile, contents, 0644); err != nil {
+		return fmt.Errorf("error writing %v: %w", stateFile, err)
+	}
+	return nil
+}
+
+func (d *Daemon) initNVML() error {
+	if d.nvmllib == nil {
+		return fmt.Errorf("no NVML library configured for daemon %v", d.name())
+	}
+	if ret := d.nvmllib.Init(); ret != nvml.SUCCESS {
+		return fmt.Errorf("error initializing NVML: %v", ret)
+	}
+	return nil
+}
+
+func (d *Daemon) shutdownNVML() {
+	_ = d.nvmllib.Shutdown()
+}
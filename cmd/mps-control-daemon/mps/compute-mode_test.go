@@ -0,0 +1,156 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"os"
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
+	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
+)
+
+// newComputeModeServer returns a mock NVML server whose devices have the
+// specified compute modes. Setting a compute mode updates the map.
+func newComputeModeServer(modes map[int]nvml.ComputeMode) *dgxa100.Server {
+	server := dgxa100.New()
+	for i, d := range server.Devices {
+		device := d.(*dgxa100.Device)
+		index := i
+		device.GetComputeModeFunc = func() (nvml.ComputeMode, nvml.Return) {
+			return modes[index], nvml.SUCCESS
+		}
+		device.SetComputeModeFunc = func(mode nvml.ComputeMode) nvml.Return {
+			modes[index] = mode
+			return nvml.SUCCESS
+		}
+	}
+	return server
+}
+
+func newComputeModeDaemon(server *dgxa100.Server, root Root, indices ...int) *Daemon {
+	devices := make(rm.Devices)
+	for _, i := range indices {
+		uuid := server.Devices[i].(*dgxa100.Device).UUID
+		for r := 0; r < 2; r++ {
+			id := string(rm.NewAnnotatedID(uuid, r))
+			devices[id] = &rm.Device{
+				Device:   pluginapi.Device{ID: id},
+				Replicas: 2,
+			}
+		}
+	}
+	d := newDaemon("nvidia.com/gpu", devices, root)
+	d.nvmllib = server
+	return d
+}
+
+func TestComputeMode(t *testing.T) {
+	modes := map[int]nvml.ComputeMode{
+		0: nvml.COMPUTEMODE_DEFAULT,
+		1: nvml.COMPUTEMODE_PROHIBITED,
+		2: nvml.COMPUTEMODE_DEFAULT,
+	}
+	server := newComputeModeServer(modes)
+	d := newComputeModeDaemon(server, Root(t.TempDir()), 0, 1)
+
+	require.NoError(t, d.setExclusiveProcessComputeMode())
+	require.Equal(t,
+		map[int]nvml.ComputeMode{
+			0: nvml.COMPUTEMODE_EXCLUSIVE_PROCESS,
+			1: nvml.COMPUTEMODE_EXCLUSIVE_PROCESS,
+			2: nvml.COMPUTEMODE_DEFAULT,
+		},
+		modes,
+	)
+	require.FileExists(t, d.computeModeStateFile())
+
+	require.NoError(t, d.restoreComputeMode())
+	require.Equal(t,
+		map[int]nvml.ComputeMode{
+			0: nvml.COMPUTEMODE_DEFAULT,
+			1: nvml.COMPUTEMODE_PROHIBITED,
+			2: nvml.COMPUTEMODE_DEFAULT,
+		},
+		modes,
+	)
+	_, err := os.Stat(d.computeModeStateFile())
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// Restoring without a state file leaves the modes unchanged.
+	require.NoError(t, d.restoreComputeMode())
+	require.Equal(t, nvml.COMPUTEMODE_PROHIBITED, modes[1])
+}
+
+func TestComputeModeAfterCrash(t *testing.T) {
+	modes := map[int]nvml.ComputeMode{
+		0: nvml.COMPUTEMODE_PROHIBITED,
+		1: nvml.COMPUTEMODE_DEFAULT,
+	}
+	server := newComputeModeServer(modes)
+	root := Root(t.TempDir())
+
+	// A daemon that crashed leaves its GPUs in EXCLUSIVE_PROCESS mode.
+	crashed := newComputeModeDaemon(server, root, 0, 1)
+	require.NoError(t, crashed.setExclusiveProcessComputeMode())
+	require.Equal(t, nvml.COMPUTEMODE_EXCLUSIVE_PROCESS, modes[0])
+
+	t.Run("restored on startup", func(t *testing.T) {
+		require.NoError(t, RestoreComputeModes(server, root))
+		require.Equal(t,
+			map[int]nvml.ComputeMode{
+				0: nvml.COMPUTEMODE_PROHIBITED,
+				1: nvml.COMPUTEMODE_DEFAULT,
+			},
+			modes,
+		)
+		_, err := os.Stat(crashed.computeModeStateFile())
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("restored before the modes are recorded again", func(t *testing.T) {
+		require.NoError(t, crashed.setExclusiveProcessComputeMode())
+
+		restarted := newComputeModeDaemon(server, root, 0, 1)
+		require.NoError(t, restarted.setExclusiveProcessComputeMode())
+		require.NoError(t, restarted.restoreComputeMode())
+		require.Equal(t, nvml.COMPUTEMODE_PROHIBITED, modes[0])
+		require.Equal(t, nvml.COMPUTEMODE_DEFAULT, modes[1])
+	})
+
+	t.Run("no state files", func(t *testing.T) {
+		require.NoError(t, RestoreComputeModes(server, Root(t.TempDir()+"/missing")))
+	})
+}
+
+func TestComputeModeMigDevice(t *testing.T) {
+	devices := rm.Devices{
+		"MIG-0::0": &rm.Device{
+			Device: pluginapi.Device{ID: "MIG-0::0"},
+			Index:  "0:0",
+		},
+	}
+	d := newDaemon("nvidia.com/mig-1g.5gb", devices, Root(t.TempDir()))
+
+	// No NVML library is required since the compute mode is not changed.
+	require.NoError(t, d.setExclusiveProcessComputeMode())
+	require.NoError(t, d.restoreComputeMode())
+}
@@ -17,28 +17,28 @@
 package mps
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
 
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
 	"github.com/opencontainers/selinux/go-selinux"
 	"k8s.io/klog/v2"
 
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/internal/mpscontrol"
 	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
 )
 
-type computeMode string
-
 const (
 	mpsControlBin = "nvidia-cuda-mps-control"
 
-	computeModeExclusiveProcess = computeMode("EXCLUSIVE_PROCESS")
-	computeModeDefault          = computeMode("DEFAULT")
-
 	unprivilegedContainerSELinuxLabel = "system_u:object_r:container_file_t:s0"
 )
 
@@ -47,25 +47,111 @@ const (
 // starting and stopping the deamon as well as ensuring that the memory and
 // thread limits are set for the devices that the resource makes available.
 type Daemon struct {
-	rm rm.ResourceManager
+	// resource is the resource that the daemon is named after. For replica
+	// classes this is the resource that the classes were carved from.
+	resource spec.ResourceName
+	// devices are the devices under the control of the daemon.
+	devices rm.Devices
+	// migUUID is the UUID of the MIG device controlled by the daemon and
+	// empty for daemons that control full GPUs.
+	migUUID string
 	// root represents the root at which the files and folders controlled by the
 	// daemon are created. These include the log and pipe directories.
 	root Root
-	// logTailer tails the MPS control daemon logs.
-	logTailer *tailer
+	// logFollowers follow the MPS control and server logs.
+	logFollowers []*logFollower
+	// serverStarted records whether an MPS server was started by the daemon.
+	// It is set by the followers of both the control and the server log.
+	serverStarted atomic.Bool
+	// nvmllib is used to set the compute mode of the GPUs of the daemon.
+	nvmllib nvml.Interface
+	// metrics are the metrics that the daemon reports to if set.
+	metrics *Metrics
+	// stopMonitor stops the periodic collection of metrics.
+	stopMonitor context.CancelFunc
+	monitorDone chan struct{}
 }
 
 // NewDaemon creates an MPS daemon instance.
 func NewDaemon(rm rm.ResourceManager, root Root) *Daemon {
-	return &Daemon{
-		rm:   rm,
-		root: root,
+	return newDaemon(daemonResource(rm.Resource(), rm.Devices()), rm.Devices(), root)
+}
+
+func newDaemon(resource spec.ResourceName, devices rm.Devices, root Root) *Daemon {
+	d := &Daemon{
+		resource: resource,
+		devices:  devices,
+		root:     root,
+	}
+	for _, device := range devices {
+		if device.IsMigDevice() {
+			d.migUUID = device.GetUUID()
+		}
+		break
+	}
+	return d
+}
+
+// NewDaemonsForResource creates the MPS daemon instances for the devices of a
+// resource. A daemon is created for each MIG device since an MPS server only
+// controls a single MIG device, while all full GPUs share a single daemon.
+func NewDaemonsForResource(rm rm.ResourceManager, root Root) []*Daemon {
+	return newDaemons(daemonResource(rm.Resource(), rm.Devices()), rm.Devices(), root)
+}
+
+func newDaemons(resource spec.ResourceName, devices rm.Devices, root Root) []*Daemon {
+	var uuids []string
+	devicesByDaemon := make(map[string]rm.Devices)
+	for id, device := range devices {
+		var uuid string
+		if device.IsMigDevice() {
+			uuid = device.GetUUID()
+		}
+		if _, exists := devicesByDaemon[uuid]; !exists {
+			uuids = append(uuids, uuid)
+			devicesByDaemon[uuid] = make(rm.Devices)
+		}
+		devicesByDaemon[uuid][id] = device
+	}
+	sort.Strings(uuids)
+
+	var daemons []*Daemon
+	for _, uuid := range uuids {
+		daemons = append(daemons, newDaemon(resource, devicesByDaemon[uuid], root))
+	}
+	return daemons
+}
+
+// daemonResource returns the resource that the daemon controlling the
+// specified devices is named after. Replicas of all classes of a resource
+// share the daemon of that resource since a GPU can only be controlled by a
+// single MPS daemon.
+func daemonResource(resource spec.ResourceName, devices rm.Devices) spec.ResourceName {
+	for _, device := range devices {
+		if device.SharedResource != "" {
+			return device.SharedResource
+		}
 	}
+	return resource
+}
+
+// name returns the name of the daemon in logs and metrics. This is the
+// resource of the daemon, followed by the UUID of its MIG device if any.
+func (d *Daemon) name() string {
+	if d.migUUID == "" {
+		return string(d.resource)
+	}
+	return string(d.resource) + "/" + d.migUUID
+}
+
+// Resource returns the resource that the daemon is named after.
+func (d *Daemon) Resource() spec.ResourceName {
+	return d.resource
 }
 
 // Devices returns the list of devices under the control of this MPS daemon.
 func (d *Daemon) Devices() rm.Devices {
-	return d.rm.Devices()
+	return d.devices
 }
 
 type envvars map[string]string
@@ -80,21 +166,31 @@ func (e envvars) toSlice() []string {
 
 // EnvVars returns the environment variables required for the daemon.
 // These should be passed to clients consuming the device shared using MPS.
-// TODO: Set CUDA_VISIBLE_DEVICES to include only the devices for this resource type.
+// CUDA_VISIBLE_DEVICES restricts the daemon to the devices of its resource so
+// that the daemons for different resources do not control the same devices.
 func (d *Daemon) EnvVars() envvars {
 	return map[string]string{
 		"CUDA_MPS_PIPE_DIRECTORY": d.PipeDir(),
 		"CUDA_MPS_LOG_DIRECTORY":  d.LogDir(),
+		"CUDA_VISIBLE_DEVICES":    strings.Join(d.Devices().GetUUIDs(), ","),
 	}
 }
 
 // Start starts the MPS deamon as a background process.
 func (d *Daemon) Start() error {
-	if err := d.setComputeMode(computeModeExclusiveProcess); err != nil {
-		return fmt.Errorf("error setting compute mode %v: %w", computeModeExclusiveProcess, err)
+	if err := d.assertLimits(); err != nil {
+		return fmt.Errorf("invalid MPS limits: %w", err)
+	}
+	user, err := d.User()
+	if err != nil {
+		return fmt.Errorf("invalid MPS user: %w", err)
+	}
+
+	if err := d.setExclusiveProcessComputeMode(); err != nil {
+		return fmt.Errorf("error setting compute mode %v: %w", nvml.COMPUTEMODE_EXCLUSIVE_PROCESS, err)
 	}
 
-	klog.InfoS("Staring MPS daemon", "resource", d.rm.Resource())
+	klog.InfoS("Staring MPS daemon", "resource", d.resource, "migDevice", d.migUUID, "user", user)
 
 	pipeDir := d.PipeDir()
 	if err := os.MkdirAll(pipeDir, 0755); err != nil {
@@ -110,35 +206,42 @@ func (d *Daemon) Start() error {
 		return fmt.Errorf("error creating directory %v: %w", logDir, err)
 	}
 
+	if err := chownToUser(user, pipeDir, logDir); err != nil {
+		return err
+	}
+
 	mpsDaemon := exec.Command(mpsControlBin, "-d")
 	mpsDaemon.Env = append(mpsDaemon.Env, d.EnvVars().toSlice()...)
+	setUser(mpsDaemon, user)
 	if err := mpsDaemon.Run(); err != nil {
 		return err
 	}
 
+	client := d.Client()
 	for index, limit := range d.perDevicePinnedDeviceMemoryLimits() {
-		_, err := d.EchoPipeToControl(fmt.Sprintf("set_default_device_pinned_mem_limit %s %s", index, limit))
-		if err != nil {
+		if err := client.SetDefaultDevicePinnedMemoryLimit(index, limit); err != nil {
 			return fmt.Errorf("error setting pinned memory limit for device %v: %w", index, err)
 		}
 	}
-	if threadPercentage := d.activeThreadPercentage(); threadPercentage != "" {
-		_, err := d.EchoPipeToControl(fmt.Sprintf("set_default_active_thread_percentage %s", threadPercentage))
-		if err != nil {
+	if threadPercentage := d.activeThreadPercentage(); threadPercentage != 0 {
+		if err := client.SetDefaultActiveThreadPercentage(threadPercentage); err != nil {
 			return fmt.Errorf("error setting active thread percentage: %w", err)
 		}
 	}
 
-	statusFile, err := os.Create(d.startedFile())
-	if err != nil {
-		return err
+	klog.InfoS("Following MPS logs", "daemon", d.name())
+	for _, log := range []string{"control.log", "server.log"} {
+		follower := newLogFollower(filepath.Join(logDir, log), d.logHandler(log))
+		follower.Start()
+		d.logFollowers = append(d.logFollowers, follower)
+	}
+
+	if d.metrics != nil {
+		d.startMonitor()
 	}
-	defer statusFile.Close()
 
-	d.logTailer = newTailer(filepath.Join(logDir, "control.log"))
-	klog.InfoS("Starting log tailer", "resource", d.rm.Resource())
-	if err := d.logTailer.Start(); err != nil {
-		klog.ErrorS(err, "Could not start tail command on control.log; ignoring logs")
+	if err := d.writeReadyFile(); err != nil {
+		return fmt.Errorf("error writing ready file: %w", err)
 	}
 
 	return nil
@@ -159,21 +262,24 @@ func setSELinuxContext(path string, context string) error {
 
 // Stop ensures that the MPS daemon is quit.
 func (d *Daemon) Stop() error {
-	_, err := d.EchoPipeToControl("quit")
-	if err != nil {
+	if err := d.Client().Quit(); err != nil {
 		return fmt.Errorf("error sending quit message: %w", err)
 	}
-	klog.InfoS("Stopped MPS control daemon", "resource", d.rm.Resource())
+	klog.InfoS("Stopped MPS control daemon", "resource", d.resource)
 
-	err = d.logTailer.Stop()
-	klog.InfoS("Stopped log tailer", "resource", d.rm.Resource(), "error", err)
+	d.stopMonitoring()
+	for _, follower := range d.logFollowers {
+		follower.Stop()
+	}
+	d.logFollowers = nil
+	klog.InfoS("Stopped following MPS logs", "daemon", d.name())
 
-	if err := d.setComputeMode(computeModeDefault); err != nil {
-		return fmt.Errorf("error setting compute mode %v: %w", computeModeDefault, err)
+	if err := d.restoreComputeMode(); err != nil {
+		return fmt.Errorf("error restoring compute mode: %w", err)
 	}
 
-	if err := os.Remove(d.startedFile()); err != nil && err != os.ErrNotExist {
-		return fmt.Errorf("failed to remove started file: %w", err)
+	if err := os.Remove(d.ReadyFile()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove ready file: %w", err)
 	}
 
 	logDir := d.LogDir()
@@ -185,96 +291,143 @@ func (d *Daemon) Stop() error {
 }
 
 func (d *Daemon) LogDir() string {
-	return d.root.LogDir(d.rm.Resource())
+	return d.root.daemonPath(d.resource, d.migUUID, "log")
 }
 
 func (d *Daemon) PipeDir() string {
-	return d.root.PipeDir(d.rm.Resource())
+	return d.root.daemonPath(d.resource, d.migUUID, "pipe")
+}
+
+// HostPipeDir returns the pipe dir of the daemon under the specified root on
+// the host.
+func (d *Daemon) HostPipeDir(hostRoot Root) string {
+	return hostRoot.daemonPath(d.resource, d.migUUID, "pipe")
 }
 
 func (d *Daemon) ShmDir() string {
 	return "/dev/shm"
 }
 
-func (d *Daemon) startedFile() string {
-	return d.root.startedFile(d.rm.Resource())
+// Client returns a client for the control pipe of the MPS daemon. Commands are
+// sent to the control socket in the pipe directory so that no
+// nvidia-cuda-mps-control process is started per command.
+func (d *Daemon) Client() *mpscontrol.Client {
+	return mpscontrol.New(d.PipeDir())
 }
 
 // AssertHealthy checks that the MPS control daemon is healthy.
 func (d *Daemon) AssertHealthy() error {
-	_, err := d.EchoPipeToControl("get_default_active_thread_percentage")
+	_, err := d.Client().GetDefaultActiveThreadPercentage()
 	return err
 }
 
-// EchoPipeToControl sends the specified command to the MPS control daemon.
-func (d *Daemon) EchoPipeToControl(command string) (string, error) {
-	var out bytes.Buffer
-	reader, writer := io.Pipe()
-	defer writer.Close()
-	defer reader.Close()
-
-	mpsDaemon := exec.Command(mpsControlBin)
-	mpsDaemon.Env = append(mpsDaemon.Env, d.EnvVars().toSlice()...)
-
-	mpsDaemon.Stdin = reader
-	mpsDaemon.Stdout = &out
-
-	if err := mpsDaemon.Start(); err != nil {
-		return "", fmt.Errorf("failed to start NVIDIA MPS command: %w", err)
+// perDevicePinnedMemoryLimits returns the pinned memory limits in bytes for
+// each device.
+// Since the daemon only sees the devices in CUDA_VISIBLE_DEVICES, devices are
+// referenced by their ordinal in this list instead of their NVML index.
+// Devices split into replica classes use the limit of their smallest class as
+// the default; clients of larger classes raise it through their environment.
+func (m *Daemon) perDevicePinnedDeviceMemoryLimits() map[int]uint64 {
+	ordinals := make(map[string]int)
+	for i, uuid := range m.Devices().GetUUIDs() {
+		ordinals[uuid] = i
 	}
 
-	if _, err := writer.Write([]byte(command)); err != nil {
-		return "", fmt.Errorf("failed to write message to pipe: %w", err)
+	totalMemoryInBytesPerDevice := make(map[int]uint64)
+	replicasPerDevice := make(map[int]uint64)
+	replicaMemoryPerDevice := make(map[int]uint64)
+	for _, device := range m.Devices() {
+		index := ordinals[device.GetUUID()]
+		totalMemoryInBytesPerDevice[index] = device.TotalMemory
+		replicasPerDevice[index] += 1
+		if memory := device.ReplicaMemory; memory != 0 {
+			if current, ok := replicaMemoryPerDevice[index]; !ok || memory < current {
+				replicaMemoryPerDevice[index] = memory
+			}
+		}
 	}
-	_ = writer.Close()
 
-	if err := mpsDaemon.Wait(); err != nil {
-		return "", fmt.Errorf("failed to send command to MPS daemon: %w", err)
+	limits := make(map[int]uint64)
+	for index, totalMemory := range totalMemoryInBytesPerDevice {
+		if memory, ok := replicaMemoryPerDevice[index]; ok {
+			limits[index] = memory
+			continue
+		}
+		if totalMemory == 0 {
+			continue
+		}
+		replicas := replicasPerDevice[index]
+		limits[index] = totalMemory / replicas
 	}
-	return out.String(), nil
+	return limits
 }
 
-func (d *Daemon) setComputeMode(mode computeMode) error {
-	for _, uuid := range d.Devices().GetUUIDs() {
-		cmd := exec.Command(
-			"nvidia-smi",
-			"-i", uuid,
-			"-c", string(mode))
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			klog.Errorf("\n%v", string(output))
-			return fmt.Errorf("error running nvidia-smi: %w", err)
+// assertLimits checks that the limits of the replicas are valid for their
+// devices. The pinned memory limits of the replicas of a device must not
+// exceed its memory in total.
+func (m *Daemon) assertLimits() error {
+	requiredMemoryPerDevice := make(map[string]uint64)
+	totalMemoryPerDevice := make(map[string]uint64)
+	for _, d := range m.Devices() {
+		if d.TotalMemory != 0 && d.ReplicaMemory > d.TotalMemory {
+			return fmt.Errorf("pinned memory limit of %vM for %v exceeds the device memory of %vM", d.ReplicaMemory/1024/1024, d.ID, d.TotalMemory/1024/1024)
+		}
+		if d.ActiveThreadPercentage < 0 || d.ActiveThreadPercentage > 100 {
+			return fmt.Errorf("active thread percentage %v for %v must be between 0 and 100", d.ActiveThreadPercentage, d.ID)
+		}
+		uuid := d.GetUUID()
+		requiredMemoryPerDevice[uuid] += d.ReplicaMemory
+		totalMemoryPerDevice[uuid] = d.TotalMemory
+	}
+	for uuid, required := range requiredMemoryPerDevice {
+		if total := totalMemoryPerDevice[uuid]; total != 0 && required > total {
+			return fmt.Errorf("pinned memory limits of %vM for the replicas of %v exceed the device memory of %vM", required/1024/1024, uuid, total/1024/1024)
 		}
 	}
 	return nil
 }
 
-// perDevicePinnedMemoryLimits returns the pinned memory limits for each device.
-func (m *Daemon) perDevicePinnedDeviceMemoryLimits() map[string]string {
-	totalMemoryInBytesPerDevice := make(map[string]uint64)
-	replicasPerDevice := make(map[string]uint64)
+// perDeviceActiveThreadPercentages returns the default active thread
+// percentage for each device. This is the smallest percentage of any replica
+// of the device; replicas with a larger percentage raise it through their
+// environment. No default is set for memory units since the number of clients
+// is not known up front, which is indicated by returning an empty map.
+func (m *Daemon) perDeviceActiveThreadPercentages() map[string]int {
+	percentages := make(map[string]int)
 	for _, device := range m.Devices() {
-		index := device.Index
-		totalMemoryInBytesPerDevice[index] = device.TotalMemory
-		replicasPerDevice[index] += 1
+		if device.MemoryUnit {
+			return map[string]int{}
+		}
 	}
 
-	limits := make(map[string]string)
-	for index, totalMemory := range totalMemoryInBytesPerDevice {
-		if totalMemory == 0 {
-			continue
+	replicasPerDevice := make(map[string]int)
+	for _, device := range m.Devices() {
+		replicasPerDevice[device.GetUUID()]++
+	}
+	for _, device := range m.Devices() {
+		uuid := device.GetUUID()
+		p := device.ActiveThreadPercentage
+		if p == 0 {
+			p = 100 / replicasPerDevice[uuid]
+		}
+		if current, ok := percentages[uuid]; !ok || p < current {
+			percentages[uuid] = p
 		}
-		replicas := replicasPerDevice[index]
-		limits[index] = fmt.Sprintf("%vM", totalMemory/replicas/1024/1024)
 	}
-	return limits
+	return percentages
 }
 
-func (m *Daemon) activeThreadPercentage() string {
-	if len(m.Devices()) == 0 {
-		return ""
+// activeThreadPercentage returns the default active thread percentage that is
+// set for the daemon. MPS only supports a single default per daemon and the
+// config validation ensures that the MPS limits give all devices of a resource
+// the same percentage; the smallest percentage of any device is used
+// regardless. A value of 0 indicates that no default is set.
+func (m *Daemon) activeThreadPercentage() int {
+	percentage := 0
+	for _, p := range m.perDeviceActiveThreadPercentages() {
+		if percentage == 0 || p < percentage {
+			percentage = p
+		}
 	}
-	replicasPerDevice := len(m.Devices()) / len(m.Devices().GetUUIDs())
-
-	return fmt.Sprintf("%d", 100/replicasPerDevice)
+	return percentage
 }
@@ -0,0 +1,321 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
+)
+
+func TestDaemonDevices(t *testing.T) {
+	devices := make(rm.Devices)
+	for _, d := range []struct {
+		uuid  string
+		index string
+	}{
+		{"GPU-3", "3"},
+		{"GPU-1", "1"},
+	} {
+		for r := 0; r < 2; r++ {
+			id := string(rm.NewAnnotatedID(d.uuid, r))
+			devices[id] = &rm.Device{
+				Device:      pluginapi.Device{ID: id},
+				Index:       d.index,
+				TotalMemory: 2048 * 1024 * 1024,
+				Replicas:    2,
+			}
+		}
+	}
+
+	d := NewDaemon(&rm.ResourceManagerMock{
+		DevicesFunc: func() rm.Devices {
+			return devices
+		},
+		ResourceFunc: func() spec.ResourceName {
+			return "nvidia.com/t4"
+		},
+	}, ContainerRoot)
+
+	envs := d.EnvVars()
+	require.Equal(t, "GPU-1,GPU-3", envs["CUDA_VISIBLE_DEVICES"])
+	require.Equal(t, "/mps/nvidia.com/t4/pipe", envs["CUDA_MPS_PIPE_DIRECTORY"])
+
+	require.Equal(t,
+		map[int]uint64{0: 1024 * 1024 * 1024, 1: 1024 * 1024 * 1024},
+		d.perDevicePinnedDeviceMemoryLimits(),
+	)
+	require.Equal(t, 50, d.activeThreadPercentage())
+}
+
+func TestDaemonReplicaClasses(t *testing.T) {
+	devices := make(rm.Devices)
+	for r, class := range []struct {
+		memory           uint64
+		threadPercentage int
+	}{
+		{20 * 1024 * 1024 * 1024, 25},
+		{10 * 1024 * 1024 * 1024, 12},
+		{10 * 1024 * 1024 * 1024, 12},
+	} {
+		id := string(rm.NewAnnotatedID("GPU-0", r))
+		devices[id] = &rm.Device{
+			Device:                 pluginapi.Device{ID: id},
+			Index:                  "0",
+			TotalMemory:            80 * 1024 * 1024 * 1024,
+			Replicas:               3,
+			SharedResource:         "nvidia.com/gpu",
+			ReplicaMemory:          class.memory,
+			ActiveThreadPercentage: class.threadPercentage,
+		}
+	}
+
+	d := NewDaemon(&rm.ResourceManagerMock{
+		DevicesFunc: func() rm.Devices {
+			return devices
+		},
+		ResourceFunc: func() spec.ResourceName {
+			return "nvidia.com/gpu-20g"
+		},
+	}, ContainerRoot)
+
+	require.Equal(t, spec.ResourceName("nvidia.com/gpu"), d.Resource())
+	require.Equal(t, "/mps/nvidia.com/gpu/pipe", d.PipeDir())
+	require.Equal(t,
+		map[int]uint64{0: 10240 * 1024 * 1024},
+		d.perDevicePinnedDeviceMemoryLimits(),
+	)
+	require.Equal(t, 12, d.activeThreadPercentage())
+}
+
+func TestDaemonMemoryUnits(t *testing.T) {
+	devices := make(rm.Devices)
+	for i := 0; i < 4; i++ {
+		id := string(rm.NewAnnotatedID("GPU-0", i))
+		devices[id] = &rm.Device{
+			Device:        pluginapi.Device{ID: id},
+			Index:         "0",
+			TotalMemory:   4 * 1024 * 1024 * 1024,
+			Replicas:      4,
+			ReplicaMemory: 1024 * 1024 * 1024,
+			MemoryUnit:    true,
+		}
+	}
+
+	d := NewDaemon(&rm.ResourceManagerMock{
+		DevicesFunc: func() rm.Devices {
+			return devices
+		},
+		ResourceFunc: func() spec.ResourceName {
+			return "nvidia.com/gpumem"
+		},
+	}, ContainerRoot)
+
+	require.Equal(t,
+		map[int]uint64{0: 1024 * 1024 * 1024},
+		d.perDevicePinnedDeviceMemoryLimits(),
+	)
+	require.Equal(t, 0, d.activeThreadPercentage())
+}
+
+func TestDaemonLimits(t *testing.T) {
+	newDevices := func(memory uint64, threadPercentages [2]int) rm.Devices {
+		devices := make(rm.Devices)
+		for i, d := range []struct {
+			uuid   string
+			index  string
+			memory uint64
+		}{
+			{"GPU-0", "0", 0},
+			{"GPU-1", "1", memory},
+		} {
+			for r := 0; r < 4; r++ {
+				id := string(rm.NewAnnotatedID(d.uuid, r))
+				devices[id] = &rm.Device{
+					Device:                 pluginapi.Device{ID: id},
+					Index:                  d.index,
+					TotalMemory:            40 * 1024 * 1024 * 1024,
+					Replicas:               4,
+					ReplicaMemory:          d.memory,
+					ActiveThreadPercentage: threadPercentages[i],
+				}
+			}
+		}
+		return devices
+	}
+
+	testCases := []struct {
+		description              string
+		memory                   uint64
+		threadPercentages        [2]int
+		expectedError            bool
+		expectedLimits           map[int]uint64
+		expectedThreadPercentage int
+	}{
+		{
+			description:              "limits are applied per device",
+			memory:                   8 * 1024 * 1024 * 1024,
+			threadPercentages:        [2]int{30, 30},
+			expectedLimits:           map[int]uint64{0: 10240 * 1024 * 1024, 1: 8192 * 1024 * 1024},
+			expectedThreadPercentage: 30,
+		},
+		{
+			description:              "default thread percentage matches explicit limit",
+			memory:                   8 * 1024 * 1024 * 1024,
+			threadPercentages:        [2]int{0, 25},
+			expectedLimits:           map[int]uint64{0: 10240 * 1024 * 1024, 1: 8192 * 1024 * 1024},
+			expectedThreadPercentage: 25,
+		},
+		{
+			description:   "limit exceeds device memory",
+			memory:        48 * 1024 * 1024 * 1024,
+			expectedError: true,
+		},
+		{
+			description:   "limits of all replicas exceed device memory",
+			memory:        12 * 1024 * 1024 * 1024,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			devices := newDevices(tc.memory, tc.threadPercentages)
+			d := NewDaemon(&rm.ResourceManagerMock{
+				DevicesFunc: func() rm.Devices {
+					return devices
+				},
+				ResourceFunc: func() spec.ResourceName {
+					return "nvidia.com/gpu"
+				},
+			}, ContainerRoot)
+
+			err := d.assertLimits()
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedLimits, d.perDevicePinnedDeviceMemoryLimits())
+			require.Equal(t, tc.expectedThreadPercentage, d.activeThreadPercentage())
+		})
+	}
+}
+
+func TestNewDaemonsForMigDevices(t *testing.T) {
+	devices := make(rm.Devices)
+	for _, d := range []struct {
+		uuid  string
+		index string
+	}{
+		{"MIG-b", "0:1"},
+		{"MIG-a", "0:0"},
+	} {
+		for r := 0; r < 2; r++ {
+			id := string(rm.NewAnnotatedID(d.uuid, r))
+			devices[id] = &rm.Device{
+				Device:      pluginapi.Device{ID: id},
+				Index:       d.index,
+				TotalMemory: 10240 * 1024 * 1024,
+				Replicas:    2,
+			}
+		}
+	}
+
+	daemons := NewDaemonsForResource(&rm.ResourceManagerMock{
+		DevicesFunc: func() rm.Devices {
+			return devices
+		},
+		ResourceFunc: func() spec.ResourceName {
+			return "nvidia.com/mig-1g.10gb"
+		},
+	}, ContainerRoot)
+	require.Len(t, daemons, 2)
+
+	for i, uuid := range []string{"MIG-a", "MIG-b"} {
+		d := daemons[i]
+		require.ElementsMatch(t, []string{uuid + "::0", uuid + "::1"}, d.Devices().GetIDs())
+
+		envs := d.EnvVars()
+		require.Equal(t, uuid, envs["CUDA_VISIBLE_DEVICES"])
+		require.Equal(t, "/mps/nvidia.com/mig-1g.10gb/"+uuid+"/pipe", envs["CUDA_MPS_PIPE_DIRECTORY"])
+		require.Equal(t, "/mps/nvidia.com/mig-1g.10gb/"+uuid+"/log", envs["CUDA_MPS_LOG_DIRECTORY"])
+		require.Equal(t, "/mps/nvidia.com/mig-1g.10gb/"+uuid+"/.ready", d.ReadyFile())
+		require.Equal(t, "/var/lib/nvidia/mps/nvidia.com/mig-1g.10gb/"+uuid+"/pipe", d.HostPipeDir("/var/lib/nvidia/mps"))
+		require.Equal(t, map[int]uint64{0: 5120 * 1024 * 1024}, d.perDevicePinnedDeviceMemoryLimits())
+	}
+}
+
+func TestDaemonReadyFile(t *testing.T) {
+	devices := make(rm.Devices)
+	for _, uuid := range []string{"GPU-0", "GPU-1"} {
+		for r := 0; r < 2; r++ {
+			id := string(rm.NewAnnotatedID(uuid, r))
+			devices[id] = &rm.Device{
+				Device:      pluginapi.Device{ID: id},
+				TotalMemory: 2048 * 1024 * 1024,
+				Replicas:    2,
+			}
+		}
+	}
+	root := Root(t.TempDir())
+	d := newDaemon("nvidia.com/gpu", devices, root)
+	require.Equal(t, root.Path("nvidia.com/gpu/.ready"), d.ReadyFile())
+
+	_, err := d.ReadStatus()
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.MkdirAll(d.PipeDir(), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(d.PipeDir(), pidFileName), []byte("4242\n"), 0644))
+	require.NoError(t, d.writeReadyFile())
+
+	status, err := d.ReadStatus()
+	require.NoError(t, err)
+	require.Equal(t,
+		&ReadyStatus{
+			PID:                    4242,
+			ActiveThreadPercentage: 50,
+			PinnedMemoryLimits: map[string]uint64{
+				"GPU-0": 1024 * 1024 * 1024,
+				"GPU-1": 1024 * 1024 * 1024,
+			},
+		},
+		status,
+	)
+}
+
+func TestContainerRootFor(t *testing.T) {
+	require.Equal(t, ContainerRoot, ContainerRootFor(nil))
+	require.Equal(t, ContainerRoot, ContainerRootFor(&spec.Config{}))
+
+	root := "/run/nvidia/mps"
+	config := &spec.Config{
+		Flags: spec.Flags{
+			CommandLineFlags: spec.CommandLineFlags{
+				MpsRoot: &root,
+			},
+		},
+	}
+	require.Equal(t, Root("/run/nvidia/mps"), ContainerRootFor(config))
+	require.Equal(t, "/run/nvidia/mps/.ready", ContainerRootFor(config).ReadyFile())
+}
@@ -0,0 +1,69 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"k8s.io/klog/v2"
+)
+
+// StartDaemons starts the specified daemons and creates the ready file of the
+// root once all of them are started. If a daemon fails to start, an error is
+// returned and the daemons that were started are left running so that they
+// can be stopped with StopDaemons.
+func StartDaemons(root Root, daemons ...*Daemon) error {
+	for _, daemon := range daemons {
+		if err := daemon.Start(); err != nil {
+			return fmt.Errorf("failed to start MPS daemon %v: %w", daemon.name(), err)
+		}
+	}
+	readyFile, err := os.Create(root.ReadyFile())
+	if err != nil {
+		return fmt.Errorf("failed to create .ready file: %w", err)
+	}
+	return readyFile.Close()
+}
+
+// StopDaemons removes the ready file of the root and stops the specified
+// daemons. The ready file is left unchanged if the root is empty.
+func StopDaemons(root Root, daemons ...*Daemon) error {
+	if root != "" {
+		if err := os.Remove(root.ReadyFile()); err != nil {
+			klog.Warningf("Failed to remove .ready file: %v", err)
+		}
+	}
+	klog.Info("Stopping MPS daemons.")
+	var errs error
+	for _, daemon := range daemons {
+		errs = errors.Join(errs, daemon.Stop())
+	}
+	return errs
+}
+
+// AssertDaemonsHealthy checks that all of the specified daemons respond on
+// their control pipes.
+func AssertDaemonsHealthy(daemons ...*Daemon) error {
+	for _, daemon := range daemons {
+		if err := daemon.AssertHealthy(); err != nil {
+			return fmt.Errorf("MPS daemon %v is unhealthy: %w", daemon.name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
+)
+
+func TestStartStopDaemons(t *testing.T) {
+	root := Root(t.TempDir())
+
+	require.NoError(t, StartDaemons(root))
+	require.FileExists(t, root.ReadyFile())
+
+	// The ready file is left unchanged for an empty root.
+	require.NoError(t, StopDaemons(""))
+	require.FileExists(t, root.ReadyFile())
+
+	require.NoError(t, StopDaemons(root))
+	_, err := os.Stat(root.ReadyFile())
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestStartDaemonsWithInvalidDaemon(t *testing.T) {
+	root := Root(t.TempDir())
+	devices := rm.Devices{
+		"GPU-0::0": &rm.Device{
+			Device:        pluginapi.Device{ID: "GPU-0::0"},
+			TotalMemory:   1024 * 1024 * 1024,
+			ReplicaMemory: 2048 * 1024 * 1024,
+			Replicas:      1,
+		},
+	}
+	d := newDaemon("nvidia.com/gpu", devices, root)
+
+	err := StartDaemons(root, d)
+	require.ErrorContains(t, err, "failed to start MPS daemon nvidia.com/gpu")
+	_, err = os.Stat(root.ReadyFile())
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -32,7 +32,11 @@ var errInvalidDevice = errors.New("invalid device")
 type mpsDevice rm.Device
 
 // assertReplicas checks whether the number of replicas specified is valid.
+// Memory units are not clients of their own and are not limited.
 func (d *mpsDevice) assertReplicas() error {
+	if d.MemoryUnit {
+		return nil
+	}
 	maxClients := d.maxClients()
 	if d.Replicas > maxClients {
 		return fmt.Errorf("%w maximum allowed replicas exceeded: %d > %d", errInvalidDevice, d.Replicas, maxClients)
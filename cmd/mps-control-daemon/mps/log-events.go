@@ -0,0 +1,103 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type logEventType string
+
+const (
+	logEventServerStart      = logEventType("server-start")
+	logEventServerExit       = logEventType("server-exit")
+	logEventClientConnect    = logEventType("client-connect")
+	logEventClientDisconnect = logEventType("client-disconnect")
+	logEventError            = logEventType("error")
+	logEventOther            = logEventType("")
+)
+
+// logEvent represents a line of the MPS control or server log. Lines have the
+// form:
+//
+//	[2024-05-01 10:00:00.123 Control  4242] Starting new server 4343 for user 0
+type logEvent struct {
+	Type      logEventType
+	Time      string
+	Component string
+	// PID is the PID of the process that wrote the line.
+	PID int
+	// SubjectPID is the PID of the server or client that the event refers to
+	// if it is known.
+	SubjectPID int
+	Message    string
+}
+
+var (
+	logLineRegexp = regexp.MustCompile(`^\[(\S+ \S+) (\S+)\s+(\d+)\] (.*)$`)
+
+	logEventPatterns = []struct {
+		eventType logEventType
+		pattern   *regexp.Regexp
+	}{
+		{logEventServerStart, regexp.MustCompile(`^Starting new server (\d+)`)},
+		{logEventServerExit, regexp.MustCompile(`^Server (\d+) exited`)},
+		{logEventServerExit, regexp.MustCompile(`^Server exiting`)},
+		{logEventClientConnect, regexp.MustCompile(`^NEW CLIENT (\d+)`)},
+		{logEventClientConnect, regexp.MustCompile(`^Received new client request`)},
+		{logEventClientDisconnect, regexp.MustCompile(`^Client (\d+) disconnected`)},
+		{logEventClientDisconnect, regexp.MustCompile(`^Client disconnected`)},
+	}
+)
+
+// parseLogLine parses a line of the MPS control or server log. Lines that do
+// not match the expected format are returned as the message of an event
+// without a type.
+func parseLogLine(line string) logEvent {
+	match := logLineRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return logEvent{Type: logEventOther, Message: line}
+	}
+	pid, _ := strconv.Atoi(match[3])
+	event := logEvent{
+		Type:      logEventOther,
+		Time:      match[1],
+		Component: match[2],
+		PID:       pid,
+		Message:   match[4],
+	}
+
+	for _, p := range logEventPatterns {
+		m := p.pattern.FindStringSubmatch(event.Message)
+		if m == nil {
+			continue
+		}
+		event.Type = p.eventType
+		if len(m) > 1 {
+			event.SubjectPID, _ = strconv.Atoi(m[1])
+		}
+		return event
+	}
+
+	lower := strings.ToLower(event.Message)
+	if strings.Contains(lower, "error") || strings.Contains(lower, "fail") {
+		event.Type = logEventError
+	}
+	return event
+}
@@ -0,0 +1,150 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogLine(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected logEvent
+	}{
+		{
+			line: "[2024-05-01 10:00:00.123 Control  4242] Starting new server 4343 for user 0",
+			expected: logEvent{
+				Type:       logEventServerStart,
+				Time:       "2024-05-01 10:00:00.123",
+				Component:  "Control",
+				PID:        4242,
+				SubjectPID: 4343,
+				Message:    "Starting new server 4343 for user 0",
+			},
+		},
+		{
+			line: "[2024-05-01 10:00:09.000 Control  4242] Server 4343 exited with status 1",
+			expected: logEvent{
+				Type:       logEventServerExit,
+				Time:       "2024-05-01 10:00:09.000",
+				Component:  "Control",
+				PID:        4242,
+				SubjectPID: 4343,
+				Message:    "Server 4343 exited with status 1",
+			},
+		},
+		{
+			line: "[2024-05-01 10:00:01.000 Control  4242] NEW CLIENT 5151 from user 0: Server already exists",
+			expected: logEvent{
+				Type:       logEventClientConnect,
+				Time:       "2024-05-01 10:00:01.000",
+				Component:  "Control",
+				PID:        4242,
+				SubjectPID: 5151,
+				Message:    "NEW CLIENT 5151 from user 0: Server already exists",
+			},
+		},
+		{
+			line: "[2024-05-01 10:00:05.000 Server  4343] Client disconnected",
+			expected: logEvent{
+				Type:      logEventClientDisconnect,
+				Time:      "2024-05-01 10:00:05.000",
+				Component: "Server",
+				PID:       4343,
+				Message:   "Client disconnected",
+			},
+		},
+		{
+			line: "[2024-05-01 10:00:05.000 Server  4343] Failed to allocate pinned memory",
+			expected: logEvent{
+				Type:      logEventError,
+				Time:      "2024-05-01 10:00:05.000",
+				Component: "Server",
+				PID:       4343,
+				Message:   "Failed to allocate pinned memory",
+			},
+		},
+		{
+			line: "[2024-05-01 10:00:00.100 Control  4242] Accepting connection...",
+			expected: logEvent{
+				Type:      logEventOther,
+				Time:      "2024-05-01 10:00:00.100",
+				Component: "Control",
+				PID:       4242,
+				Message:   "Accepting connection...",
+			},
+		},
+		{
+			line: "not an MPS log line",
+			expected: logEvent{
+				Type:    logEventOther,
+				Message: "not an MPS log line",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.line, func(t *testing.T) {
+			require.Equal(t, tc.expected, parseLogLine(tc.line))
+		})
+	}
+}
+
+func TestLogFollower(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "control.log")
+
+	var lock sync.Mutex
+	var lines []string
+	follower := newLogFollower(filename, func(line string) {
+		lock.Lock()
+		defer lock.Unlock()
+		lines = append(lines, line)
+	})
+	follower.pollInterval = 10 * time.Millisecond
+	follower.Start()
+	defer follower.Stop()
+
+	received := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string{}, lines...)
+	}
+
+	// The file is created after the follower was started.
+	require.NoError(t, os.WriteFile(filename, []byte("first\nsec"), 0600))
+	require.Eventually(t, func() bool { return len(received()) == 1 }, time.Second, 10*time.Millisecond)
+
+	// Incomplete lines are passed to the handler once they are completed.
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString("ond\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Eventually(t, func() bool { return len(received()) == 2 }, time.Second, 10*time.Millisecond)
+
+	// A truncated file is followed from its start.
+	require.NoError(t, os.WriteFile(filename, []byte("third\n"), 0600))
+	require.Eventually(t, func() bool { return len(received()) == 3 }, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, []string{"first", "second", "third"}, received())
+}
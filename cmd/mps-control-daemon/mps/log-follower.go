@@ -0,0 +1,143 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultLogPollInterval = 500 * time.Millisecond
+
+// logFollower follows a log file from its start and passes each complete line
+// to a handler. The file need not exist when the follower is started, and a
+// file that is truncated or replaced is followed from its start.
+type logFollower struct {
+	filename     string
+	handler      func(string)
+	pollInterval time.Duration
+	cancel       context.CancelFunc
+	done         chan struct{}
+}
+
+// newLogFollower creates a follower for the specified file.
+func newLogFollower(filename string, handler func(string)) *logFollower {
+	return &logFollower{
+		filename:     filename,
+		handler:      handler,
+		pollInterval: defaultLogPollInterval,
+	}
+}
+
+// Start starts following the file in the background.
+func (f *logFollower) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	f.done = make(chan struct{})
+	go func() {
+		defer close(f.done)
+		f.follow(ctx)
+	}()
+}
+
+// Stop stops following the file and waits for the follower to exit.
+func (f *logFollower) Stop() {
+	if f == nil || f.cancel == nil {
+		return
+	}
+	f.cancel()
+	<-f.done
+}
+
+func (f *logFollower) follow(ctx context.Context) {
+	var file *os.File
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+	var reader *bufio.Reader
+	var offset int64
+	var partial strings.Builder
+
+	for {
+		if file == nil {
+			file, _ = os.Open(f.filename)
+			if file != nil {
+				reader = bufio.NewReader(file)
+				offset = 0
+				partial.Reset()
+			}
+		}
+		if file != nil {
+			offset += f.readLines(reader, &partial)
+			if f.replaced(file, offset) {
+				file.Close()
+				file = nil
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(f.pollInterval):
+		}
+	}
+}
+
+// readLines passes the complete lines available from the reader to the
+// handler and returns the number of bytes read. An incomplete line at the end
+// of the file is kept until it is completed.
+func (f *logFollower) readLines(reader *bufio.Reader, partial *strings.Builder) int64 {
+	var read int64
+	for {
+		line, err := reader.ReadString('\n')
+		read += int64(len(line))
+		partial.WriteString(line)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				partial.Reset()
+			}
+			return read
+		}
+		f.handler(strings.TrimRight(partial.String(), "\r\n"))
+		partial.Reset()
+	}
+}
+
+// replaced checks whether the followed file was truncated or replaced by a
+// different file since it was opened.
+func (f *logFollower) replaced(file *os.File, offset int64) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return true
+	}
+	current, err := os.Stat(f.filename)
+	if err != nil {
+		return false
+	}
+	if !os.SameFile(opened, current) {
+		return true
+	}
+	return current.Size() < offset
+}
@@ -37,6 +37,7 @@ type manager struct {
 	nvmllib   nvml.Interface
 	devicelib device.Interface
 	config    *spec.Config
+	metrics   *Metrics
 }
 
 type nullManager struct{}
@@ -75,7 +76,10 @@ func (m *manager) Daemons() ([]*Daemon, error) {
 	if err != nil {
 		return nil, err
 	}
-	var daemons []*Daemon
+	// The replicas of all classes of a resource are controlled by a single
+	// daemon, so the devices of these resources are grouped by daemon.
+	var resources []spec.ResourceName
+	devicesByResource := make(map[spec.ResourceName]rm.Devices)
 	for _, resourceManager := range resourceManagers {
 		// We don't create daemons if there are no devices associated with the resource manager.
 		if len(resourceManager.Devices()) == 0 {
@@ -88,18 +92,28 @@ func (m *manager) Daemons() ([]*Daemon, error) {
 			klog.InfoS("Resource is not shared", "resource", "resource", resourceManager.Resource())
 			continue
 		}
-		// Check if MIG devices are included.
 		for _, rmDevice := range resourceManager.Devices() {
-			if rmDevice.IsMigDevice() {
-				klog.Warning("MPS sharing is not supported for MIG devices; skipping daemon creation")
-				continue
-			}
 			if err := (*mpsDevice)(rmDevice).assertReplicas(); err != nil {
 				return nil, fmt.Errorf("invalid MPS configuration: %w", err)
 			}
 		}
-		daemon := NewDaemon(resourceManager, ContainerRoot)
-		daemons = append(daemons, daemon)
+		resource := daemonResource(resourceManager.Resource(), resourceManager.Devices())
+		if _, exists := devicesByResource[resource]; !exists {
+			resources = append(resources, resource)
+			devicesByResource[resource] = make(rm.Devices)
+		}
+		for id, device := range resourceManager.Devices() {
+			devicesByResource[resource][id] = device
+		}
+	}
+
+	var daemons []*Daemon
+	for _, resource := range resources {
+		daemons = append(daemons, newDaemons(resource, devicesByResource[resource], ContainerRootFor(m.config))...)
+	}
+	for _, daemon := range daemons {
+		daemon.nvmllib = m.nvmllib
+		daemon.metrics = m.metrics
 	}
 
 	return daemons, nil
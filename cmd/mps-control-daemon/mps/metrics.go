@@ -0,0 +1,176 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	metricServers                = "nvidia_mps_servers"
+	metricServerRestarts         = "nvidia_mps_server_restarts_total"
+	metricActiveClients          = "nvidia_mps_active_clients"
+	metricLogErrors              = "nvidia_mps_log_errors_total"
+	metricActiveThreadPercentage = "nvidia_mps_default_active_thread_percentage"
+	metricPinnedMemoryLimitBytes = "nvidia_mps_default_pinned_memory_limit_bytes"
+	metricCollectionErrors       = "nvidia_mps_metrics_collection_errors_total"
+
+	metricTypeGauge   = "gauge"
+	metricTypeCounter = "counter"
+
+	metricLabelDaemon = "daemon"
+	metricLabelDevice = "device"
+
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+type metricDescription struct {
+	name       string
+	help       string
+	metricType string
+}
+
+// metricDescriptions describes the metrics exposed for MPS daemons. Daemons
+// are identified by their resource and, for MIG devices, the UUID of the MIG
+// device; devices by their UUID.
+var metricDescriptions = []metricDescription{
+	{metricServers, "Number of MPS servers started by the control daemon.", metricTypeGauge},
+	{metricServerRestarts, "Number of times an MPS server was started after the first server of the control daemon.", metricTypeCounter},
+	{metricActiveClients, "Number of MPS clients per device.", metricTypeGauge},
+	{metricLogErrors, "Number of errors in the MPS control and server logs.", metricTypeCounter},
+	{metricActiveThreadPercentage, "Default active thread percentage of the MPS control daemon.", metricTypeGauge},
+	{metricPinnedMemoryLimitBytes, "Default pinned device memory limit per device of the MPS control daemon.", metricTypeGauge},
+	{metricCollectionErrors, "Number of failed queries of the MPS control daemon.", metricTypeCounter},
+}
+
+// Metrics holds the metrics of the MPS daemons and exposes them in the
+// Prometheus text format.
+type Metrics struct {
+	sync.Mutex
+	// values holds the value of each series by metric and label set.
+	values map[string]map[string]float64
+}
+
+// NewMetrics creates an empty set of metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		values: make(map[string]map[string]float64),
+	}
+}
+
+// labels represents the label names and values of a series as pairs.
+type labels []string
+
+// String returns the labels in the Prometheus text format. Label values are
+// escaped as required by the format: backslashes, double quotes, and line
+// feeds are escaped and all other characters are written as is.
+func (l labels) String() string {
+	var pairs []string
+	for i := 0; i+1 < len(l); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, l[i], labelValueReplacer.Replace(l[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var (
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func (m *Metrics) set(name string, l labels, value float64) {
+	if m == nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.values[name] == nil {
+		m.values[name] = make(map[string]float64)
+	}
+	m.values[name][l.String()] = value
+}
+
+func (m *Metrics) inc(name string, l labels) {
+	if m == nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.values[name] == nil {
+		m.values[name] = make(map[string]float64)
+	}
+	m.values[name][l.String()]++
+}
+
+// resetGauges removes the gauges of the specified daemon. Counters are kept
+// since they are expected to increase monotonically.
+func (m *Metrics) resetGauges(daemon string) {
+	if m == nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	prefix := labels{metricLabelDaemon, daemon}.String()
+	prefix = strings.TrimSuffix(prefix, "}")
+	for _, d := range metricDescriptions {
+		if d.metricType != metricTypeGauge {
+			continue
+		}
+		for series := range m.values[d.name] {
+			if series == prefix+"}" || strings.HasPrefix(series, prefix+",") {
+				delete(m.values[d.name], series)
+			}
+		}
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	var b strings.Builder
+	for _, d := range metricDescriptions {
+		series := m.values[d.name]
+		if len(series) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n", d.name, helpReplacer.Replace(d.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", d.name, d.metricType)
+		var keys []string
+		for k := range series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s%s %s\n", d.name, k, strconv.FormatFloat(series[k], 'g', -1, 64))
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	_, _ = m.WriteTo(w)
+}
@@ -0,0 +1,85 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsWriteTo(t *testing.T) {
+	m := NewMetrics()
+
+	var output strings.Builder
+	_, err := m.WriteTo(&output)
+	require.NoError(t, err)
+	require.Empty(t, output.String())
+
+	m.set(metricPinnedMemoryLimitBytes, labels{metricLabelDaemon, "nvidia.com/gpu", metricLabelDevice, "GPU-1"}, 1024*1024*1024)
+	m.set(metricPinnedMemoryLimitBytes, labels{metricLabelDaemon, "nvidia.com/gpu", metricLabelDevice, "GPU-0"}, 0.5)
+	m.set(metricServers, labels{metricLabelDaemon, `a"b\c` + "\n" + "é"}, 2)
+	m.inc(metricLogErrors, labels{metricLabelDaemon, "nvidia.com/gpu"})
+	m.inc(metricLogErrors, labels{metricLabelDaemon, "nvidia.com/gpu"})
+
+	_, err = m.WriteTo(&output)
+	require.NoError(t, err)
+	require.Equal(t, `# HELP nvidia_mps_servers Number of MPS servers started by the control daemon.
+# TYPE nvidia_mps_servers gauge
+nvidia_mps_servers{daemon="a\"b\\c\né"} 2
+# HELP nvidia_mps_log_errors_total Number of errors in the MPS control and server logs.
+# TYPE nvidia_mps_log_errors_total counter
+nvidia_mps_log_errors_total{daemon="nvidia.com/gpu"} 2
+# HELP nvidia_mps_default_pinned_memory_limit_bytes Default pinned device memory limit per device of the MPS control daemon.
+# TYPE nvidia_mps_default_pinned_memory_limit_bytes gauge
+nvidia_mps_default_pinned_memory_limit_bytes{daemon="nvidia.com/gpu",device="GPU-0"} 0.5
+nvidia_mps_default_pinned_memory_limit_bytes{daemon="nvidia.com/gpu",device="GPU-1"} 1.073741824e+09
+`, output.String())
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	m := NewMetrics()
+	m.set(metricActiveThreadPercentage, labels{metricLabelDaemon, "nvidia.com/gpu"}, 25)
+
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	require.Equal(t, `# HELP nvidia_mps_default_active_thread_percentage Default active thread percentage of the MPS control daemon.
+# TYPE nvidia_mps_default_active_thread_percentage gauge
+nvidia_mps_default_active_thread_percentage{daemon="nvidia.com/gpu"} 25
+`, recorder.Body.String())
+}
+
+func TestMetricDescriptions(t *testing.T) {
+	nameRegexp := regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	names := make(map[string]bool)
+	for _, d := range metricDescriptions {
+		require.Regexp(t, nameRegexp, d.name)
+		require.False(t, names[d.name], "duplicate metric %v", d.name)
+		names[d.name] = true
+
+		require.NotEmpty(t, d.help)
+		require.Contains(t, []string{metricTypeGauge, metricTypeCounter}, d.metricType)
+		require.Equal(t, d.metricType == metricTypeCounter, strings.HasSuffix(d.name, "_total"), d.name)
+	}
+}
@@ -0,0 +1,157 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const metricsPollInterval = 15 * time.Second
+
+// controlClient defines the queries of the MPS control daemon that metrics
+// are collected from.
+type controlClient interface {
+	GetServerList() ([]int, error)
+	GetDeviceClientList(serverPID int) (map[string][]int, error)
+	GetDefaultActiveThreadPercentage() (float64, error)
+	GetDefaultDevicePinnedMemoryLimit(device int) (uint64, error)
+}
+
+// logHandler returns a handler for the lines of the specified MPS log. Errors
+// and server lifecycle events are logged as structured entries, while client
+// events and all other lines are only logged at verbosity 4. Server restarts
+// and errors are counted. The handler is called concurrently for the control
+// and server logs.
+func (d *Daemon) logHandler(log string) func(string) {
+	return func(line string) {
+		event := parseLogLine(line)
+		keysAndValues := []interface{}{
+			"daemon", d.name(),
+			"log", log,
+			"component", event.Component,
+			"pid", event.PID,
+			"time", event.Time,
+			"message", event.Message,
+		}
+		switch event.Type {
+		case logEventError:
+			d.metrics.inc(metricLogErrors, labels{metricLabelDaemon, d.name()})
+			klog.ErrorS(nil, "MPS error", keysAndValues...)
+		case logEventOther:
+			klog.V(4).InfoS("MPS log", keysAndValues...)
+		default:
+			keysAndValues = append(keysAndValues, "event", event.Type)
+			if event.SubjectPID != 0 {
+				keysAndValues = append(keysAndValues, "subjectPID", event.SubjectPID)
+			}
+			switch event.Type {
+			case logEventServerStart:
+				if d.serverStarted.Swap(true) {
+					d.metrics.inc(metricServerRestarts, labels{metricLabelDaemon, d.name()})
+					klog.InfoS("MPS server restarted", keysAndValues...)
+					return
+				}
+				klog.InfoS("MPS event", keysAndValues...)
+			case logEventServerExit:
+				klog.InfoS("MPS event", keysAndValues...)
+			default:
+				klog.V(4).InfoS("MPS event", keysAndValues...)
+			}
+		}
+	}
+}
+
+// startMonitor starts collecting the metrics of the daemon periodically.
+func (d *Daemon) startMonitor() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.stopMonitor = cancel
+	d.monitorDone = make(chan struct{})
+	go func() {
+		defer close(d.monitorDone)
+		client := d.Client()
+		for {
+			if err := d.collectMetrics(client); err != nil {
+				d.metrics.inc(metricCollectionErrors, labels{metricLabelDaemon, d.name()})
+				klog.ErrorS(err, "Failed to collect MPS metrics", "daemon", d.name())
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(metricsPollInterval):
+			}
+		}
+	}()
+}
+
+// stopMonitoring stops collecting metrics and removes the gauges of the
+// daemon.
+func (d *Daemon) stopMonitoring() {
+	if d.stopMonitor == nil {
+		return
+	}
+	d.stopMonitor()
+	<-d.monitorDone
+	d.stopMonitor = nil
+	d.metrics.resetGauges(d.name())
+}
+
+// collectMetrics queries the servers and clients of the daemon as well as the
+// limits in force. Clients are counted per device that they enumerated.
+func (d *Daemon) collectMetrics(client controlClient) error {
+	servers, err := client.GetServerList()
+	if err != nil {
+		return fmt.Errorf("error getting server list: %w", err)
+	}
+	d.metrics.set(metricServers, labels{metricLabelDaemon, d.name()}, float64(len(servers)))
+
+	uuids := d.Devices().GetUUIDs()
+	clientsPerDevice := make(map[string]int)
+	for _, uuid := range uuids {
+		clientsPerDevice[uuid] = 0
+	}
+	for _, server := range servers {
+		deviceClients, err := client.GetDeviceClientList(server)
+		if err != nil {
+			return fmt.Errorf("error getting clients of server %d: %w", server, err)
+		}
+		for device, clients := range deviceClients {
+			clientsPerDevice[device] += len(clients)
+		}
+	}
+	for device, clients := range clientsPerDevice {
+		d.metrics.set(metricActiveClients, labels{metricLabelDaemon, d.name(), metricLabelDevice, device}, float64(clients))
+	}
+
+	percentage, err := client.GetDefaultActiveThreadPercentage()
+	if err != nil {
+		return fmt.Errorf("error getting active thread percentage: %w", err)
+	}
+	d.metrics.set(metricActiveThreadPercentage, labels{metricLabelDaemon, d.name()}, percentage)
+
+	for i, uuid := range uuids {
+		limit, err := client.GetDefaultDevicePinnedMemoryLimit(i)
+		if err != nil {
+			return fmt.Errorf("error getting pinned memory limit of %v: %w", uuid, err)
+		}
+		d.metrics.set(metricPinnedMemoryLimitBytes, labels{metricLabelDaemon, d.name(), metricLabelDevice, uuid}, float64(limit))
+	}
+	return nil
+}
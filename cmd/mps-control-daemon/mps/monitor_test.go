@@ -0,0 +1,114 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
+)
+
+type fakeControlClient struct {
+	servers          []int
+	deviceClients    map[int]map[string][]int
+	threadPercentage float64
+	memoryLimits     map[int]uint64
+}
+
+func (c *fakeControlClient) GetServerList() ([]int, error) {
+	return c.servers, nil
+}
+
+func (c *fakeControlClient) GetDeviceClientList(serverPID int) (map[string][]int, error) {
+	return c.deviceClients[serverPID], nil
+}
+
+func (c *fakeControlClient) GetDefaultActiveThreadPercentage() (float64, error) {
+	return c.threadPercentage, nil
+}
+
+func (c *fakeControlClient) GetDefaultDevicePinnedMemoryLimit(device int) (uint64, error) {
+	return c.memoryLimits[device], nil
+}
+
+func TestDaemonMetrics(t *testing.T) {
+	devices := make(rm.Devices)
+	for _, uuid := range []string{"GPU-0", "GPU-1"} {
+		for r := 0; r < 2; r++ {
+			id := string(rm.NewAnnotatedID(uuid, r))
+			devices[id] = &rm.Device{
+				Device:   pluginapi.Device{ID: id},
+				Replicas: 2,
+			}
+		}
+	}
+	metrics := NewMetrics()
+	d := newDaemon("nvidia.com/gpu", devices, ContainerRoot)
+	d.metrics = metrics
+
+	err := d.collectMetrics(&fakeControlClient{
+		servers: []int{100},
+		deviceClients: map[int]map[string][]int{
+			100: {"GPU-0": {200, 201}},
+		},
+		threadPercentage: 50,
+		memoryLimits:     map[int]uint64{0: 1024, 1: 2048},
+	})
+	require.NoError(t, err)
+
+	handle := d.logHandler("control.log")
+	handle("[2024-05-01 10:00:00.000 Control  10] Starting new server 100 for user 0")
+	handle("[2024-05-01 10:00:09.000 Control  10] Server 100 exited with status 1")
+	handle("[2024-05-01 10:00:10.000 Control  10] Starting new server 101 for user 0")
+	handle("[2024-05-01 10:00:11.000 Server  101] Failed to allocate pinned memory")
+
+	var output strings.Builder
+	_, err = metrics.WriteTo(&output)
+	require.NoError(t, err)
+	require.Equal(t, `# HELP nvidia_mps_servers Number of MPS servers started by the control daemon.
+# TYPE nvidia_mps_servers gauge
+nvidia_mps_servers{daemon="nvidia.com/gpu"} 1
+# HELP nvidia_mps_server_restarts_total Number of times an MPS server was started after the first server of the control daemon.
+# TYPE nvidia_mps_server_restarts_total counter
+nvidia_mps_server_restarts_total{daemon="nvidia.com/gpu"} 1
+# HELP nvidia_mps_active_clients Number of MPS clients per device.
+# TYPE nvidia_mps_active_clients gauge
+nvidia_mps_active_clients{daemon="nvidia.com/gpu",device="GPU-0"} 2
+nvidia_mps_active_clients{daemon="nvidia.com/gpu",device="GPU-1"} 0
+# HELP nvidia_mps_log_errors_total Number of errors in the MPS control and server logs.
+# TYPE nvidia_mps_log_errors_total counter
+nvidia_mps_log_errors_total{daemon="nvidia.com/gpu"} 1
+# HELP nvidia_mps_default_active_thread_percentage Default active thread percentage of the MPS control daemon.
+# TYPE nvidia_mps_default_active_thread_percentage gauge
+nvidia_mps_default_active_thread_percentage{daemon="nvidia.com/gpu"} 50
+# HELP nvidia_mps_default_pinned_memory_limit_bytes Default pinned device memory limit per device of the MPS control daemon.
+# TYPE nvidia_mps_default_pinned_memory_limit_bytes gauge
+nvidia_mps_default_pinned_memory_limit_bytes{daemon="nvidia.com/gpu",device="GPU-0"} 1024
+nvidia_mps_default_pinned_memory_limit_bytes{daemon="nvidia.com/gpu",device="GPU-1"} 2048
+`, output.String())
+
+	metrics.resetGauges(d.name())
+	output.Reset()
+	_, err = metrics.WriteTo(&output)
+	require.NoError(t, err)
+	require.NotContains(t, output.String(), "gauge")
+	require.Contains(t, output.String(), `nvidia_mps_server_restarts_total{daemon="nvidia.com/gpu"} 1`)
+}
@@ -23,6 +23,13 @@ import (
 // Option defines a functional option for configuring an MPS manager.
 type Option func(*manager)
 
+// WithMetrics sets the metrics that the daemons of the MPS manager report to.
+func WithMetrics(metrics *Metrics) Option {
+	return func(m *manager) {
+		m.metrics = metrics
+	}
+}
+
 // WithConfig sets the config associated with the MPS manager.
 func WithConfig(config *spec.Config) Option {
 	return func(m *manager) {
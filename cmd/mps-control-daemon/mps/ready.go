@@ -0,0 +1,110 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	readyFileName = ".ready"
+	// pidFileName is the file in the pipe directory that the MPS control
+	// daemon writes its PID to.
+	pidFileName = "nvidia-cuda-mps-control.pid"
+)
+
+// ReadyStatus is written to the ready file of a daemon once the daemon was
+// started and its limits were applied.
+type ReadyStatus struct {
+	// PID is the PID of the MPS control daemon or 0 if it is not known.
+	PID int `json:"pid"`
+	// ActiveThreadPercentage is the default active thread percentage of the
+	// daemon or 0 if no default is set.
+	ActiveThreadPercentage int `json:"activeThreadPercentage,omitempty"`
+	// PinnedMemoryLimits are the default pinned memory limits in bytes by
+	// device UUID.
+	PinnedMemoryLimits map[string]uint64 `json:"pinnedMemoryLimits,omitempty"`
+}
+
+// ReadyFile returns the path of the ready file of the daemon.
+func (d *Daemon) ReadyFile() string {
+	return d.root.daemonPath(d.resource, d.migUUID, readyFileName)
+}
+
+// ReadStatus reads the status from the ready file of the daemon. An error
+// satisfying os.IsNotExist is returned if the daemon is not ready.
+func (d *Daemon) ReadStatus() (*ReadyStatus, error) {
+	contents, err := os.ReadFile(d.ReadyFile())
+	if err != nil {
+		return nil, err
+	}
+	var status ReadyStatus
+	if err := json.Unmarshal(contents, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse %v: %w", d.ReadyFile(), err)
+	}
+	return &status, nil
+}
+
+// writeReadyFile writes the status of the started daemon to its ready file.
+// The file is written to a temporary file first so that readers never see a
+// partially written status.
+func (d *Daemon) writeReadyFile() error {
+	uuids := d.Devices().GetUUIDs()
+	status := ReadyStatus{
+		PID:                    d.pid(),
+		ActiveThreadPercentage: d.activeThreadPercentage(),
+	}
+	for index, limit := range d.perDevicePinnedDeviceMemoryLimits() {
+		if status.PinnedMemoryLimits == nil {
+			status.PinnedMemoryLimits = make(map[string]uint64)
+		}
+		status.PinnedMemoryLimits[uuids[index]] = limit
+	}
+
+	contents, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+	readyFile := d.ReadyFile()
+	tmp := filepath.Join(filepath.Dir(readyFile), "."+readyFileName+".tmp")
+	if err := os.WriteFile(tmp, contents, 0644); err != nil {
+		return fmt.Errorf("failed to write %v: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, readyFile); err != nil {
+		return fmt.Errorf("failed to rename %v to %v: %w", tmp, readyFile, err)
+	}
+	return nil
+}
+
+// pid returns the PID of the MPS control daemon as written to its PID file or
+// 0 if the PID file cannot be read.
+func (d *Daemon) pid() int {
+	contents, err := os.ReadFile(filepath.Join(d.PipeDir(), pidFileName))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
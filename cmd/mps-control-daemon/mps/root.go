@@ -26,19 +26,35 @@ const (
 	ContainerRoot = Root("/mps")
 )
 
+// ContainerRootFor returns the MPS root in containers for the specified
+// config. The host root set by --mps-root is mounted at the same path in the
+// containers of the device plugin, the MPS control daemon, and workloads. If
+// no host root is set, ContainerRoot is returned.
+func ContainerRootFor(config *spec.Config) Root {
+	if config == nil || config.Flags.MpsRoot == nil || *config.Flags.MpsRoot == "" {
+		return ContainerRoot
+	}
+	return Root(*config.Flags.MpsRoot)
+}
+
+// ReadyFile returns the file that indicates that all MPS daemons were started.
+func (r Root) ReadyFile() string {
+	return r.Path(readyFileName)
+}
+
 // Root represents an MPS root.
 // This is where per-resource pipe and log directories are created.
-// For containerised applications the host root is typically mounted to /mps in the container.
+// For containerised applications the host root is mounted at the same path in the container.
 type Root string
 
 // LogDir returns the per-resource pipe dir for the specified root.
 func (r Root) LogDir(resourceName spec.ResourceName) string {
-	return r.Path(string(resourceName), "log")
+	return r.daemonPath(resourceName, "", "log")
 }
 
 // PipeDir returns the per-resource pipe dir for the specified root.
 func (r Root) PipeDir(resourceName spec.ResourceName) string {
-	return r.Path(string(resourceName), "pipe")
+	return r.daemonPath(resourceName, "", "pipe")
 }
 
 // ShmDir returns the shm dir associated with the root.
@@ -47,9 +63,15 @@ func (r Root) ShmDir(resourceName spec.ResourceName) string {
 	return r.Path("shm")
 }
 
-// startedFile returns the per-resource .started file name for the specified root.
-func (r Root) startedFile(resourceName spec.ResourceName) string {
-	return r.Path(string(resourceName), ".started")
+// daemonPath returns a path in the directory of the daemon for the specified
+// resource. Each MIG device is controlled by a daemon of its own, whose
+// directory is named after the UUID of the MIG device and nested in the
+// directory of the resource.
+func (r Root) daemonPath(resourceName spec.ResourceName, migUUID string, name string) string {
+	if migUUID == "" {
+		return r.Path(string(resourceName), name)
+	}
+	return r.Path(string(resourceName), migUUID, name)
 }
 
 // Path returns a path relative to the MPS root.
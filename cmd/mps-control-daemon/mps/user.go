@@ -0,0 +1,77 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+// User returns the user that the daemon is started as, or nil if the daemon
+// runs as the user of its container. The user is configured per shared
+// resource, so an error is returned if the devices of the daemon were shared
+// with different users, e.g. since resources with different users were
+// renamed to the same resource.
+func (d *Daemon) User() (*spec.MPSUser, error) {
+	var user *spec.MPSUser
+	first := true
+	for _, device := range d.devices {
+		if first {
+			user = device.MPSUser
+			first = false
+			continue
+		}
+		if !user.Equal(device.MPSUser) {
+			return nil, fmt.Errorf("devices of MPS daemon %v are configured with different users", d.name())
+		}
+	}
+	return user, nil
+}
+
+// setUser configures the command to run as the specified user. The command
+// runs as the current user if no user is specified.
+func setUser(cmd *exec.Cmd, user *spec.MPSUser) {
+	if user == nil {
+		return
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{
+			Uid:    user.UID,
+			Gid:    user.GID,
+			Groups: []uint32{},
+		},
+	}
+}
+
+// chownToUser changes the owner of the specified directories to the specified
+// user so that a daemon running as this user can create its pipes and logs
+// there. The owner is left unchanged if no user is specified.
+func chownToUser(user *spec.MPSUser, dirs ...string) error {
+	if user == nil {
+		return nil
+	}
+	for _, dir := range dirs {
+		if err := os.Chown(dir, int(user.UID), int(user.GID)); err != nil {
+			return fmt.Errorf("error changing owner of %v to %v:%v: %w", dir, user.UID, user.GID, err)
+		}
+	}
+	return nil
+}
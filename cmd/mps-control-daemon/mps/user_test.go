@@ -0,0 +1,103 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mps
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
+)
+
+func TestDaemonUser(t *testing.T) {
+	testCases := []struct {
+		description   string
+		users         []*spec.MPSUser
+		expectedUser  *spec.MPSUser
+		expectedError bool
+	}{
+		{
+			description: "no user",
+			users:       []*spec.MPSUser{nil, nil},
+		},
+		{
+			description:  "same user",
+			users:        []*spec.MPSUser{{UID: 1000, GID: 1000}, {UID: 1000, GID: 1000}},
+			expectedUser: &spec.MPSUser{UID: 1000, GID: 1000},
+		},
+		{
+			description:   "different users",
+			users:         []*spec.MPSUser{{UID: 1000, GID: 1000}, {UID: 1000, GID: 2000}},
+			expectedError: true,
+		},
+		{
+			description:   "user and no user",
+			users:         []*spec.MPSUser{{UID: 1000, GID: 1000}, nil},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			devices := make(rm.Devices)
+			for i, user := range tc.users {
+				id := string(rm.NewAnnotatedID("GPU-0", i))
+				devices[id] = &rm.Device{
+					Device:   pluginapi.Device{ID: id},
+					Replicas: len(tc.users),
+					MPSUser:  user,
+				}
+			}
+			d := newDaemon("nvidia.com/gpu", devices, Root(t.TempDir()))
+
+			user, err := d.User()
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedUser, user)
+		})
+	}
+}
+
+func TestSetUser(t *testing.T) {
+	cmd := exec.Command("true")
+	setUser(cmd, nil)
+	require.Nil(t, cmd.SysProcAttr)
+
+	setUser(cmd, &spec.MPSUser{UID: 1000, GID: 2000})
+	require.Equal(t, uint32(1000), cmd.SysProcAttr.Credential.Uid)
+	require.Equal(t, uint32(2000), cmd.SysProcAttr.Credential.Gid)
+	require.Empty(t, cmd.SysProcAttr.Credential.Groups)
+}
+
+func TestChownToUser(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, chownToUser(nil, dir))
+
+	// Changing the owner to the current user does not require privileges.
+	user := &spec.MPSUser{UID: uint32(os.Getuid()), GID: uint32(os.Getgid())}
+	require.NoError(t, chownToUser(user, dir))
+
+	require.Error(t, chownToUser(user, dir+"/missing"))
+}
@@ -34,15 +34,16 @@ import (
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/internal/flags"
 	"github.com/NVIDIA/k8s-device-plugin/internal/info"
 	"github.com/NVIDIA/k8s-device-plugin/internal/plugin"
 	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
+	"github.com/NVIDIA/k8s-device-plugin/internal/root"
 	"github.com/NVIDIA/k8s-device-plugin/internal/watch"
 )
 
 type options struct {
 	flags         []cli.Flag
-	configFile    string
 	kubeletSocket string
 }
 
@@ -56,71 +57,9 @@ func main() {
 		return start(ctx, o)
 	}
 
-	c.Flags = []cli.Flag{
-		&cli.StringFlag{
-			Name:    "mig-strategy",
-			Value:   spec.MigStrategyNone,
-			Usage:   "the desired strategy for exposing MIG devices on GPUs that support it:\n\t\t[none | single | mixed]",
-			EnvVars: []string{"MIG_STRATEGY"},
-		},
-		&cli.BoolFlag{
-			Name:    "fail-on-init-error",
-			Value:   true,
-			Usage:   "fail the plugin if an error is encountered during initialization, otherwise block indefinitely",
-			EnvVars: []string{"FAIL_ON_INIT_ERROR"},
-		},
-		&cli.StringFlag{
-			Name:    "resource-name-prefix",
-			Value:   "nvidia.com",
-			Usage:   "the prefix to use for resource names (e.g., 'nvidia.com' for nvidia.com/gpu)",
-			EnvVars: []string{"RESOURCE_NAME_PREFIX"},
-		},
-		&cli.StringFlag{
-			Name:    "driver-root",
-			Aliases: []string{"nvidia-driver-root"},
-			Value:   "/",
-			Usage:   "the root path for the NVIDIA driver installation on the host (typical values are '/' or '/run/nvidia/driver')",
-			EnvVars: []string{"NVIDIA_DRIVER_ROOT"},
-		},
-		&cli.StringFlag{
-			Name:    "dev-root",
-			Aliases: []string{"nvidia-dev-root"},
-			Usage:   "the root path for the NVIDIA device nodes on the host (typical values are '/' or '/run/nvidia/driver')",
-			EnvVars: []string{"NVIDIA_DEV_ROOT"},
-		},
-		&cli.BoolFlag{
-			Name:    "pass-device-specs",
-			Value:   false,
-			Usage:   "pass the list of DeviceSpecs to the kubelet on Allocate()",
-			EnvVars: []string{"PASS_DEVICE_SPECS"},
-		},
-		&cli.StringSliceFlag{
-			Name:    "device-list-strategy",
-			Value:   cli.NewStringSlice(string(spec.DeviceListStrategyEnvVar)),
-			Usage:   "the desired strategy for passing the device list to the underlying runtime:\n\t\t[envvar | volume-mounts | cdi-annotations]",
-			EnvVars: []string{"DEVICE_LIST_STRATEGY"},
-		},
-		&cli.StringFlag{
-			Name:    "device-id-strategy",
-			Value:   spec.DeviceIDStrategyUUID,
-			Usage:   "the desired strategy for passing device IDs to the underlying runtime:\n\t\t[uuid | index]",
-			EnvVars: []string{"DEVICE_ID_STRATEGY"},
-		},
-		&cli.BoolFlag{
-			Name:    "gdrcopy-enabled",
-			Usage:   "ensure that containers that request NVIDIA GPU resources are started with GDRCopy support",
-			EnvVars: []string{"GDRCOPY_ENABLED"},
-		},
-		&cli.BoolFlag{
-			Name:    "gds-enabled",
-			Usage:   "ensure that containers that request NVIDIA GPU resources are started with GPUDirect Storage support",
-			EnvVars: []string{"GDS_ENABLED"},
-		},
-		&cli.BoolFlag{
-			Name:    "mofed-enabled",
-			Usage:   "ensure that containers that request NVIDIA GPU resources are started with MOFED support",
-			EnvVars: []string{"MOFED_ENABLED"},
-		},
+	c.Flags = flags.CommonFlags("", flags.PluginDeviceDiscoveryStrategies)
+	c.Flags = append(c.Flags, flags.PluginFlags()...)
+	c.Flags = append(c.Flags,
 		&cli.StringFlag{
 			Name:        "kubelet-socket",
 			Value:       pluginapi.KubeletSocket,
@@ -128,59 +67,7 @@ func main() {
 			Destination: &o.kubeletSocket,
 			EnvVars:     []string{"KUBELET_SOCKET"},
 		},
-		&cli.StringFlag{
-			Name:        "config-file",
-			Usage:       "the path to a config file as an alternative to command line options or environment variables",
-			Destination: &o.configFile,
-			EnvVars:     []string{"CONFIG_FILE"},
-		},
-		&cli.StringFlag{
-			Name:    "cdi-annotation-prefix",
-			Value:   spec.DefaultCDIAnnotationPrefix,
-			Usage:   "the prefix to use for CDI container annotation keys",
-			EnvVars: []string{"CDI_ANNOTATION_PREFIX"},
-		},
-		&cli.StringFlag{
-			Name:    "nvidia-cdi-hook-path",
-			Aliases: []string{"nvidia-ctk-path"},
-			Value:   spec.DefaultNvidiaCTKPath,
-			Usage:   "the path to use for NVIDIA CDI hooks in the generated CDI specification",
-			EnvVars: []string{"NVIDIA_CDI_HOOK_PATH", "NVIDIA_CTK_PATH"},
-		},
-		&cli.StringFlag{
-			Name:    "driver-root-ctr-path",
-			Aliases: []string{"container-driver-root"},
-			Value:   spec.DefaultContainerDriverRoot,
-			Usage:   "the path where the NVIDIA driver root is mounted in the container; used for generating CDI specifications",
-			EnvVars: []string{"DRIVER_ROOT_CTR_PATH", "CONTAINER_DRIVER_ROOT"},
-		},
-		&cli.StringFlag{
-			Name:    "mps-root",
-			Usage:   "the path on the host where MPS-specific mounts and files are created by the MPS control daemon manager",
-			EnvVars: []string{"MPS_ROOT"},
-		},
-		&cli.StringFlag{
-			Name:    "device-discovery-strategy",
-			Value:   "auto",
-			Usage:   "the strategy to use to discover devices: 'auto', 'nvml', or 'tegra'",
-			EnvVars: []string{"DEVICE_DISCOVERY_STRATEGY"},
-		},
-		&cli.IntSliceFlag{
-			Name:    "imex-channel-ids",
-			Usage:   "A list of IMEX channels to inject.",
-			EnvVars: []string{"IMEX_CHANNEL_IDS"},
-		},
-		&cli.BoolFlag{
-			Name:    "imex-required",
-			Usage:   "The specified IMEX channels are required",
-			EnvVars: []string{"IMEX_REQUIRED"},
-		},
-		&cli.StringSliceFlag{
-			Name:    "cdi-feature-flags",
-			Usage:   "A set of feature flags to be passed to the CDI spec generation logic",
-			EnvVars: []string{"CDI_FEATURE_FLAGS"},
-		},
-	}
+	)
 	o.flags = c.Flags
 
 	err := c.Run(os.Args)
@@ -190,64 +77,6 @@ func main() {
 	}
 }
 
-func validateFlags(infolib nvinfo.Interface, config *spec.Config) error {
-	deviceListStrategies, err := spec.NewDeviceListStrategies(*config.Flags.Plugin.DeviceListStrategy)
-	if err != nil {
-		return fmt.Errorf("invalid --device-list-strategy option: %v", err)
-	}
-
-	hasNvml, _ := infolib.HasNvml()
-	if deviceListStrategies.AnyCDIEnabled() && !hasNvml {
-		return fmt.Errorf("CDI --device-list-strategy options are only supported on NVML-based systems")
-	}
-
-	if *config.Flags.Plugin.DeviceIDStrategy != spec.DeviceIDStrategyUUID && *config.Flags.Plugin.DeviceIDStrategy != spec.DeviceIDStrategyIndex {
-		return fmt.Errorf("invalid --device-id-strategy option: %v", *config.Flags.Plugin.DeviceIDStrategy)
-	}
-
-	if config.Sharing.SharingStrategy() == spec.SharingStrategyMPS {
-		if *config.Flags.MigStrategy == spec.MigStrategyMixed {
-			return fmt.Errorf("using --mig-strategy=mixed is not supported with MPS")
-		}
-		if config.Flags.MpsRoot == nil || *config.Flags.MpsRoot == "" {
-			return fmt.Errorf("using MPS requires --mps-root to be specified")
-		}
-	}
-
-	switch *config.Flags.DeviceDiscoveryStrategy {
-	case "auto":
-	case "nvml":
-	case "tegra":
-	default:
-		return fmt.Errorf("invalid --device-discovery-strategy option %v", *config.Flags.DeviceDiscoveryStrategy)
-	}
-
-	switch *config.Flags.MigStrategy {
-	case spec.MigStrategyNone:
-	case spec.MigStrategySingle:
-	case spec.MigStrategyMixed:
-	default:
-		return fmt.Errorf("unknown MIG strategy: %v", *config.Flags.MigStrategy)
-	}
-
-	if err := spec.AssertChannelIDsValid(config.Imex.ChannelIDs); err != nil {
-		return fmt.Errorf("invalid IMEX channel IDs: %w", err)
-	}
-
-	// Validate resource name prefix format
-	if config.Flags.ResourceNamePrefix != nil && *config.Flags.ResourceNamePrefix != "" {
-		prefix := *config.Flags.ResourceNamePrefix
-		if prefix == "nvidia.com" {
-			// This is the default, no special validation needed
-			return nil
-		}
-		klog.Warningf("Using custom resource name prefix: %s (default is nvidia.com)", prefix)
-		klog.Warning("All pods requesting GPU resources must be updated to use the new resource name format")
-	}
-
-	return nil
-}
-
 func loadConfig(c *cli.Context, flags []cli.Flag) (*spec.Config, error) {
 	config, err := spec.NewConfig(c, flags)
 	if err != nil {
@@ -273,7 +102,9 @@ func start(c *cli.Context, o *options) error {
 
 	var started bool
 	var restartTimeout <-chan time.Time
+	var mpsHealthCheck <-chan time.Time
 	var plugins []plugin.Interface
+	var mpsDaemons mpsDaemonManager
 restart:
 	// If we are restarting, stop plugins from previous run.
 	if started {
@@ -281,10 +112,15 @@ restart:
 		if err != nil {
 			return fmt.Errorf("error stopping plugins from previous run: %v", err)
 		}
+		// The MPS daemons may be stopped since they are unhealthy, so errors
+		// are not fatal. The daemons and compute modes are reset on start.
+		if err := mpsDaemons.stop(); err != nil {
+			klog.Warningf("Failed to stop MPS daemons from previous run: %v", err)
+		}
 	}
 
 	klog.Info("Starting Plugins.")
-	plugins, restartPlugins, err := startPlugins(c, o)
+	plugins, restartPlugins, err := startPlugins(c, o, &mpsDaemons)
 	if err != nil {
 		return fmt.Errorf("error starting plugins: %v", err)
 	}
@@ -294,6 +130,7 @@ restart:
 		klog.Infof("Failed to start one or more plugins. Retrying in 30s...")
 		restartTimeout = time.After(30 * time.Second)
 	}
+	mpsHealthCheck = mpsDaemons.healthCheck()
 
 	// Start an infinite loop, waiting for several indicators to either log
 	// some messages, trigger a restart of the plugins, or exit the program.
@@ -303,6 +140,15 @@ restart:
 		case <-restartTimeout:
 			goto restart
 
+		// Check the MPS daemons that are managed by the device plugin. If a
+		// daemon is unhealthy, restart the daemons and the plugins.
+		case <-mpsHealthCheck:
+			if err := mpsDaemons.assertHealthy(); err != nil {
+				klog.Errorf("%v; restarting.", err)
+				goto restart
+			}
+			mpsHealthCheck = mpsDaemons.healthCheck()
+
 		// Detect a kubelet restart by watching for a newly created
 		// 'pluginapi.KubeletSocket' file. When this occurs, restart this loop,
 		// restarting all of the plugins in the process.
@@ -335,23 +181,25 @@ exit:
 	if err != nil {
 		return fmt.Errorf("error stopping plugins: %v", err)
 	}
+	if err := mpsDaemons.stop(); err != nil {
+		return fmt.Errorf("error stopping MPS daemons: %v", err)
+	}
 	return nil
 }
 
-func startPlugins(c *cli.Context, o *options) ([]plugin.Interface, bool, error) {
+func startPlugins(c *cli.Context, o *options, mpsDaemons *mpsDaemonManager) ([]plugin.Interface, bool, error) {
 	// Load the configuration file
 	klog.Info("Loading configuration.")
 	config, err := loadConfig(c, o.flags)
 	if err != nil {
 		return nil, false, fmt.Errorf("unable to load config: %v", err)
 	}
-	spec.DisableResourceNamingInConfig(config)
 
-	driverRoot := root(*config.Flags.Plugin.ContainerDriverRoot)
+	driverRoot := root.Root(*config.Flags.Plugin.ContainerDriverRoot)
 	// We construct an NVML library specifying the path to libnvidia-ml.so.1
 	// explicitly so that we don't have to rely on the library path.
 	nvmllib := nvml.New(
-		nvml.WithLibraryPath(driverRoot.tryResolveLibrary("libnvidia-ml.so.1")),
+		nvml.WithLibraryPath(driverRoot.TryResolveLibrary("libnvidia-ml.so.1")),
 	)
 	devicelib := device.New(nvmllib)
 	infolib := nvinfo.New(
@@ -360,7 +208,7 @@ func startPlugins(c *cli.Context, o *options) ([]plugin.Interface, bool, error)
 		nvinfo.WithDeviceLib(devicelib),
 	)
 
-	err = validateFlags(infolib, config)
+	err = flags.ValidatePluginFlags(infolib, config)
 	if err != nil {
 		return nil, false, fmt.Errorf("unable to validate flags: %v", err)
 	}
@@ -379,6 +227,16 @@ func startPlugins(c *cli.Context, o *options) ([]plugin.Interface, bool, error)
 	}
 	klog.Infof("\nRunning with config:\n%v", string(configJSON))
 
+	// Start the MPS daemons if these are managed by the device plugin. The
+	// daemons are started first so that the plugins find them ready.
+	restartDaemons, err := mpsDaemons.start(infolib, nvmllib, devicelib, config)
+	if err != nil {
+		return nil, false, fmt.Errorf("error starting MPS daemons: %v", err)
+	}
+	if restartDaemons {
+		return nil, true, nil
+	}
+
 	// Get the set of plugins.
 	klog.Info("Retrieving plugins.")
 	plugins, err := GetPlugins(c.Context, infolib, nvmllib, devicelib, config)
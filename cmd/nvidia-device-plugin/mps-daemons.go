@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/info"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"k8s.io/klog/v2"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/cmd/mps-control-daemon/mount"
+	"github.com/NVIDIA/k8s-device-plugin/cmd/mps-control-daemon/mps"
+)
+
+// mpsHealthCheckInterval is the interval at which the MPS daemons that are
+// managed by the device plugin are checked.
+const mpsHealthCheckInterval = 30 * time.Second
+
+// mpsDaemonManager runs the MPS daemons in the device plugin if
+// --manage-mps-daemons is set. This replaces the MPS control daemon. The
+// daemons are started before the plugins and stopped after them on each run
+// of the restart loop.
+type mpsDaemonManager struct {
+	// shmRoot is the MPS root at which the shm tmpfs was mounted. The tmpfs is
+	// only mounted again if the root changes.
+	shmRoot mps.Root
+	// root is the MPS root of the running daemons.
+	root    mps.Root
+	daemons []*mps.Daemon
+}
+
+// start starts the MPS daemons for the specified config. The returned bool
+// indicates that a daemon failed to start and that starting the daemons
+// should be retried.
+func (m *mpsDaemonManager) start(infolib info.Interface, nvmllib nvml.Interface, devicelib device.Interface, config *spec.Config) (bool, error) {
+	if config.Flags.ManageMpsDaemons == nil || !*config.Flags.ManageMpsDaemons {
+		return false, nil
+	}
+	if strategy := config.Sharing.SharingStrategy(); strategy != spec.SharingStrategyMPS {
+		klog.InfoS("Sharing strategy is not MPS; not starting MPS daemons", "strategy", strategy)
+		return false, nil
+	}
+
+	root := mps.ContainerRootFor(config)
+	if m.shmRoot != root {
+		klog.InfoS("Mounting MPS shm", "root", root)
+		if err := mount.Shm(string(root)); err != nil {
+			return false, err
+		}
+		if err := mount.BindDevShm(string(root)); err != nil {
+			return false, err
+		}
+		m.shmRoot = root
+	}
+
+	// Restore the compute modes of GPUs of daemons that were not stopped,
+	// e.g. since a previous run crashed.
+	if err := mps.RestoreComputeModes(nvmllib, root); err != nil {
+		return false, fmt.Errorf("error restoring compute modes: %w", err)
+	}
+
+	klog.Info("Retrieving MPS daemons.")
+	daemons, err := mps.NewDaemons(infolib, nvmllib, devicelib,
+		mps.WithConfig(config),
+	)
+	if err != nil {
+		return false, fmt.Errorf("error getting MPS daemons: %w", err)
+	}
+	m.root = root
+	m.daemons = daemons
+
+	klog.Info("Starting MPS daemons.")
+	if err := mps.StartDaemons(root, daemons...); err != nil {
+		klog.Errorf("Failed to start MPS daemons: %v", err)
+		return true, nil
+	}
+	return false, nil
+}
+
+// stop stops the running MPS daemons, if any.
+func (m *mpsDaemonManager) stop() error {
+	if m.root == "" {
+		return nil
+	}
+	err := mps.StopDaemons(m.root, m.daemons...)
+	m.root = ""
+	m.daemons = nil
+	return err
+}
+
+// healthCheck returns a channel on which a value is sent when the running
+// MPS daemons should be checked next. If no daemons are running, a nil
+// channel is returned.
+func (m *mpsDaemonManager) healthCheck() <-chan time.Time {
+	if len(m.daemons) == 0 {
+		return nil
+	}
+	return time.After(mpsHealthCheckInterval)
+}
+
+// assertHealthy checks that the running MPS daemons respond on their control
+// pipes.
+func (m *mpsDaemonManager) assertHealthy() error {
+	return mps.AssertDaemonsHealthy(m.daemons...)
+}
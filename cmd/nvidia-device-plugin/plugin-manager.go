@@ -25,22 +25,25 @@ import (
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
 	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/internal/audit"
 	"github.com/NVIDIA/k8s-device-plugin/internal/cdi"
 	"github.com/NVIDIA/k8s-device-plugin/internal/imex"
+	"github.com/NVIDIA/k8s-device-plugin/internal/nccl"
 	"github.com/NVIDIA/k8s-device-plugin/internal/plugin"
+	"github.com/NVIDIA/k8s-device-plugin/internal/root"
 )
 
 // GetPlugins returns a set of plugins for the specified configuration.
 func GetPlugins(ctx context.Context, infolib info.Interface, nvmllib nvml.Interface, devicelib device.Interface, config *spec.Config) ([]plugin.Interface, error) {
 	// TODO: We could consider passing this as an argument since it should already be used to construct nvmllib.
-	driverRoot := root(*config.Flags.Plugin.ContainerDriverRoot)
+	driverRoot := root.Root(*config.Flags.Plugin.ContainerDriverRoot)
 
 	deviceListStrategies, err := spec.NewDeviceListStrategies(*config.Flags.Plugin.DeviceListStrategy)
 	if err != nil {
 		return nil, fmt.Errorf("invalid device list strategy: %v", err)
 	}
 
-	imexChannels, err := imex.GetChannels(config, driverRoot.getDevRoot())
+	imexChannels, err := imex.GetChannels(config, driverRoot.GetDevRoot())
 	if err != nil {
 		return nil, fmt.Errorf("error querying IMEX channels: %w", err)
 	}
@@ -48,7 +51,7 @@ func GetPlugins(ctx context.Context, infolib info.Interface, nvmllib nvml.Interf
 	cdiHandler, err := cdi.New(infolib, nvmllib, devicelib,
 		cdi.WithDeviceListStrategies(deviceListStrategies),
 		cdi.WithDriverRoot(string(driverRoot)),
-		cdi.WithDevRoot(driverRoot.getDevRoot()),
+		cdi.WithDevRoot(driverRoot.GetDevRoot()),
 		cdi.WithTargetDriverRoot(*config.Flags.NvidiaDriverRoot),
 		cdi.WithTargetDevRoot(*config.Flags.NvidiaDevRoot),
 		cdi.WithNvidiaCTKPath(*config.Flags.Plugin.NvidiaCTKPath),
@@ -63,12 +66,24 @@ func GetPlugins(ctx context.Context, infolib info.Interface, nvmllib nvml.Interf
 		return nil, fmt.Errorf("unable to create cdi handler: %v", err)
 	}
 
+	auditLogger, err := audit.New(config.Flags.Plugin.AuditLog)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create audit logger: %w", err)
+	}
+
+	ncclTopology, err := nccl.New(nvmllib, config.Flags.Plugin.NCCLTopology)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create NCCL topology manager: %w", err)
+	}
+
 	plugins, err := plugin.New(ctx, infolib, nvmllib, devicelib,
 		plugin.WithCDIHandler(cdiHandler),
 		plugin.WithConfig(config),
 		plugin.WithDeviceListStrategies(deviceListStrategies),
 		plugin.WithFailOnInitError(*config.Flags.FailOnInitError),
 		plugin.WithImexChannels(imexChannels),
+		plugin.WithAuditLogger(auditLogger),
+		plugin.WithNCCLTopology(ncclTopology),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create plugins: %w", err)
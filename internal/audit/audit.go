@@ -0,0 +1,236 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+const (
+	defaultMaxSizeMiB = 100
+	defaultMaxBackups = 5
+
+	redactedValue = "<redacted>"
+	redactAll     = "*"
+
+	// The kubelet only records the devices of a container once Allocate has
+	// returned, so the pod is looked up in the background for some time.
+	podLookupTimeout  = 500 * time.Millisecond
+	podLookupInterval = time.Second
+	podLookupAttempts = 10
+)
+
+// Operations recorded in the audit log.
+const (
+	OperationAllocate               = "Allocate"
+	OperationGetPreferredAllocation = "GetPreferredAllocation"
+	// OperationAssign records the pod to which the devices of a previous
+	// Allocate entry were assigned.
+	OperationAssign = "Assign"
+)
+
+// Entry represents a single allocation decision recorded in the audit log.
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Operation string    `json:"operation"`
+	Resource  string    `json:"resource"`
+
+	// RequestedIDs are the annotated device IDs requested by the kubelet.
+	RequestedIDs []string `json:"requestedIDs,omitempty"`
+	// AvailableIDs, MustIncludeIDs, and AllocationSize are only set for a
+	// GetPreferredAllocation request.
+	AvailableIDs   []string `json:"availableIDs,omitempty"`
+	MustIncludeIDs []string `json:"mustIncludeIDs,omitempty"`
+	AllocationSize int      `json:"allocationSize,omitempty"`
+	// PreferredIDs are the annotated device IDs returned by GetPreferredAllocation.
+	PreferredIDs []string `json:"preferredIDs,omitempty"`
+
+	// DeviceIDs are the unique device IDs passed to the container runtime
+	// as determined by the device ID strategy.
+	DeviceIDs []string `json:"deviceIDs,omitempty"`
+	// UUIDs and Indices are the unique physical devices that were resolved.
+	UUIDs   []string `json:"uuids,omitempty"`
+	Indices []string `json:"indices,omitempty"`
+
+	CDIDevices []string          `json:"cdiDevices,omitempty"`
+	Envs       map[string]string `json:"envs,omitempty"`
+
+	// Validation holds the result of validating the request.
+	Validation string `json:"validation,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	// Pod is only set for an Assign entry.
+	Pod *Pod `json:"pod,omitempty"`
+}
+
+// Pod identifies the container to which a set of devices is assigned.
+type Pod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Container string `json:"container"`
+}
+
+// A PodLocator resolves the pod to which a set of devices is assigned.
+type PodLocator interface {
+	Locate(ctx context.Context, resource string, deviceIDs []string) (*Pod, error)
+}
+
+// Logger writes audit entries to a size-rotated JSON-lines file.
+// A nil Logger is valid and discards all entries.
+type Logger struct {
+	mu                sync.Mutex
+	writer            *rotatingWriter
+	redactEnvvars     map[string]bool
+	podLocator        PodLocator
+	podLookupInterval time.Duration
+	// podLookups tracks the pending pod lookups.
+	podLookups sync.WaitGroup
+}
+
+// New creates an audit logger from the specified flags.
+// If the audit log is not configured, a nil logger is returned.
+func New(flags *spec.AuditLogFlags) (*Logger, error) {
+	if flags == nil || flags.Path == nil || *flags.Path == "" {
+		return nil, nil
+	}
+
+	maxSizeMiB := defaultMaxSizeMiB
+	if flags.MaxSizeMiB != nil && *flags.MaxSizeMiB > 0 {
+		maxSizeMiB = *flags.MaxSizeMiB
+	}
+	maxBackups := defaultMaxBackups
+	if flags.MaxBackups != nil && *flags.MaxBackups >= 0 {
+		maxBackups = *flags.MaxBackups
+	}
+
+	l := &Logger{
+		writer:            newRotatingWriter(*flags.Path, int64(maxSizeMiB)*1024*1024, maxBackups),
+		redactEnvvars:     make(map[string]bool),
+		podLookupInterval: podLookupInterval,
+	}
+	if flags.RedactEnvvars != nil {
+		for _, name := range *flags.RedactEnvvars {
+			l.redactEnvvars[name] = true
+		}
+	}
+	if flags.PodResourcesSocket != nil && *flags.PodResourcesSocket != "" {
+		l.podLocator = NewPodResourcesLocator(*flags.PodResourcesSocket)
+	}
+
+	if err := l.writer.assertWritable(); err != nil {
+		return nil, fmt.Errorf("invalid audit log path: %w", err)
+	}
+
+	return l, nil
+}
+
+// Record writes the specified entry to the audit log.
+// Errors are logged and do not fail the allocation. If a pod locator is
+// configured, the pod to which the devices of an Allocate entry are assigned
+// is looked up in the background and recorded in a separate Assign entry.
+func (l *Logger) Record(_ context.Context, entry *Entry) {
+	if l == nil || entry == nil {
+		return
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	entry.Envs = l.redact(entry.Envs)
+	l.write(entry)
+
+	if entry.Operation == OperationAllocate && entry.Error == "" && l.podLocator != nil && len(entry.RequestedIDs) > 0 {
+		l.podLookups.Add(1)
+		go func() {
+			defer l.podLookups.Done()
+			l.recordPod(entry.Resource, entry.RequestedIDs)
+		}()
+	}
+}
+
+// write marshals the specified entry and appends it to the audit log.
+func (l *Logger) write(entry *Entry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		klog.ErrorS(err, "Failed to marshal audit entry", "resource", entry.Resource)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.writer.writeLine(line); err != nil {
+		klog.ErrorS(err, "Failed to write audit entry", "resource", entry.Resource)
+	}
+}
+
+// redact returns a copy of the specified envvars with the values of the
+// configured envvars redacted.
+func (l *Logger) redact(envs map[string]string) map[string]string {
+	if len(envs) == 0 {
+		return envs
+	}
+	redacted := make(map[string]string, len(envs))
+	for k, v := range envs {
+		if l.redactEnvvars[redactAll] || l.redactEnvvars[k] {
+			v = redactedValue
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// recordPod looks up the pod to which the specified devices are assigned and
+// records it in an Assign entry. The lookup is retried until the kubelet
+// reports the assignment or the maximum number of attempts is reached.
+func (l *Logger) recordPod(resource string, ids []string) {
+	for attempt := 0; attempt < podLookupAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(l.podLookupInterval)
+		}
+		pod, err := l.locatePod(resource, ids)
+		if err != nil {
+			klog.V(4).InfoS("Unable to resolve pod for audit entry", "resource", resource, "error", err)
+			continue
+		}
+		if pod == nil {
+			continue
+		}
+		l.write(&Entry{
+			Timestamp:    time.Now().UTC(),
+			Operation:    OperationAssign,
+			Resource:     resource,
+			RequestedIDs: ids,
+			Pod:          pod,
+		})
+		return
+	}
+	klog.V(4).InfoS("No pod found for audit entry", "resource", resource, "ids", ids)
+}
+
+func (l *Logger) locatePod(resource string, ids []string) (*Pod, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), podLookupTimeout)
+	defer cancel()
+	return l.podLocator.Locate(ctx, resource, ids)
+}
@@ -0,0 +1,174 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+type podLocatorMock func(string, []string) *Pod
+
+func (m podLocatorMock) Locate(_ context.Context, resource string, ids []string) (*Pod, error) {
+	return m(resource, ids), nil
+}
+
+func readEntries(t *testing.T, path string) []Entry {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestNewDisabled(t *testing.T) {
+	l, err := New(nil)
+	require.NoError(t, err)
+	require.Nil(t, l)
+
+	l, err = New(&spec.AuditLogFlags{Path: ptr("")})
+	require.NoError(t, err)
+	require.Nil(t, l)
+
+	// Recording to a nil logger is a no-op.
+	l.Record(context.TODO(), &Entry{})
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "allocations.log")
+	l, err := New(&spec.AuditLogFlags{
+		Path:          &path,
+		RedactEnvvars: &[]string{"SECRET"},
+	})
+	require.NoError(t, err)
+	// The pod is only found on the second lookup as the kubelet records the
+	// assignment after Allocate has returned.
+	lookups := 0
+	l.podLocator = podLocatorMock(func(resource string, ids []string) *Pod {
+		lookups++
+		if lookups == 1 {
+			return nil
+		}
+		return &Pod{Namespace: "default", Name: resource, Container: ids[0]}
+	})
+	l.podLookupInterval = time.Millisecond
+
+	l.Record(context.TODO(), &Entry{
+		Operation:    OperationAllocate,
+		Resource:     "nvidia.com/gpu",
+		RequestedIDs: []string{"GPU-0::1"},
+		Envs: map[string]string{
+			"NVIDIA_VISIBLE_DEVICES": "GPU-0",
+			"SECRET":                 "value",
+		},
+		Validation: "ok",
+	})
+
+	l.podLookups.Wait()
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 2)
+	require.False(t, entries[0].Timestamp.IsZero())
+	require.Equal(t, "GPU-0", entries[0].Envs["NVIDIA_VISIBLE_DEVICES"])
+	require.Equal(t, redactedValue, entries[0].Envs["SECRET"])
+	require.Nil(t, entries[0].Pod)
+
+	require.Equal(t, OperationAssign, entries[1].Operation)
+	require.Equal(t, []string{"GPU-0::1"}, entries[1].RequestedIDs)
+	require.Equal(t, &Pod{Namespace: "default", Name: "nvidia.com/gpu", Container: "GPU-0::1"}, entries[1].Pod)
+}
+
+func TestRedactAll(t *testing.T) {
+	l := &Logger{redactEnvvars: map[string]bool{redactAll: true}}
+	require.Equal(t,
+		map[string]string{"A": redactedValue, "B": redactedValue},
+		l.redact(map[string]string{"A": "a", "B": "b"}),
+	)
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allocations.log")
+	w := newRotatingWriter(path, 10, 2)
+
+	for _, line := range []string{"aaaaaaaa", "bbbbbbbb", "cccccccc", "dddddddd"} {
+		require.NoError(t, w.writeLine([]byte(line)))
+	}
+
+	contents := func(p string) string {
+		b, err := os.ReadFile(p)
+		require.NoError(t, err)
+		return string(b)
+	}
+	require.Equal(t, "dddddddd\n", contents(path))
+	require.Equal(t, "cccccccc\n", contents(path+".1"))
+	require.Equal(t, "bbbbbbbb\n", contents(path+".2"))
+	require.NoFileExists(t, path+".3")
+}
+
+func TestFindPod(t *testing.T) {
+	pods := []*podresourcesapi.PodResources{
+		{
+			Namespace: "ns",
+			Name:      "other",
+			Containers: []*podresourcesapi.ContainerResources{
+				{
+					Name: "ctr",
+					Devices: []*podresourcesapi.ContainerDevices{
+						{ResourceName: "nvidia.com/gpu", DeviceIds: []string{"GPU-1"}},
+					},
+				},
+			},
+		},
+		{
+			Namespace: "ns",
+			Name:      "pod",
+			Containers: []*podresourcesapi.ContainerResources{
+				{
+					Name: "ctr",
+					Devices: []*podresourcesapi.ContainerDevices{
+						{ResourceName: "nvidia.com/gpu.shared", DeviceIds: []string{"GPU-0"}},
+						{ResourceName: "nvidia.com/gpu", DeviceIds: []string{"GPU-0"}},
+					},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, &Pod{Namespace: "ns", Name: "pod", Container: "ctr"}, findPod(pods, "nvidia.com/gpu", []string{"GPU-0"}))
+	require.Nil(t, findPod(pods, "nvidia.com/gpu", []string{"GPU-2"}))
+}
+
+func ptr[T any](x T) *T {
+	return &x
+}
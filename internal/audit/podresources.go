@@ -0,0 +1,97 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+// podResourcesLocator resolves pods using the kubelet PodResources API.
+type podResourcesLocator struct {
+	socket string
+}
+
+var _ PodLocator = (*podResourcesLocator)(nil)
+
+// NewPodResourcesLocator creates a PodLocator that queries the kubelet
+// PodResources API at the specified unix socket.
+func NewPodResourcesLocator(socket string) PodLocator {
+	return &podResourcesLocator{
+		socket: socket,
+	}
+}
+
+// Locate returns the first container to which any of the specified device
+// IDs of the given resource are assigned.
+// Note that the kubelet only records an assignment once the Allocate call for
+// it has completed, meaning that a pod is only found for devices that are
+// already bound to a container.
+func (l *podResourcesLocator) Locate(ctx context.Context, resource string, deviceIDs []string) (*Pod, error) {
+	conn, err := grpc.NewClient("unix://"+l.socket,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", l.socket)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %v: %w", l.socket, err)
+	}
+	defer conn.Close()
+
+	client := podresourcesapi.NewPodResourcesListerClient(conn)
+	resp, err := client.List(ctx, &podresourcesapi.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pod resources: %w", err)
+	}
+
+	return findPod(resp.GetPodResources(), resource, deviceIDs), nil
+}
+
+// findPod returns the first container with any of the specified devices assigned.
+func findPod(pods []*podresourcesapi.PodResources, resource string, deviceIDs []string) *Pod {
+	requested := make(map[string]bool)
+	for _, id := range deviceIDs {
+		requested[id] = true
+	}
+
+	for _, pod := range pods {
+		for _, container := range pod.GetContainers() {
+			for _, devices := range container.GetDevices() {
+				if devices.GetResourceName() != resource {
+					continue
+				}
+				for _, id := range devices.GetDeviceIds() {
+					if !requested[id] {
+						continue
+					}
+					return &Pod{
+						Namespace: pod.GetNamespace(),
+						Name:      pod.GetName(),
+						Container: container.GetName(),
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
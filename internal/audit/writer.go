@@ -0,0 +1,107 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// rotatingWriter appends lines to a file, rotating it once it reaches a
+// maximum size. Rotated files are named <path>.1 (most recent) to
+// <path>.<maxBackups> (oldest).
+//
+// The file is opened for each write so that the writer holds no open file
+// handles between allocations and tolerates the file being removed externally.
+type rotatingWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+}
+
+func newRotatingWriter(path string, maxSize int64, maxBackups int) *rotatingWriter {
+	return &rotatingWriter{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+}
+
+// assertWritable ensures that the parent directory of the log exists and that
+// the log can be opened for writing.
+func (w *rotatingWriter) assertWritable() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeLine appends the specified line (and a trailing newline) to the file,
+// rotating the file first if the write would exceed the maximum size.
+func (w *rotatingWriter) writeLine(line []byte) error {
+	if err := w.rotateIfRequired(int64(len(line) + 1)); err != nil {
+		return fmt.Errorf("error rotating audit log: %w", err)
+	}
+
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (w *rotatingWriter) rotateIfRequired(size int64) error {
+	info, err := os.Stat(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 || info.Size()+size <= w.maxSize {
+		return nil
+	}
+	return w.rotate()
+}
+
+func (w *rotatingWriter) rotate() error {
+	if w.maxBackups == 0 {
+		return os.Remove(w.path)
+	}
+	for i := w.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(w.backup(i), w.backup(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(w.path, w.backup(1))
+}
+
+func (w *rotatingWriter) backup(i int) string {
+	return fmt.Sprintf("%s.%d", w.path, i)
+}
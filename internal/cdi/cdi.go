@@ -111,7 +111,7 @@ func New(infolib info.Interface, nvmllib nvml.Interface, devicelib device.Interf
 		c.targetDevRoot = c.devRoot
 	}
 
-	deviceNamer, err := nvcdi.NewDeviceNamer(c.deviceIDStrategy)
+	deviceNamer, err := newDeviceNamer(c.nvmllib, c.deviceIDStrategy)
 	if err != nil {
 		return nil, err
 	}
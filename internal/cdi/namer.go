@@ -0,0 +1,80 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package cdi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/nvidia-container-toolkit/pkg/nvcdi"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+// pciBusIDNamer names CDI devices by the PCI bus ID of the GPU, e.g.
+// 0000:07:00.0. MIG devices are named by the bus ID of their parent and their
+// index on the parent, e.g. 0000:07:00.0:1, analogous to the index strategy.
+type pciBusIDNamer struct {
+	nvmllib nvml.Interface
+}
+
+var _ nvcdi.DeviceNamer = (*pciBusIDNamer)(nil)
+
+// newDeviceNamer creates a device namer for the specified device ID strategy.
+func newDeviceNamer(nvmllib nvml.Interface, strategy string) (nvcdi.DeviceNamer, error) {
+	if strategy == spec.DeviceIDStrategyPCIBusID {
+		return &pciBusIDNamer{nvmllib: nvmllib}, nil
+	}
+	return nvcdi.NewDeviceNamer(strategy)
+}
+
+// GetDeviceName returns the PCI bus ID of the specified GPU.
+func (n *pciBusIDNamer) GetDeviceName(_ int, d nvcdi.UUIDer) (string, error) {
+	uuid, err := d.GetUUID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get device UUID: %w", err)
+	}
+	device, ret := n.nvmllib.DeviceGetHandleByUUID(uuid)
+	if ret != nvml.SUCCESS {
+		return "", fmt.Errorf("failed to get device handle for %v: %v", uuid, ret)
+	}
+	info, ret := device.GetPciInfo()
+	if ret != nvml.SUCCESS {
+		return "", fmt.Errorf("failed to get PCI info for %v: %v", uuid, ret)
+	}
+
+	var busID []byte
+	for _, b := range info.BusId {
+		if b == 0 {
+			break
+		}
+		busID = append(busID, byte(b))
+	}
+	// NVML reports an eight digit PCI domain.
+	return strings.ToLower(strings.TrimPrefix(string(busID), "0000")), nil
+}
+
+// GetMigDeviceName returns the PCI bus ID of the parent GPU followed by the
+// index of the MIG device.
+func (n *pciBusIDNamer) GetMigDeviceName(i int, parent nvcdi.UUIDer, j int, _ nvcdi.UUIDer) (string, error) {
+	busID, err := n.GetDeviceName(i, parent)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d", busID, j), nil
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flags
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+var (
+	// PluginDeviceDiscoveryStrategies are the device discovery strategies
+	// supported by the device plugin and the MPS control daemon.
+	PluginDeviceDiscoveryStrategies = []string{"auto", "nvml", "tegra"}
+	// GFDDeviceDiscoveryStrategies are the device discovery strategies
+	// supported by GFD.
+	GFDDeviceDiscoveryStrategies = []string{"auto", "nvml", "tegra", "vfio"}
+)
+
+// CommonFlags returns the flags that are shared by the device plugin, GFD, and
+// the MPS control daemon. If envvarPrefix is not empty, each flag is also read
+// from the prefixed envvar, which takes precedence over the unprefixed one.
+func CommonFlags(envvarPrefix string, deviceDiscoveryStrategies []string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "mig-strategy",
+			Value:   spec.MigStrategyNone,
+			Usage:   "the desired strategy for exposing MIG devices on GPUs that support it:\n\t\t[none | single | mixed]",
+			EnvVars: envvars(envvarPrefix, "MIG_STRATEGY"),
+		},
+		&cli.BoolFlag{
+			Name:    "fail-on-init-error",
+			Value:   true,
+			Usage:   "fail if an error is encountered during initialization, otherwise block indefinitely",
+			EnvVars: envvars(envvarPrefix, "FAIL_ON_INIT_ERROR"),
+		},
+		&cli.StringFlag{
+			Name:    "resource-name-prefix",
+			Value:   "nvidia.com",
+			Usage:   "the prefix to use for resource names (e.g., 'nvidia.com' for nvidia.com/gpu)",
+			EnvVars: envvars(envvarPrefix, "RESOURCE_NAME_PREFIX"),
+		},
+		&cli.StringFlag{
+			Name:    "config-file",
+			Usage:   "the path to a config file as an alternative to command line options or environment variables",
+			EnvVars: envvars(envvarPrefix, "CONFIG_FILE"),
+		},
+		&cli.StringFlag{
+			Name:    "device-discovery-strategy",
+			Value:   "auto",
+			Usage:   "the strategy to use to discover devices: " + quotedList(deviceDiscoveryStrategies),
+			EnvVars: envvars(envvarPrefix, "DEVICE_DISCOVERY_STRATEGY"),
+		},
+		&cli.StringFlag{
+			Name:    "driver-root-ctr-path",
+			Aliases: []string{"container-driver-root"},
+			Value:   spec.DefaultContainerDriverRoot,
+			Usage:   "the path where the NVIDIA driver root is mounted in the container; used to locate the NVML library and to generate CDI specifications",
+			EnvVars: envvars(envvarPrefix, "DRIVER_ROOT_CTR_PATH", "CONTAINER_DRIVER_ROOT"),
+		},
+	}
+}
+
+// PluginFlags returns the flags that configure the device plugin. These
+// include the flags returned by MPSDaemonFlags.
+func PluginFlags() []cli.Flag {
+	return append([]cli.Flag{
+		&cli.StringFlag{
+			Name:    "driver-root",
+			Aliases: []string{"nvidia-driver-root"},
+			Value:   "/",
+			Usage:   "the root path for the NVIDIA driver installation on the host (typical values are '/' or '/run/nvidia/driver')",
+			EnvVars: []string{"NVIDIA_DRIVER_ROOT"},
+		},
+		&cli.StringFlag{
+			Name:    "dev-root",
+			Aliases: []string{"nvidia-dev-root"},
+			Usage:   "the root path for the NVIDIA device nodes on the host (typical values are '/' or '/run/nvidia/driver')",
+			EnvVars: []string{"NVIDIA_DEV_ROOT"},
+		},
+		&cli.BoolFlag{
+			Name:    "pass-device-specs",
+			Value:   false,
+			Usage:   "pass the list of DeviceSpecs to the kubelet on Allocate()",
+			EnvVars: []string{"PASS_DEVICE_SPECS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "device-list-strategy",
+			Value:   cli.NewStringSlice(string(spec.DeviceListStrategyEnvVar)),
+			Usage:   "the desired strategy for passing the device list to the underlying runtime:\n\t\t[envvar | volume-mounts | cdi-annotations]",
+			EnvVars: []string{"DEVICE_LIST_STRATEGY"},
+		},
+		&cli.StringFlag{
+			Name:    "device-id-strategy",
+			Value:   spec.DeviceIDStrategyUUID,
+			Usage:   "the desired strategy for passing device IDs to the underlying runtime:\n\t\t[uuid | index | pci-bus-id]",
+			EnvVars: []string{"DEVICE_ID_STRATEGY"},
+		},
+		&cli.BoolFlag{
+			Name:    "gdrcopy-enabled",
+			Usage:   "ensure that containers that request NVIDIA GPU resources are started with GDRCopy support",
+			EnvVars: []string{"GDRCOPY_ENABLED"},
+		},
+		&cli.BoolFlag{
+			Name:    "gds-enabled",
+			Usage:   "ensure that containers that request NVIDIA GPU resources are started with GPUDirect Storage support",
+			EnvVars: []string{"GDS_ENABLED"},
+		},
+		&cli.BoolFlag{
+			Name:    "mofed-enabled",
+			Usage:   "ensure that containers that request NVIDIA GPU resources are started with MOFED support",
+			EnvVars: []string{"MOFED_ENABLED"},
+		},
+		&cli.StringFlag{
+			Name:    "cdi-annotation-prefix",
+			Value:   spec.DefaultCDIAnnotationPrefix,
+			Usage:   "the prefix to use for CDI container annotation keys",
+			EnvVars: []string{"CDI_ANNOTATION_PREFIX"},
+		},
+		&cli.StringFlag{
+			Name:    "nvidia-cdi-hook-path",
+			Aliases: []string{"nvidia-ctk-path"},
+			Value:   spec.DefaultNvidiaCTKPath,
+			Usage:   "the path to use for NVIDIA CDI hooks in the generated CDI specification",
+			EnvVars: []string{"NVIDIA_CDI_HOOK_PATH", "NVIDIA_CTK_PATH"},
+		},
+		&cli.IntSliceFlag{
+			Name:    "imex-channel-ids",
+			Usage:   "A list of IMEX channels to inject.",
+			EnvVars: []string{"IMEX_CHANNEL_IDS"},
+		},
+		&cli.BoolFlag{
+			Name:    "imex-required",
+			Usage:   "The specified IMEX channels are required",
+			EnvVars: []string{"IMEX_REQUIRED"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "cdi-feature-flags",
+			Usage:   "A set of feature flags to be passed to the CDI spec generation logic",
+			EnvVars: []string{"CDI_FEATURE_FLAGS"},
+		},
+		&cli.StringFlag{
+			Name:    "audit-log",
+			Usage:   "the path to a JSON-lines file in which allocation decisions are recorded; if this is empty, no audit log is written",
+			EnvVars: []string{"AUDIT_LOG"},
+		},
+		&cli.IntFlag{
+			Name:    "audit-log-max-size",
+			Value:   100,
+			Usage:   "the size in MiB at which the audit log is rotated",
+			EnvVars: []string{"AUDIT_LOG_MAX_SIZE"},
+		},
+		&cli.IntFlag{
+			Name:    "audit-log-max-backups",
+			Value:   5,
+			Usage:   "the number of rotated audit logs to retain",
+			EnvVars: []string{"AUDIT_LOG_MAX_BACKUPS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "audit-log-redact-envvars",
+			Usage:   "the names of envvars whose values are redacted in the audit log; '*' redacts all values",
+			EnvVars: []string{"AUDIT_LOG_REDACT_ENVVARS"},
+		},
+		&cli.StringFlag{
+			Name:    "pod-resources-socket",
+			Usage:   "the kubelet PodResources socket used to record pod identities in the audit log and to remove unused NCCL topology files (e.g. /var/lib/kubelet/pod-resources/kubelet.sock)",
+			EnvVars: []string{"POD_RESOURCES_SOCKET"},
+		},
+		&cli.StringFlag{
+			Name:    "nccl-topology-dir",
+			Usage:   "the directory in which an NCCL topology file is written for each allocation and mounted into the container; this must be the same path on the host and in the plugin container; if this is empty, no topology files are written",
+			EnvVars: []string{"NCCL_TOPOLOGY_DIR"},
+		},
+	}, MPSDaemonFlags()...)
+}
+
+// MPSDaemonFlags returns the flags of the device plugin that are also read by
+// the MPS control daemon so that both agree on the MPS root and on which of
+// them manages the MPS daemons.
+func MPSDaemonFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "mps-root",
+			Usage:   "the path on the host where MPS-specific mounts and files are created by the MPS control daemon manager; this is mounted at the same path in the containers of the device plugin, the MPS control daemon, and workloads",
+			EnvVars: []string{"MPS_ROOT"},
+		},
+		&cli.BoolFlag{
+			Name:    "manage-mps-daemons",
+			Usage:   "start and stop the MPS control daemons in the device plugin instead of in a separate MPS control daemon; this requires the device plugin container to be privileged",
+			EnvVars: []string{"MANAGE_MPS_DAEMONS"},
+		},
+	}
+}
+
+// envvars returns the specified envvars, preceded by their prefixed variants
+// if a prefix is specified.
+func envvars(prefix string, names ...string) []string {
+	var envvars []string
+	if prefix != "" {
+		for _, name := range names {
+			envvars = append(envvars, prefix+name)
+		}
+	}
+	return append(envvars, names...)
+}
+
+// quotedList returns the specified values as a quoted, comma-separated list,
+// e.g. "'a', 'b', or 'c'".
+func quotedList(values []string) string {
+	var quoted []string
+	for _, v := range values {
+		quoted = append(quoted, fmt.Sprintf("'%v'", v))
+	}
+	if len(quoted) < 2 {
+		return strings.Join(quoted, "")
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + ", or " + quoted[len(quoted)-1]
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flags
+
+import (
+	"fmt"
+	"slices"
+
+	nvinfo "github.com/NVIDIA/go-nvlib/pkg/nvlib/info"
+	"k8s.io/klog/v2"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+// ValidateCommonFlags validates the flags returned by CommonFlags. The device
+// discovery strategy must be one of the specified strategies.
+func ValidateCommonFlags(config *spec.Config, deviceDiscoveryStrategies []string) error {
+	if !slices.Contains(deviceDiscoveryStrategies, *config.Flags.DeviceDiscoveryStrategy) {
+		return fmt.Errorf("invalid --device-discovery-strategy option %v", *config.Flags.DeviceDiscoveryStrategy)
+	}
+
+	switch *config.Flags.MigStrategy {
+	case spec.MigStrategyNone:
+	case spec.MigStrategySingle:
+	case spec.MigStrategyMixed:
+	default:
+		return fmt.Errorf("unknown MIG strategy: %v", *config.Flags.MigStrategy)
+	}
+
+	// Validate resource name prefix format
+	if config.Flags.ResourceNamePrefix != nil && *config.Flags.ResourceNamePrefix != "" {
+		prefix := *config.Flags.ResourceNamePrefix
+		if prefix != "nvidia.com" {
+			klog.Warningf("Using custom resource name prefix: %s (default is nvidia.com)", prefix)
+			klog.Warning("All pods requesting GPU resources must be updated to use the new resource name format")
+		}
+	}
+
+	return nil
+}
+
+// ValidatePluginFlags validates the config of the device plugin.
+func ValidatePluginFlags(infolib nvinfo.Interface, config *spec.Config) error {
+	if err := ValidateCommonFlags(config, PluginDeviceDiscoveryStrategies); err != nil {
+		return err
+	}
+
+	deviceListStrategies, err := spec.NewDeviceListStrategies(*config.Flags.Plugin.DeviceListStrategy)
+	if err != nil {
+		return fmt.Errorf("invalid --device-list-strategy option: %v", err)
+	}
+
+	hasNvml, _ := infolib.HasNvml()
+	if deviceListStrategies.AnyCDIEnabled() && !hasNvml {
+		return fmt.Errorf("CDI --device-list-strategy options are only supported on NVML-based systems")
+	}
+
+	switch *config.Flags.Plugin.DeviceIDStrategy {
+	case spec.DeviceIDStrategyUUID:
+	case spec.DeviceIDStrategyIndex:
+	case spec.DeviceIDStrategyPCIBusID:
+		if *config.Flags.MigStrategy != spec.MigStrategyNone {
+			return fmt.Errorf("using --device-id-strategy=%v is not supported with --mig-strategy=%v since MIG devices have no PCI bus ID", spec.DeviceIDStrategyPCIBusID, *config.Flags.MigStrategy)
+		}
+		if *config.Flags.DeviceDiscoveryStrategy == "tegra" {
+			return fmt.Errorf("using --device-id-strategy=%v is not supported with --device-discovery-strategy=tegra", spec.DeviceIDStrategyPCIBusID)
+		}
+	default:
+		return fmt.Errorf("invalid --device-id-strategy option: %v", *config.Flags.Plugin.DeviceIDStrategy)
+	}
+
+	if err := validateSharing(config); err != nil {
+		return err
+	}
+
+	if err := spec.AssertChannelIDsValid(config.Imex.ChannelIDs); err != nil {
+		return fmt.Errorf("invalid IMEX channel IDs: %w", err)
+	}
+
+	return nil
+}
+
+// ValidateMPSDaemonFlags validates the config of the MPS control daemon. The
+// common flags and the sharing config are validated as for the device plugin
+// so that the MPS control daemon rejects the configs that the device plugin
+// rejects.
+func ValidateMPSDaemonFlags(config *spec.Config) error {
+	if err := ValidateCommonFlags(config, PluginDeviceDiscoveryStrategies); err != nil {
+		return err
+	}
+	return validateSharing(config)
+}
+
+// validateSharing checks that the sharing config only uses MPS-specific
+// settings when sharing with MPS and that the MPS root is set if it does.
+func validateSharing(config *spec.Config) error {
+	if len(config.Sharing.TimeSlicing.Limits) > 0 {
+		return fmt.Errorf("limits are only supported when sharing with MPS")
+	}
+
+	if config.Sharing.TimeSlicing.AllowMultiGPURequests {
+		return fmt.Errorf("allowMultiGPURequests is only supported when sharing with MPS")
+	}
+
+	for _, r := range config.Sharing.TimeSlicing.Resources {
+		if r.User != nil {
+			return fmt.Errorf("a user for %v is only supported when sharing with MPS", r.Name)
+		}
+	}
+
+	if config.Sharing.SharingStrategy() == spec.SharingStrategyMPS {
+		if config.Flags.MpsRoot == nil || *config.Flags.MpsRoot == "" {
+			return fmt.Errorf("using MPS requires --mps-root to be specified")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flags
+
+import (
+	"flag"
+	"testing"
+
+	nvinfo "github.com/NVIDIA/go-nvlib/pkg/nvlib/info"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+type infolibWithNvml struct {
+	nvinfo.Interface
+	hasNvml bool
+}
+
+func (i infolibWithNvml) HasNvml() (bool, string) {
+	return i.hasNvml, ""
+}
+
+// newConfig returns the config constructed from the specified arguments as it
+// is by the device plugin.
+func newConfig(t *testing.T, args ...string) *spec.Config {
+	return newConfigFromFlags(t, append(CommonFlags("", PluginDeviceDiscoveryStrategies), PluginFlags()...), args...)
+}
+
+// newConfigFromFlags returns the config constructed from the specified
+// arguments for the specified flags.
+func newConfigFromFlags(t *testing.T, flags []cli.Flag, args ...string) *spec.Config {
+	flagSet := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	for _, f := range flags {
+		require.NoError(t, f.Apply(flagSet))
+	}
+	require.NoError(t, flagSet.Parse(args))
+
+	config, err := spec.NewConfig(cli.NewContext(cli.NewApp(), flagSet, nil), flags)
+	require.NoError(t, err)
+	return config
+}
+
+func TestValidatePluginFlags(t *testing.T) {
+	testCases := []struct {
+		description   string
+		args          []string
+		hasNvml       bool
+		expectedError string
+	}{
+		{
+			description: "defaults",
+		},
+		{
+			description:   "invalid MIG strategy",
+			args:          []string{"--mig-strategy=all"},
+			expectedError: "unknown MIG strategy: all",
+		},
+		{
+			description:   "invalid device discovery strategy",
+			args:          []string{"--device-discovery-strategy=vfio"},
+			expectedError: "invalid --device-discovery-strategy option vfio",
+		},
+		{
+			description:   "invalid device ID strategy",
+			args:          []string{"--device-id-strategy=serial"},
+			expectedError: "invalid --device-id-strategy option: serial",
+		},
+		{
+			description:   "PCI bus ID strategy with MIG",
+			args:          []string{"--device-id-strategy=pci-bus-id", "--mig-strategy=mixed"},
+			expectedError: "using --device-id-strategy=pci-bus-id is not supported with --mig-strategy=mixed since MIG devices have no PCI bus ID",
+		},
+		{
+			description:   "CDI without NVML",
+			args:          []string{"--device-list-strategy=cdi-annotations"},
+			expectedError: "CDI --device-list-strategy options are only supported on NVML-based systems",
+		},
+		{
+			description: "CDI with NVML",
+			args:        []string{"--device-list-strategy=cdi-annotations"},
+			hasNvml:     true,
+		},
+		{
+			description:   "invalid IMEX channel",
+			args:          []string{"--imex-channel-ids=3000"},
+			expectedError: "invalid IMEX channel IDs",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			config := newConfig(t, tc.args...)
+
+			err := ValidatePluginFlags(infolibWithNvml{hasNvml: tc.hasNvml}, config)
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestValidateCommonFlags(t *testing.T) {
+	config := newConfig(t, "--device-discovery-strategy=vfio")
+	require.NoError(t, ValidateCommonFlags(config, GFDDeviceDiscoveryStrategies))
+	require.Error(t, ValidateCommonFlags(config, PluginDeviceDiscoveryStrategies))
+}
+
+func TestCommonFlagsEnvvarPrefix(t *testing.T) {
+	require.Equal(t, []string{"MIG_STRATEGY"}, envvars("", "MIG_STRATEGY"))
+	require.Equal(t,
+		[]string{"GFD_DRIVER_ROOT_CTR_PATH", "GFD_CONTAINER_DRIVER_ROOT", "DRIVER_ROOT_CTR_PATH", "CONTAINER_DRIVER_ROOT"},
+		envvars("GFD_", "DRIVER_ROOT_CTR_PATH", "CONTAINER_DRIVER_ROOT"),
+	)
+}
+
+func TestValidatePluginFlagsAllowMultiGPURequests(t *testing.T) {
+	config := newConfig(t)
+	config.Sharing.TimeSlicing = spec.ReplicatedResources{
+		AllowMultiGPURequests: true,
+		Resources:             []spec.ReplicatedResource{{Name: "nvidia.com/gpu", Replicas: 2}},
+	}
+	require.ErrorContains(t, ValidatePluginFlags(infolibWithNvml{}, config), "allowMultiGPURequests is only supported when sharing with MPS")
+
+	config = newConfig(t, "--mps-root=/run/nvidia/mps")
+	config.Sharing.MPS = &spec.ReplicatedResources{
+		AllowMultiGPURequests: true,
+		Resources:             []spec.ReplicatedResource{{Name: "nvidia.com/gpu", Replicas: 2}},
+	}
+	require.NoError(t, ValidatePluginFlags(infolibWithNvml{}, config))
+}
+
+func TestValidatePluginFlagsMPSUser(t *testing.T) {
+	config := newConfig(t)
+	config.Sharing.TimeSlicing = spec.ReplicatedResources{
+		Resources: []spec.ReplicatedResource{{Name: "nvidia.com/gpu", Replicas: 2, User: &spec.MPSUser{UID: 1000, GID: 1000}}},
+	}
+	require.ErrorContains(t, ValidatePluginFlags(infolibWithNvml{}, config), "a user for nvidia.com/gpu is only supported when sharing with MPS")
+
+	config = newConfig(t, "--mps-root=/run/nvidia/mps")
+	config.Sharing.MPS = &spec.ReplicatedResources{
+		Resources: []spec.ReplicatedResource{{Name: "nvidia.com/gpu", Replicas: 2, User: &spec.MPSUser{UID: 1000, GID: 1000}}},
+	}
+	require.NoError(t, ValidatePluginFlags(infolibWithNvml{}, config))
+}
+
+func TestManageMpsDaemonsFlag(t *testing.T) {
+	config := newConfig(t)
+	require.False(t, *config.Flags.ManageMpsDaemons)
+
+	config = newConfig(t, "--manage-mps-daemons")
+	require.True(t, *config.Flags.ManageMpsDaemons)
+}
+
+func TestMPSDaemonFlags(t *testing.T) {
+	var names []string
+	for _, f := range MPSDaemonFlags() {
+		names = append(names, f.Names()[0])
+	}
+	require.Equal(t, []string{"mps-root", "manage-mps-daemons"}, names)
+
+	flags := append(CommonFlags("", PluginDeviceDiscoveryStrategies), MPSDaemonFlags()...)
+	flagSet := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	for _, f := range flags {
+		require.NoError(t, f.Apply(flagSet))
+	}
+	require.Error(t, flagSet.Parse([]string{"--audit-log=/var/log/audit.log"}))
+
+	config := newConfigFromFlags(t, flags, "--mps-root=/run/nvidia/mps", "--manage-mps-daemons")
+	require.Equal(t, "/run/nvidia/mps", *config.Flags.MpsRoot)
+	require.True(t, *config.Flags.ManageMpsDaemons)
+	require.NoError(t, ValidateMPSDaemonFlags(config))
+
+	config = newConfigFromFlags(t, flags)
+	config.Sharing.MPS = &spec.ReplicatedResources{
+		Resources: []spec.ReplicatedResource{{Name: "nvidia.com/gpu", Replicas: 2}},
+	}
+	require.ErrorContains(t, ValidateMPSDaemonFlags(config), "using MPS requires --mps-root to be specified")
+}
@@ -18,6 +18,7 @@ package lm
 
 import (
 	"fmt"
+	"strconv"
 
 	"k8s.io/klog/v2"
 
@@ -34,11 +35,11 @@ const (
 )
 
 // migResource is used to track MIG devices for labelling under the single and mixed strategies.
-// This allows a particular resource name to be associated with an resource.Device and count.
+// This allows a particular resource name to be associated with an resource.Device and the devices of the resource.
 type migResource struct {
-	name   spec.ResourceName
-	device resource.Device
-	count  int
+	name    spec.ResourceName
+	device  resource.Device
+	devices []indexedDevice
 }
 
 // NewResourceLabeler creates a labeler for available GPU resources.
@@ -110,43 +111,54 @@ func newMigLabeler(manager resource.Manager, config *spec.Config) (Labeler, erro
 	return labelers, nil
 }
 
-// newGPULabelers creates a set of labelers for full GPUs
+// newGPULabelers creates a set of labelers for full GPUs. The GPUs are grouped
+// by the resource that they are advertised under so that the count and product
+// labels of a resource match the devices that the device plugin advertises.
 func newGPULabelers(manager resource.Manager, config *spec.Config) (Labeler, error) {
-	deviceInfo := mig.NewDeviceInfo(manager)
-
-	devicesByMigEnabled, err := deviceInfo.GetDevicesMap()
+	devices, err := manager.GetDevices()
 	if err != nil {
-		return nil, fmt.Errorf("error getting map of devices: %v", err)
+		return nil, fmt.Errorf("error getting devices: %v", err)
 	}
 
-	if len(devicesByMigEnabled) == 0 {
+	if len(devices) == 0 {
 		return nil, fmt.Errorf("no GPU devices detected")
 	}
 
-	counts := make(map[string]int)
-	migEnabledDevices := make(map[string]resource.Device)
-	for _, device := range devicesByMigEnabled[true] {
-		name, err := device.GetName()
+	models := make(map[string]bool)
+	resourceDevices := make(map[spec.ResourceName][]indexedDevice)
+	migEnabledDevices := make(map[spec.ResourceName]resource.Device)
+	fullGPUs := make(map[spec.ResourceName]resource.Device)
+	for i, device := range devices {
+		model, err := device.GetName()
 		if err != nil {
 			return nil, fmt.Errorf("error getting device name: %v", err)
 		}
-		migEnabledDevices[name] = device
-		counts[name]++
-	}
+		models[model] = true
 
-	fullGPUs := make(map[string]resource.Device)
-	for _, device := range devicesByMigEnabled[false] {
-		name, err := device.GetName()
+		name, err := gpuResourceName(config, model, device, strconv.Itoa(i))
 		if err != nil {
-			return nil, fmt.Errorf("error getting device name: %v", err)
+			return nil, fmt.Errorf("error getting resource name: %w", err)
+		}
+		resourceDevices[name] = append(resourceDevices[name], indexedDevice{Device: device, index: strconv.Itoa(i)})
+
+		isMigEnabled, err := device.IsMigEnabled()
+		if err != nil {
+			return nil, fmt.Errorf("error checking if MIG is enabled: %v", err)
+		}
+		if isMigEnabled {
+			if _, exists := migEnabledDevices[name]; !exists {
+				migEnabledDevices[name] = device
+			}
+			continue
+		}
+		if _, exists := fullGPUs[name]; !exists {
+			fullGPUs[name] = device
 		}
-		fullGPUs[name] = device
-		counts[name]++
 	}
 
-	if len(counts) > 1 {
+	if len(models) > 1 {
 		var names []string
-		for n := range counts {
+		for n := range models {
 			names = append(names, n)
 		}
 		klog.Warningf("Multiple device types detected: %v", names)
@@ -157,7 +169,7 @@ func newGPULabelers(manager resource.Manager, config *spec.Config) (Labeler, err
 	// These do not include sharing information.
 	for name, migEnabledDevice := range migEnabledDevices {
 		// We generate a resource label with no sharing modifications
-		l, err := NewGPUResourceLabelerWithoutSharing(config, migEnabledDevice, counts[name])
+		l, err := NewGPUResourceLabelerWithoutSharing(name, config, migEnabledDevice, len(resourceDevices[name]))
 		if err != nil {
 			return nil, fmt.Errorf("failed to construct labeler: %v", err)
 		}
@@ -168,7 +180,11 @@ func newGPULabelers(manager resource.Manager, config *spec.Config) (Labeler, err
 	// We construct labelers for the full GPUs.
 	// These override any resources with the same name that have MIG enabled.
 	for name, fullGPU := range fullGPUs {
-		l, err := NewGPUResourceLabeler(config, fullGPU, counts[name])
+		selected, err := countSelectedDevices(config, name, resourceDevices[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get devices selected for sharing: %w", err)
+		}
+		l, err := NewGPUResourceLabeler(name, config, fullGPU, len(resourceDevices[name]), selected)
 		if err != nil {
 			return nil, fmt.Errorf("failed to construct labeler: %v", err)
 		}
@@ -196,7 +212,7 @@ func newMigStrategySingleLabeler(manager resource.Manager, config *spec.Config)
 	}
 	// If any migEnabled=true device is empty, we return the set of mig-strategy-invalid labels.
 	if hasEmpty {
-		return newInvalidMigStrategyLabeler(config, migEnabledDevices[0], "at least one MIG device is enabled but empty")
+		return newInvalidMigStrategyLabeler(manager, config, "at least one MIG device is enabled but empty")
 	}
 
 	migDisabledDevices, err := deviceInfo.GetDevicesWithMigDisabled()
@@ -205,53 +221,72 @@ func newMigStrategySingleLabeler(manager resource.Manager, config *spec.Config)
 	}
 	// If we have a mix of mig-enabled and mig-disabled device we return the set of mig-strategy-invalid labels
 	if len(migDisabledDevices) != 0 {
-		return newInvalidMigStrategyLabeler(config, migEnabledDevices[0], "devices with MIG enabled and disable detected")
+		return newInvalidMigStrategyLabeler(manager, config, "devices with MIG enabled and disable detected")
 	}
 
-	migs, err := deviceInfo.GetAllMigDevices()
+	migs, err := getMigDevices(manager)
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve list of MIG devices: %v", err)
 	}
 
-	// Add new MIG related labels on each individual MIG type
-	resources := make(map[string]migResource)
+	profiles := make(map[string]bool)
 	for _, mig := range migs {
 		name, err := mig.GetName()
 		if err != nil {
 			return nil, fmt.Errorf("unable to get MIG device name: %v", err)
 		}
+		profiles[name] = true
+	}
 
-		resource, exists := resources[name]
-		// For the first occurrence we update the device reference and the resource name
-		if !exists {
-			resource.device = mig
-			resource.name = spec.ResourceName(config.GetResourceNamePrefix() + "/gpu")
-		}
-		// We increase the count
-		resource.count++
-
-		resources[name] = resource
+	// Multiple profiles mean that we have more than one MIG profile defined. Return the set of mig-strategy-invalid labels.
+	if len(profiles) != 1 {
+		return newInvalidMigStrategyLabeler(manager, config, "more than one MIG device type present on node")
 	}
 
-	// Multiple resources mean that we have more than one MIG profile defined. Return the set of mig-strategy-invalid labels.
-	if len(resources) != 1 {
-		return newInvalidMigStrategyLabeler(config, migEnabledDevices[0], "more than one MIG device type present on node")
+	resources, err := newMigResources(config, migs, func(string) string { return "gpu" })
+	if err != nil {
+		return nil, err
 	}
 
 	return newMIGDeviceLabelers(resources, config)
 }
 
-func newInvalidMigStrategyLabeler(config *spec.Config, device resource.Device, reason string) (Labeler, error) {
+// newInvalidMigStrategyLabeler creates the mig-strategy-invalid labels for the
+// resource of the first MIG-enabled device.
+func newInvalidMigStrategyLabeler(manager resource.Manager, config *spec.Config, reason string) (Labeler, error) {
 	klog.Warningf("Invalid configuration detected for mig-strategy=single: %v", reason)
 
+	devices, err := manager.GetDevices()
+	if err != nil {
+		return nil, fmt.Errorf("error getting devices: %v", err)
+	}
+	var device resource.Device
+	var index int
+	for i, d := range devices {
+		isMigEnabled, err := d.IsMigEnabled()
+		if err != nil {
+			return nil, fmt.Errorf("error checking if MIG is enabled: %v", err)
+		}
+		if isMigEnabled {
+			device, index = d, i
+			break
+		}
+	}
+	if device == nil {
+		return nil, fmt.Errorf("no MIG-enabled devices detected")
+	}
+
 	model, err := device.GetName()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get device model: %v", err)
 	}
 
-	prefix := config.GetResourceNamePrefix()
+	resourceName, err := gpuResourceName(config, model, device, strconv.Itoa(index))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource name: %w", err)
+	}
 	rl := resourceLabeler{
-		resourceName: spec.ResourceName(prefix + "/gpu"),
+		resourceName: resourceName,
 	}
 
 	labels := rl.productLabel(model, "MIG", "INVALID")
@@ -265,42 +300,95 @@ func newInvalidMigStrategyLabeler(config *spec.Config, device resource.Device, r
 }
 
 func newMigStrategyMixedLabeler(manager resource.Manager, config *spec.Config) (Labeler, error) {
-	deviceInfo := mig.NewDeviceInfo(manager)
-
 	// Enumerate the MIG devices on this node. In mig.strategy=mixed we ignore devices
 	// configured with migEnabled=true but exposing no MIG devices.
-	migs, err := deviceInfo.GetAllMigDevices()
+	migs, err := getMigDevices(manager)
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve list of MIG devices: %v", err)
 	}
 
-	// Add new MIG related labels on each individual MIG type
-	resources := make(map[string]migResource)
+	resources, err := newMigResources(config, migs, func(profile string) string { return "mig-" + profile })
+	if err != nil {
+		return nil, err
+	}
+
+	return newMIGDeviceLabelers(resources, config)
+}
+
+// indexedDevice associates a device with the index that the device plugin
+// enumerates it under. The index of a MIG device has the form
+// <gpu-index>:<mig-index>.
+type indexedDevice struct {
+	resource.Device
+	index string
+}
+
+// getMigDevices returns the MIG devices of all MIG-enabled GPUs along with
+// their indexes.
+func getMigDevices(manager resource.Manager) ([]indexedDevice, error) {
+	devices, err := manager.GetDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	var migs []indexedDevice
+	for i, d := range devices {
+		isMigEnabled, err := d.IsMigEnabled()
+		if err != nil {
+			return nil, err
+		}
+		if !isMigEnabled {
+			continue
+		}
+		devs, err := d.GetMigDevices()
+		if err != nil {
+			return nil, err
+		}
+		for j, m := range devs {
+			migs = append(migs, indexedDevice{Device: m, index: fmt.Sprintf("%d:%d", i, j)})
+		}
+	}
+	return migs, nil
+}
+
+// newMigResources groups the specified MIG devices by the resource that they
+// are advertised under. The default name for a MIG profile is used for
+// devices that match no resource in config.resources.mig.
+func newMigResources(config *spec.Config, migs []indexedDevice, defaultName func(string) string) (map[spec.ResourceName]migResource, error) {
+	resources := make(map[spec.ResourceName]migResource)
 	for _, mig := range migs {
-		name, err := mig.GetName()
+		profile, err := mig.GetName()
 		if err != nil {
 			return nil, fmt.Errorf("unable to get MIG device name: %v", err)
 		}
 
+		name, err := migResourceName(config, profile, mig.Device, mig.index, defaultName(profile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to get resource name for MIG device: %w", err)
+		}
+
 		resource, exists := resources[name]
 		// For the first occurrence we update the device reference and the resource name
 		if !exists {
-			resource.device = mig
-			resource.name = spec.ResourceName(config.GetResourceNamePrefix() + "/mig-" + name)
+			resource.device = mig.Device
+			resource.name = name
 		}
-		// We increase the count
-		resource.count++
+		// We add the device to the devices of the resource
+		resource.devices = append(resource.devices, mig)
 
 		resources[name] = resource
 	}
-
-	return newMIGDeviceLabelers(resources, config)
+	return resources, nil
 }
 
-func newMIGDeviceLabelers(resources map[string]migResource, config *spec.Config) (Labeler, error) {
+func newMIGDeviceLabelers(resources map[spec.ResourceName]migResource, config *spec.Config) (Labeler, error) {
 	var labelers list
 	for _, resource := range resources {
-		l, err := NewMIGResourceLabeler(resource.name, config, resource.device, resource.count)
+		selected, err := countSelectedDevices(config, resource.name, resource.devices)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get devices selected for sharing: %w", err)
+		}
+		l, err := NewMIGResourceLabeler(resource.name, config, resource.device, len(resource.devices), selected)
 		if err != nil {
 			return nil, fmt.Errorf("failed to construct labeler: %v", err)
 		}
@@ -310,3 +398,64 @@ func newMIGDeviceLabelers(resources map[string]migResource, config *spec.Config)
 
 	return labelers, nil
 }
+
+// countSelectedDevices returns the number of the specified devices of the
+// resource that are selected for sharing. The devices are selected in the same
+// way as by the device plugin and must be ordered by index.
+func countSelectedDevices(config *spec.Config, name spec.ResourceName, devices []indexedDevice) (int, error) {
+	for _, r := range config.Sharing.ReplicatedResources().Resources {
+		if r.Name != name {
+			continue
+		}
+		switch {
+		case r.Devices.All:
+			return len(devices), nil
+		case r.Devices.Count > 0:
+			return min(r.Devices.Count, len(devices)), nil
+		case len(r.Devices.List) == 0:
+			// All devices are shared if no devices are specified.
+			return len(devices), nil
+		}
+		selected := 0
+		for _, d := range devices {
+			isSelected, err := isSelectedDevice(r.Devices.List, d)
+			if err != nil {
+				return 0, err
+			}
+			if isSelected {
+				selected++
+			}
+		}
+		return selected, nil
+	}
+	return 0, nil
+}
+
+// isSelectedDevice checks whether the specified device matches any of the
+// specified references by index or UUID. The UUID of the device is only
+// queried if a reference is a UUID.
+func isSelectedDevice(refs []spec.ReplicatedDeviceRef, device indexedDevice) (bool, error) {
+	var uuid string
+	for _, ref := range refs {
+		if ref.IsGPUIndex() || ref.IsMigIndex() {
+			if string(ref) == device.index {
+				return true, nil
+			}
+			continue
+		}
+		if !ref.IsUUID() {
+			continue
+		}
+		if uuid == "" {
+			var err error
+			uuid, err = device.GetUUID()
+			if err != nil {
+				return false, fmt.Errorf("failed to get device UUID: %w", err)
+			}
+		}
+		if string(ref) == uuid {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -417,6 +417,205 @@ func TestMigStrategySingleLabels(t *testing.T) {
 }
 
 // prt returns a reference to whatever type is passed into it
+func TestResourceLabelerWithSelectors(t *testing.T) {
+	testCases := []struct {
+		description    string
+		devices        []resource.Device
+		migStrategy    string
+		resources      spec.Resources
+		expectedLabels Labels
+	}{
+		{
+			description: "full GPUs are counted per selected resource",
+			devices: []resource.Device{
+				rt.NewFullGPU(),
+				rt.NewFullGPU(),
+			},
+			migStrategy: MigStrategyNone,
+			resources: spec.Resources{
+				GPUs: []spec.Resource{
+					{Selector: &spec.DeviceSelector{Indices: []spec.DeviceIndex{"1"}}, Name: "nvidia.com/second"},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/gpu.compute.major":       "8",
+				"nvidia.com/gpu.compute.minor":       "0",
+				"nvidia.com/gpu.family":              "ampere",
+				"nvidia.com/gpu.count":               "1",
+				"nvidia.com/gpu.replicas":            "1",
+				"nvidia.com/gpu.sharing-strategy":    "none",
+				"nvidia.com/gpu.memory":              "300",
+				"nvidia.com/gpu.product":             "MOCKMODEL",
+				"nvidia.com/second.compute.major":    "8",
+				"nvidia.com/second.compute.minor":    "0",
+				"nvidia.com/second.family":           "ampere",
+				"nvidia.com/second.count":            "1",
+				"nvidia.com/second.replicas":         "1",
+				"nvidia.com/second.sharing-strategy": "none",
+				"nvidia.com/second.memory":           "300",
+				"nvidia.com/second.product":          "MOCKMODEL",
+			},
+		},
+		{
+			description: "UUID, PCI device ID, and NUMA node selectors match",
+			devices: []resource.Device{
+				rt.NewFullGPU(),
+			},
+			migStrategy: MigStrategyNone,
+			resources: spec.Resources{
+				GPUs: []spec.Resource{
+					{
+						Selector: &spec.DeviceSelector{
+							UUIDs:        []string{"GPU-MOCK"},
+							PCIDeviceIDs: []string{"0x20B0"},
+							NUMANode:     ptr(0),
+						},
+						Name: "nvidia.com/selected",
+					},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/selected.compute.major":    "8",
+				"nvidia.com/selected.compute.minor":    "0",
+				"nvidia.com/selected.family":           "ampere",
+				"nvidia.com/selected.count":            "1",
+				"nvidia.com/selected.replicas":         "1",
+				"nvidia.com/selected.sharing-strategy": "none",
+				"nvidia.com/selected.memory":           "300",
+				"nvidia.com/selected.product":          "MOCKMODEL",
+			},
+		},
+		{
+			description: "MIG devices are counted per selected resource",
+			devices: []resource.Device{
+				rt.NewMigEnabledDevice(
+					rt.NewMigDevice(1, 1, 5),
+					rt.NewMigDevice(1, 1, 5),
+				),
+			},
+			migStrategy: MigStrategyMixed,
+			resources: spec.Resources{
+				MIGs: []spec.Resource{
+					{Selector: &spec.DeviceSelector{Indices: []spec.DeviceIndex{"0:1"}}, Name: "nvidia.com/second"},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/mig.strategy":                "mixed",
+				"nvidia.com/gpu.count":                   "1",
+				"nvidia.com/gpu.replicas":                "0",
+				"nvidia.com/gpu.sharing-strategy":        "none",
+				"nvidia.com/gpu.memory":                  "300",
+				"nvidia.com/gpu.product":                 "MOCKMODEL",
+				"nvidia.com/mig-1g.5gb.count":            "1",
+				"nvidia.com/mig-1g.5gb.replicas":         "1",
+				"nvidia.com/mig-1g.5gb.sharing-strategy": "none",
+				"nvidia.com/mig-1g.5gb.product":          "MOCKMODEL-MIG-1g.5gb",
+				"nvidia.com/mig-1g.5gb.memory":           "5",
+				"nvidia.com/mig-1g.5gb.multiprocessors":  "0",
+				"nvidia.com/mig-1g.5gb.slices.gi":        "1",
+				"nvidia.com/mig-1g.5gb.slices.ci":        "1",
+				"nvidia.com/mig-1g.5gb.engines.copy":     "0",
+				"nvidia.com/mig-1g.5gb.engines.decoder":  "0",
+				"nvidia.com/mig-1g.5gb.engines.encoder":  "0",
+				"nvidia.com/mig-1g.5gb.engines.jpeg":     "0",
+				"nvidia.com/mig-1g.5gb.engines.ofa":      "0",
+				"nvidia.com/second.count":                "1",
+				"nvidia.com/second.replicas":             "1",
+				"nvidia.com/second.sharing-strategy":     "none",
+				"nvidia.com/second.product":              "MOCKMODEL-MIG-1g.5gb",
+				"nvidia.com/second.memory":               "5",
+				"nvidia.com/second.multiprocessors":      "0",
+				"nvidia.com/second.slices.gi":            "1",
+				"nvidia.com/second.slices.ci":            "1",
+				"nvidia.com/second.engines.copy":         "0",
+				"nvidia.com/second.engines.decoder":      "0",
+				"nvidia.com/second.engines.encoder":      "0",
+				"nvidia.com/second.engines.jpeg":         "0",
+				"nvidia.com/second.engines.ofa":          "0",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			nvmlMock := rt.NewManagerMockWithDevices(tc.devices...)
+
+			config := spec.Config{
+				Flags: spec.Flags{
+					CommandLineFlags: spec.CommandLineFlags{
+						MigStrategy: ptr(tc.migStrategy),
+					},
+				},
+				Resources: tc.resources,
+			}
+
+			l, err := NewResourceLabeler(nvmlMock, &config)
+			require.NoError(t, err)
+
+			labels, err := l.Labels()
+			require.NoError(t, err)
+
+			require.EqualValues(t, tc.expectedLabels, labels)
+		})
+	}
+}
+
+func TestCountSelectedDevices(t *testing.T) {
+	second := rt.NewDeviceMock(false)
+	second.GetUUIDFunc = func() (string, error) { return "GPU-8dc8e4c4-2ea5-4a1e-9c3f-1b5f8f3c7a10", nil }
+	devices := []indexedDevice{
+		{Device: rt.NewFullGPU(), index: "0"},
+		{Device: second, index: "1"},
+	}
+
+	testCases := []struct {
+		description      string
+		replicated       spec.ReplicatedResource
+		expectedSelected int
+	}{
+		{
+			description:      "other resource selects no devices",
+			replicated:       spec.ReplicatedResource{Name: "nvidia.com/other", Devices: spec.ReplicatedDevices{All: true}},
+			expectedSelected: 0,
+		},
+		{
+			description:      "all devices",
+			replicated:       spec.ReplicatedResource{Name: "nvidia.com/gpu", Devices: spec.ReplicatedDevices{All: true}},
+			expectedSelected: 2,
+		},
+		{
+			description:      "device count",
+			replicated:       spec.ReplicatedResource{Name: "nvidia.com/gpu", Devices: spec.ReplicatedDevices{Count: 1}},
+			expectedSelected: 1,
+		},
+		{
+			description:      "device index",
+			replicated:       spec.ReplicatedResource{Name: "nvidia.com/gpu", Devices: spec.ReplicatedDevices{List: []spec.ReplicatedDeviceRef{"1"}}},
+			expectedSelected: 1,
+		},
+		{
+			description:      "device UUID",
+			replicated:       spec.ReplicatedResource{Name: "nvidia.com/gpu", Devices: spec.ReplicatedDevices{List: []spec.ReplicatedDeviceRef{"GPU-8dc8e4c4-2ea5-4a1e-9c3f-1b5f8f3c7a10"}}},
+			expectedSelected: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			config := &spec.Config{
+				Sharing: spec.Sharing{
+					TimeSlicing: spec.ReplicatedResources{
+						Resources: []spec.ReplicatedResource{tc.replicated},
+					},
+				},
+			}
+			selected, err := countSelectedDevices(config, "nvidia.com/gpu", devices)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedSelected, selected)
+		})
+	}
+}
+
 func ptr[T any](x T) *T {
 	return &x
 }
@@ -32,6 +32,10 @@ import (
 
 var errMPSSharingNotSupported = errors.New("MPS sharing is not supported")
 
+// minMigMPSCudaDriverVersion is the minimum CUDA driver version that supports
+// MPS on MIG devices.
+var minMigMPSCudaDriverVersion = [2]int{11, 4}
+
 // NewDeviceLabeler creates a new labeler for the specified resource manager.
 func NewDeviceLabeler(manager resource.Manager, config *spec.Config) (Labeler, error) {
 	if err := manager.Init(); err != nil {
@@ -210,12 +214,24 @@ func isMPSCapable(manager resource.Manager) (bool, error) {
 			return false, fmt.Errorf("failed to check if device is MIG-enabled: %w", err)
 		}
 		if isMigEnabled {
-			return false, fmt.Errorf("%w for mig devices", errMPSSharingNotSupported)
+			return isMigMPSCapable(manager)
 		}
 	}
 	return true, nil
 }
 
+// isMigMPSCapable checks whether the CUDA driver supports MPS on MIG devices.
+func isMigMPSCapable(manager resource.Manager) (bool, error) {
+	major, minor, err := manager.GetCudaDriverVersion()
+	if err != nil {
+		return false, fmt.Errorf("failed to get CUDA driver version: %w", err)
+	}
+	if major < minMigMPSCudaDriverVersion[0] || (major == minMigMPSCudaDriverVersion[0] && minor < minMigMPSCudaDriverVersion[1]) {
+		return false, fmt.Errorf("%w for mig devices with CUDA driver version %d.%d", errMPSSharingNotSupported, major, minor)
+	}
+	return true, nil
+}
+
 // newGPUModeLabeler creates a new labeler that reports the mode of GPUs on the node.
 // GPUs can be in Graphics or Compute mode.
 func newGPUModeLabeler(devices []resource.Device) (Labeler, error) {
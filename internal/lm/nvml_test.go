@@ -176,6 +176,41 @@ func TestSharingLabeler(t *testing.T) {
 					}
 					return devices, nil
 				},
+				GetCudaDriverVersionFunc: func() (int, int, error) {
+					return 12, 2, nil
+				},
+			},
+			config: &spec.Config{
+				Sharing: spec.Sharing{
+					MPS: &spec.ReplicatedResources{
+						Resources: []spec.ReplicatedResource{
+							{
+								Replicas: 2,
+							},
+						},
+					},
+				},
+			},
+			expectedLabels: map[string]string{
+				"nvidia.com/mps.capable": "true",
+			},
+		},
+		{
+			description: "config with mps replicas mig-devices old driver",
+			manager: &resource.ManagerMock{
+				GetDevicesFunc: func() ([]resource.Device, error) {
+					devices := []resource.Device{
+						&resource.DeviceMock{
+							IsMigEnabledFunc: func() (bool, error) {
+								return true, nil
+							},
+						},
+					}
+					return devices, nil
+				},
+				GetCudaDriverVersionFunc: func() (int, int, error) {
+					return 11, 2, nil
+				},
 			},
 			config: &spec.Config{
 				Sharing: spec.Sharing{
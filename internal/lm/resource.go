@@ -28,7 +28,7 @@ import (
 )
 
 // NewGPUResourceLabelerWithoutSharing creates a resource labeler for the specified device that does not apply sharing labels.
-func NewGPUResourceLabelerWithoutSharing(config *spec.Config, device resource.Device, count int) (Labeler, error) {
+func NewGPUResourceLabelerWithoutSharing(resourceName spec.ResourceName, config *spec.Config, device resource.Device, count int) (Labeler, error) {
 	if count == 0 {
 		return empty{}, nil
 	}
@@ -43,9 +43,8 @@ func NewGPUResourceLabelerWithoutSharing(config *spec.Config, device resource.De
 		klog.Warningf("Ignoring error getting memory info for device: %v", err)
 	}
 
-	// Get the resource name from config, but pass nil config to newResourceLabeler to disable sharing
-	resourceName := spec.ResourceName(config.GetResourceNamePrefix() + "/gpu")
-	resourceLabeler := newResourceLabeler(resourceName, nil)
+	// Pass nil config to newResourceLabeler to disable sharing
+	resourceLabeler := newResourceLabeler(resourceName, nil, count, 0)
 
 	architectureLabels, err := newArchitectureLabels(resourceLabeler, device)
 	if err != nil {
@@ -58,7 +57,7 @@ func NewGPUResourceLabelerWithoutSharing(config *spec.Config, device resource.De
 	}
 
 	labelers := Merge(
-		resourceLabeler.baseLabeler(count, model),
+		resourceLabeler.baseLabeler(model),
 		memoryLabeler,
 		architectureLabels,
 	)
@@ -66,8 +65,9 @@ func NewGPUResourceLabelerWithoutSharing(config *spec.Config, device resource.De
 	return labelers, nil
 }
 
-// NewGPUResourceLabeler creates a resource labeler for the specified full GPU device with the specified count
-func NewGPUResourceLabeler(config *spec.Config, device resource.Device, count int) (Labeler, error) {
+// NewGPUResourceLabeler creates a resource labeler for the specified full GPU device with the specified resource name and count.
+// The number of devices of the resource that are selected for sharing is specified by selected.
+func NewGPUResourceLabeler(resourceName spec.ResourceName, config *spec.Config, device resource.Device, count int, selected int) (Labeler, error) {
 	if count == 0 {
 		return empty{}, nil
 	}
@@ -82,9 +82,7 @@ func NewGPUResourceLabeler(config *spec.Config, device resource.Device, count in
 		klog.Warningf("Ignoring error getting memory info for device: %v", err)
 	}
 
-	// Get the resource name from config
-	resourceName := spec.ResourceName(config.GetResourceNamePrefix() + "/gpu")
-	resourceLabeler := newResourceLabeler(resourceName, config)
+	resourceLabeler := newResourceLabeler(resourceName, config, count, selected)
 
 	architectureLabels, err := newArchitectureLabels(resourceLabeler, device)
 	if err != nil {
@@ -97,16 +95,19 @@ func NewGPUResourceLabeler(config *spec.Config, device resource.Device, count in
 	}
 
 	labelers := Merge(
-		resourceLabeler.baseLabeler(count, model),
+		resourceLabeler.baseLabeler(model),
 		memoryLabeler,
 		architectureLabels,
+		resourceLabeler.renamedLabels(model),
+		resourceLabeler.replicaClassLabels(model),
 	)
 
 	return labelers, nil
 }
 
 // NewMIGResourceLabeler creates a resource labeler for the specified full GPU device with the specified resource name.
-func NewMIGResourceLabeler(resourceName spec.ResourceName, config *spec.Config, device resource.Device, count int) (Labeler, error) {
+// The number of devices of the resource that are selected for sharing is specified by selected.
+func NewMIGResourceLabeler(resourceName spec.ResourceName, config *spec.Config, device resource.Device, count int, selected int) (Labeler, error) {
 	if count == 0 {
 		return empty{}, nil
 	}
@@ -125,7 +126,7 @@ func NewMIGResourceLabeler(resourceName spec.ResourceName, config *spec.Config,
 		return nil, fmt.Errorf("failed to get MIG profile name: %v", err)
 	}
 
-	resourceLabeler := newResourceLabeler(resourceName, config)
+	resourceLabeler := newResourceLabeler(resourceName, config, count, selected)
 
 	attributeLabels, err := newMigAttributeLabels(resourceLabeler, device)
 	if err != nil {
@@ -133,14 +134,123 @@ func NewMIGResourceLabeler(resourceName spec.ResourceName, config *spec.Config,
 	}
 
 	labelers := Merge(
-		resourceLabeler.baseLabeler(count, model, "MIG", migProfile),
+		resourceLabeler.baseLabeler(model, "MIG", migProfile),
 		attributeLabels,
+		resourceLabeler.renamedLabels(model, "MIG", migProfile),
+		resourceLabeler.replicaClassLabels(model, "MIG", migProfile),
 	)
 
 	return labelers, nil
 }
 
-func newResourceLabeler(resourceName spec.ResourceName, config *spec.Config) resourceLabeler {
+// gpuResourceName returns the name of the resource that the specified full GPU
+// is advertised under. This is the first resource in config.resources.gpus
+// that matches the GPU, or <prefix>/gpu if there is no match. The index is the
+// index of the GPU as enumerated by the device plugin.
+func gpuResourceName(config *spec.Config, model string, device resource.Device, index string) (spec.ResourceName, error) {
+	attrs := deviceAttributes(config, model, index, device, device)
+	attrs.Product = model
+	name, matched, err := config.Resources.GPUResourceName(attrs)
+	if err != nil {
+		return "", err
+	}
+	if matched {
+		return name, nil
+	}
+	return spec.ResourceName(config.GetResourceNamePrefix() + "/gpu"), nil
+}
+
+// migResourceName returns the name of the resource that the specified MIG
+// device is advertised under. This is the first resource in
+// config.resources.mig that matches the MIG device, or the specified default
+// name if there is no match. The index is the index of the MIG device as
+// enumerated by the device plugin.
+func migResourceName(config *spec.Config, profile string, device resource.Device, index string, defaultName string) (spec.ResourceName, error) {
+	var parent resource.Device
+	if config.Resources.HasSelectors() || config.Resources.HasTemplates() {
+		var err error
+		parent, err = device.GetDeviceHandleFromMigDeviceHandle()
+		if err != nil {
+			return "", fmt.Errorf("failed to get parent of MIG device: %v", err)
+		}
+	}
+	attrs := deviceAttributes(config, profile, index, device, parent)
+	attrs.Profile = profile
+	if parent != nil {
+		product, err := parent.GetName()
+		if err != nil {
+			return "", fmt.Errorf("failed to get device model: %v", err)
+		}
+		attrs.Product = product
+	}
+	name, matched, err := config.Resources.MIGResourceName(attrs)
+	if err != nil {
+		return "", err
+	}
+	if matched {
+		return name, nil
+	}
+	return spec.ResourceName(config.GetResourceNamePrefix() + "/" + defaultName), nil
+}
+
+// deviceAttributes returns the attributes that config.resources are matched
+// against. The compute capability, PCI device ID, NUMA node, and fabric state
+// are queried from the (parent) GPU. Attributes other than the name and index
+// are only queried if a resource uses a selector or a templated name.
+// Attributes that cannot be queried are left unset and selectors on them do
+// not match.
+func deviceAttributes(config *spec.Config, name string, index string, device resource.Device, gpu resource.Device) spec.DeviceAttributes {
+	attrs := spec.DeviceAttributes{
+		Name:  name,
+		Index: index,
+	}
+	if !config.Resources.HasSelectors() && !config.Resources.HasTemplates() {
+		return attrs
+	}
+
+	uuid, err := device.GetUUID()
+	if err != nil {
+		klog.Warningf("Ignoring error getting UUID for device: %v", err)
+	}
+	attrs.UUID = uuid
+
+	totalMemoryMiB, err := device.GetTotalMemoryMiB()
+	if err != nil {
+		klog.Warningf("Ignoring error getting memory info for device: %v", err)
+	}
+	attrs.TotalMemory = totalMemoryMiB * 1024 * 1024
+
+	if gpu == nil {
+		return attrs
+	}
+	major, minor, err := gpu.GetCudaComputeCapability()
+	if err != nil {
+		klog.Warningf("Ignoring error getting compute capability for device: %v", err)
+	} else {
+		attrs.ComputeCapability = fmt.Sprintf("%d.%d", major, minor)
+	}
+	pciDeviceID, err := gpu.GetPCIDeviceID()
+	if err != nil {
+		klog.Warningf("Ignoring error getting PCI device ID for device: %v", err)
+	}
+	attrs.PCIDeviceID = pciDeviceID
+	hasNuma, numaNode, err := gpu.GetNumaNode()
+	if err != nil {
+		klog.Warningf("Ignoring error getting NUMA node for device: %v", err)
+	} else if hasNuma {
+		attrs.NUMANode = &numaNode
+	}
+	fabricAttached, err := gpu.IsFabricAttached()
+	if err != nil {
+		klog.Warningf("Ignoring error checking if device is fabric attached: %v", err)
+	} else {
+		attrs.FabricAttached = &fabricAttached
+	}
+
+	return attrs
+}
+
+func newResourceLabeler(resourceName spec.ResourceName, config *spec.Config, count int, selected int) resourceLabeler {
 	var sharing *spec.Sharing
 	if config != nil {
 		sharing = &config.Sharing
@@ -148,6 +258,8 @@ func newResourceLabeler(resourceName spec.ResourceName, config *spec.Config) res
 	return resourceLabeler{
 		resourceName: resourceName,
 		sharing:      sharing,
+		count:        count,
+		selected:     selected,
 	}
 
 }
@@ -155,6 +267,11 @@ func newResourceLabeler(resourceName spec.ResourceName, config *spec.Config) res
 type resourceLabeler struct {
 	resourceName spec.ResourceName
 	sharing      *spec.Sharing
+	// count is the number of devices of the resource.
+	count int
+	// selected is the number of devices of the resource that are selected
+	// for sharing.
+	selected int
 }
 
 // single creates a single label for the resource. The label key is
@@ -190,12 +307,16 @@ func (rl resourceLabeler) key(suffix string) string {
 }
 
 // baseLabeler generates the product, count, and replicas labels for the resource
-func (rl resourceLabeler) baseLabeler(count int, parts ...string) Labeler {
+func (rl resourceLabeler) baseLabeler(parts ...string) Labeler {
 	replicas := rl.getReplicas()
 	strategy := spec.SharingStrategyNone
 	if rl.sharing != nil && replicas > 1 {
 		strategy = rl.sharing.SharingStrategy()
 	}
+	count := rl.count
+	if rl.isSplit() {
+		count -= rl.selected
+	}
 	rawLabels := map[string]interface{}{
 		"product":          rl.getProductName(parts...),
 		"count":            count,
@@ -210,6 +331,52 @@ func (rl resourceLabeler) baseLabeler(count int, parts ...string) Labeler {
 	return labels
 }
 
+// renamedLabels generates the product, count, replicas, and sharing-strategy
+// labels for the resource that the shared devices of the resource are renamed
+// to. The count is the number of devices that are selected for sharing.
+// These labels are only generated if the devices are split between the
+// resource and the renamed resource; if all devices are shared, the labels of
+// the resource itself describe the shared devices.
+func (rl resourceLabeler) renamedLabels(parts ...string) Labels {
+	labels := make(Labels)
+	r := rl.replicatedResource()
+	if r == nil || r.Rename == "" || len(r.Classes) > 0 || !rl.isSplit() {
+		return labels
+	}
+	renamed := resourceLabeler{
+		resourceName: r.Rename,
+		sharing:      rl.sharing,
+	}
+	renamed.updateLabel(labels, "product", renamed.getProductName(parts...))
+	renamed.updateLabel(labels, "count", rl.selected)
+	renamed.updateLabel(labels, "replicas", r.Replicas)
+	renamed.updateLabel(labels, "sharing-strategy", rl.sharing.SharingStrategy())
+	return labels
+}
+
+// replicaClassLabels generates the product, count, replicas, and memory labels
+// for each replica class of the resource. Each class is advertised as its own
+// resource. The count is the number of devices that are selected for sharing.
+func (rl resourceLabeler) replicaClassLabels(parts ...string) Labels {
+	labels := make(Labels)
+	r := rl.replicatedResource()
+	if r == nil {
+		return labels
+	}
+	for _, class := range r.Classes {
+		cl := resourceLabeler{
+			resourceName: class.Name,
+			sharing:      rl.sharing,
+		}
+		cl.updateLabel(labels, "product", cl.getProductName(parts...))
+		cl.updateLabel(labels, "count", rl.selected)
+		cl.updateLabel(labels, "replicas", class.Replicas)
+		cl.updateLabel(labels, "sharing-strategy", rl.sharing.SharingStrategy())
+		cl.updateLabel(labels, "memory", class.Memory.Value()/(1024*1024))
+	}
+	return labels
+}
+
 // Deprecated
 func (rl resourceLabeler) productLabel(parts ...string) Labels {
 	name := rl.getProductName(parts...)
@@ -241,8 +408,8 @@ func (rl resourceLabeler) getProductName(parts ...string) string {
 func (rl resourceLabeler) getReplicas() int {
 	if rl.sharingDisabled() {
 		return 0
-	} else if r := rl.replicationInfo(); r != nil && r.Replicas > 0 {
-		return r.Replicas
+	} else if r := rl.replicationInfo(); r != nil && r.TotalReplicas() > 0 {
+		return r.TotalReplicas()
 	}
 	return 1
 }
@@ -255,7 +422,7 @@ func (rl resourceLabeler) sharingDisabled() bool {
 
 // isShared checks whether the resource is shared.
 func (rl resourceLabeler) isShared() bool {
-	if r := rl.replicationInfo(); r != nil && r.Replicas > 1 {
+	if r := rl.replicationInfo(); r != nil && r.TotalReplicas() > 1 {
 		return true
 	}
 	return false
@@ -269,9 +436,11 @@ func (rl resourceLabeler) isRenamed() bool {
 	return false
 }
 
-// replicationInfo searches the associated config for the resource and returns the replication info
-func (rl resourceLabeler) replicationInfo() *spec.ReplicatedResource {
-	if rl.sharingDisabled() {
+// replicatedResource searches the associated config for the resource and
+// returns the replicated resource that shares its devices. If none of the
+// devices of the resource are selected for sharing, nil is returned.
+func (rl resourceLabeler) replicatedResource() *spec.ReplicatedResource {
+	if rl.sharingDisabled() || rl.selected == 0 {
 		return nil
 	}
 	for _, r := range rl.sharing.ReplicatedResources().Resources {
@@ -282,6 +451,27 @@ func (rl resourceLabeler) replicationInfo() *spec.ReplicatedResource {
 	return nil
 }
 
+// isSplit checks whether only some devices of the resource are selected for
+// sharing and the selected devices are advertised under other resources. This
+// is the case if the shared resource is renamed or has replica classes. The
+// devices that remain advertised under the resource itself are not shared.
+func (rl resourceLabeler) isSplit() bool {
+	r := rl.replicatedResource()
+	if r == nil || (r.Rename == "" && len(r.Classes) == 0) {
+		return false
+	}
+	return rl.selected < rl.count
+}
+
+// replicationInfo returns the replication info of the devices that are
+// advertised under the resource itself.
+func (rl resourceLabeler) replicationInfo() *spec.ReplicatedResource {
+	if rl.isSplit() {
+		return nil
+	}
+	return rl.replicatedResource()
+}
+
 func newMigAttributeLabels(rl resourceLabeler, device resource.Device) (Labels, error) {
 	attributes, err := device.GetAttributes()
 	if err != nil {
@@ -303,7 +493,7 @@ func newArchitectureLabels(rl resourceLabeler, device resource.Device) (Labels,
 		return make(Labels), nil
 	}
 
-	family := getArchFamily(computeMajor, computeMinor)
+	family := spec.ArchFamily(computeMajor, computeMinor)
 
 	labels := rl.labels(map[string]interface{}{
 		"family":        family,
@@ -314,38 +504,6 @@ func newArchitectureLabels(rl resourceLabeler, device resource.Device) (Labels,
 	return labels, nil
 }
 
-// TODO: This should a function in go-nvlib
-func getArchFamily(computeMajor, computeMinor int) string {
-	switch computeMajor {
-	case 1:
-		return "tesla"
-	case 2:
-		return "fermi"
-	case 3:
-		return "kepler"
-	case 5:
-		return "maxwell"
-	case 6:
-		return "pascal"
-	case 7:
-		if computeMinor < 5 {
-			return "volta"
-		}
-		return "turing"
-	case 8:
-		if computeMinor < 9 {
-			return "ampere"
-		}
-		return "ada-lovelace"
-	case 9:
-		return "hopper"
-	// The Blackwell GPU family is bifurcated into two cuda compute capabilities 10.0 and 12.0
-	case 10, 12:
-		return "blackwell"
-	}
-	return "undefined"
-}
-
 func sanitise(input string) string {
 	var sanitised string
 	re := regexp.MustCompile("[^A-Za-z0-9-_. ]")
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
 
 	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
 	rt "github.com/NVIDIA/k8s-device-plugin/internal/resource/testing"
@@ -31,6 +32,8 @@ func TestGPUResourceLabeler(t *testing.T) {
 	testCases := []struct {
 		description    string
 		count          int
+		selected       *int
+		resources      spec.Resources
 		sharing        spec.Sharing
 		expectedLabels Labels
 	}{
@@ -124,6 +127,184 @@ func TestGPUResourceLabeler(t *testing.T) {
 				"nvidia.com/gpu.compute.minor":    "0",
 			},
 		},
+		{
+			description: "resource name from matching resource pattern",
+			count:       2,
+			resources: spec.Resources{
+				GPUs: []spec.Resource{
+					{Pattern: "OTHER*", Name: "nvidia.com/other"},
+					{Pattern: "MOCK*", Name: "nvidia.com/mock"},
+				},
+			},
+			sharing: spec.Sharing{
+				TimeSlicing: spec.ReplicatedResources{
+					Resources: []spec.ReplicatedResource{
+						{
+							Name:     "nvidia.com/mock",
+							Replicas: 2,
+						},
+					},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/mock.count":            "2",
+				"nvidia.com/mock.replicas":         "2",
+				"nvidia.com/mock.sharing-strategy": "time-slicing",
+				"nvidia.com/mock.memory":           "300",
+				"nvidia.com/mock.product":          "MOCKMODEL-SHARED",
+				"nvidia.com/mock.family":           "ampere",
+				"nvidia.com/mock.compute.major":    "8",
+				"nvidia.com/mock.compute.minor":    "0",
+			},
+		},
+		{
+			description: "resource name from matching resource selector",
+			count:       1,
+			resources: spec.Resources{
+				GPUs: []spec.Resource{
+					{Selector: &spec.DeviceSelector{FabricAttached: ptr(true)}, Name: "nvidia.com/fabric"},
+					{Selector: &spec.DeviceSelector{Memory: &spec.MemoryRange{Min: ptr(apiresource.MustParse("200Mi"))}}, Name: "nvidia.com/large"},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/large.count":            "1",
+				"nvidia.com/large.replicas":         "1",
+				"nvidia.com/large.sharing-strategy": "none",
+				"nvidia.com/large.memory":           "300",
+				"nvidia.com/large.product":          "MOCKMODEL",
+				"nvidia.com/large.family":           "ampere",
+				"nvidia.com/large.compute.major":    "8",
+				"nvidia.com/large.compute.minor":    "0",
+			},
+		},
+		{
+			description: "replica classes are labeled as their own resources",
+			count:       1,
+			sharing: spec.Sharing{
+				TimeSlicing: spec.ReplicatedResources{
+					Resources: []spec.ReplicatedResource{
+						{
+							Name: "nvidia.com/gpu",
+							Classes: []spec.ReplicaClass{
+								{Name: "nvidia.com/gpu-200m", Replicas: 1, Memory: apiresource.MustParse("200Mi")},
+								{Name: "nvidia.com/gpu-50m", Replicas: 2, Memory: apiresource.MustParse("50Mi")},
+							},
+						},
+					},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/gpu.count":                 "1",
+				"nvidia.com/gpu.replicas":              "3",
+				"nvidia.com/gpu.sharing-strategy":      "time-slicing",
+				"nvidia.com/gpu.memory":                "300",
+				"nvidia.com/gpu.product":               "MOCKMODEL-SHARED",
+				"nvidia.com/gpu.family":                "ampere",
+				"nvidia.com/gpu.compute.major":         "8",
+				"nvidia.com/gpu.compute.minor":         "0",
+				"nvidia.com/gpu-200m.count":            "1",
+				"nvidia.com/gpu-200m.replicas":         "1",
+				"nvidia.com/gpu-200m.sharing-strategy": "time-slicing",
+				"nvidia.com/gpu-200m.memory":           "200",
+				"nvidia.com/gpu-200m.product":          "MOCKMODEL",
+				"nvidia.com/gpu-50m.count":             "1",
+				"nvidia.com/gpu-50m.replicas":          "2",
+				"nvidia.com/gpu-50m.sharing-strategy":  "time-slicing",
+				"nvidia.com/gpu-50m.memory":            "50",
+				"nvidia.com/gpu-50m.product":           "MOCKMODEL",
+			},
+		},
+		{
+			description: "renamed subset of devices labels unshared and renamed resources",
+			count:       2,
+			selected:    ptr(1),
+			sharing: spec.Sharing{
+				TimeSlicing: spec.ReplicatedResources{
+					Resources: []spec.ReplicatedResource{
+						{
+							Name:     "nvidia.com/gpu",
+							Rename:   "nvidia.com/gpu.shared",
+							Devices:  spec.ReplicatedDevices{List: []spec.ReplicatedDeviceRef{"1"}},
+							Replicas: 2,
+						},
+					},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/gpu.count":                   "1",
+				"nvidia.com/gpu.replicas":                "1",
+				"nvidia.com/gpu.sharing-strategy":        "none",
+				"nvidia.com/gpu.memory":                  "300",
+				"nvidia.com/gpu.product":                 "MOCKMODEL",
+				"nvidia.com/gpu.family":                  "ampere",
+				"nvidia.com/gpu.compute.major":           "8",
+				"nvidia.com/gpu.compute.minor":           "0",
+				"nvidia.com/gpu.shared.count":            "1",
+				"nvidia.com/gpu.shared.replicas":         "2",
+				"nvidia.com/gpu.shared.sharing-strategy": "time-slicing",
+				"nvidia.com/gpu.shared.product":          "MOCKMODEL",
+			},
+		},
+		{
+			description: "replica classes of subset of devices count selected devices",
+			count:       2,
+			selected:    ptr(1),
+			sharing: spec.Sharing{
+				TimeSlicing: spec.ReplicatedResources{
+					Resources: []spec.ReplicatedResource{
+						{
+							Name:    "nvidia.com/gpu",
+							Devices: spec.ReplicatedDevices{Count: 1},
+							Classes: []spec.ReplicaClass{
+								{Name: "nvidia.com/gpu-50m", Replicas: 2, Memory: apiresource.MustParse("50Mi")},
+							},
+						},
+					},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/gpu.count":                "1",
+				"nvidia.com/gpu.replicas":             "1",
+				"nvidia.com/gpu.sharing-strategy":     "none",
+				"nvidia.com/gpu.memory":               "300",
+				"nvidia.com/gpu.product":              "MOCKMODEL",
+				"nvidia.com/gpu.family":               "ampere",
+				"nvidia.com/gpu.compute.major":        "8",
+				"nvidia.com/gpu.compute.minor":        "0",
+				"nvidia.com/gpu-50m.count":            "1",
+				"nvidia.com/gpu-50m.replicas":         "2",
+				"nvidia.com/gpu-50m.sharing-strategy": "time-slicing",
+				"nvidia.com/gpu-50m.memory":           "50",
+				"nvidia.com/gpu-50m.product":          "MOCKMODEL",
+			},
+		},
+		{
+			description: "no selected devices are not shared",
+			count:       1,
+			selected:    ptr(0),
+			sharing: spec.Sharing{
+				TimeSlicing: spec.ReplicatedResources{
+					Resources: []spec.ReplicatedResource{
+						{
+							Name:     "nvidia.com/gpu",
+							Rename:   "nvidia.com/gpu.shared",
+							Devices:  spec.ReplicatedDevices{List: []spec.ReplicatedDeviceRef{"GPU-OTHER"}},
+							Replicas: 2,
+						},
+					},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/gpu.count":            "1",
+				"nvidia.com/gpu.replicas":         "1",
+				"nvidia.com/gpu.sharing-strategy": "none",
+				"nvidia.com/gpu.memory":           "300",
+				"nvidia.com/gpu.product":          "MOCKMODEL",
+				"nvidia.com/gpu.family":           "ampere",
+				"nvidia.com/gpu.compute.major":    "8",
+				"nvidia.com/gpu.compute.minor":    "0",
+			},
+		},
 		{
 			description: "mps ignores non-matching resource",
 			count:       1,
@@ -197,14 +378,53 @@ func TestGPUResourceLabeler(t *testing.T) {
 				"nvidia.com/gpu.compute.minor":    "0",
 			},
 		},
+		{
+			description: "mps renamed subset of devices labels unshared and renamed resources",
+			count:       3,
+			selected:    ptr(2),
+			sharing: spec.Sharing{
+				MPS: &spec.ReplicatedResources{
+					Resources: []spec.ReplicatedResource{
+						{
+							Name:     "nvidia.com/gpu",
+							Rename:   "nvidia.com/gpu.shared",
+							Devices:  spec.ReplicatedDevices{Count: 2},
+							Replicas: 4,
+						},
+					},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/gpu.count":                   "1",
+				"nvidia.com/gpu.replicas":                "1",
+				"nvidia.com/gpu.sharing-strategy":        "none",
+				"nvidia.com/gpu.memory":                  "300",
+				"nvidia.com/gpu.product":                 "MOCKMODEL",
+				"nvidia.com/gpu.family":                  "ampere",
+				"nvidia.com/gpu.compute.major":           "8",
+				"nvidia.com/gpu.compute.minor":           "0",
+				"nvidia.com/gpu.shared.count":            "2",
+				"nvidia.com/gpu.shared.replicas":         "4",
+				"nvidia.com/gpu.shared.sharing-strategy": "mps",
+				"nvidia.com/gpu.shared.product":          "MOCKMODEL",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.description, func(t *testing.T) {
 			config := &spec.Config{
-				Sharing: tc.sharing,
+				Resources: tc.resources,
+				Sharing:   tc.sharing,
 			}
-			l, err := NewGPUResourceLabeler(config, device, tc.count)
+			resourceName, err := gpuResourceName(config, "MOCKMODEL", device, "0")
+			require.NoError(t, err)
+
+			selected := tc.count
+			if tc.selected != nil {
+				selected = *tc.selected
+			}
+			l, err := NewGPUResourceLabeler(resourceName, config, device, tc.count, selected)
 			require.NoError(t, err)
 
 			labels, err := l.Labels()
@@ -271,6 +491,7 @@ func TestMigResourceLabeler(t *testing.T) {
 		description    string
 		resourceName   spec.ResourceName
 		count          int
+		selected       *int
 		timeSlicing    spec.ReplicatedResources
 		expectedLabels Labels
 	}{
@@ -354,6 +575,41 @@ func TestMigResourceLabeler(t *testing.T) {
 				"nvidia.com/gpu.engines.ofa":      "0",
 			},
 		},
+		{
+			description:  "renamed subset of devices labels unshared and renamed resources",
+			resourceName: "nvidia.com/gpu",
+			count:        2,
+			selected:     ptr(1),
+			timeSlicing: spec.ReplicatedResources{
+				Resources: []spec.ReplicatedResource{
+					{
+						Name:     "nvidia.com/gpu",
+						Rename:   "nvidia.com/gpu.shared",
+						Devices:  spec.ReplicatedDevices{Count: 1},
+						Replicas: 2,
+					},
+				},
+			},
+			expectedLabels: Labels{
+				"nvidia.com/gpu.count":                   "1",
+				"nvidia.com/gpu.replicas":                "1",
+				"nvidia.com/gpu.sharing-strategy":        "none",
+				"nvidia.com/gpu.memory":                  "300",
+				"nvidia.com/gpu.product":                 "MOCKMODEL-MIG-1g.300gb",
+				"nvidia.com/gpu.multiprocessors":         "0",
+				"nvidia.com/gpu.slices.gi":               "1",
+				"nvidia.com/gpu.slices.ci":               "2",
+				"nvidia.com/gpu.engines.copy":            "0",
+				"nvidia.com/gpu.engines.decoder":         "0",
+				"nvidia.com/gpu.engines.encoder":         "0",
+				"nvidia.com/gpu.engines.jpeg":            "0",
+				"nvidia.com/gpu.engines.ofa":             "0",
+				"nvidia.com/gpu.shared.count":            "1",
+				"nvidia.com/gpu.shared.replicas":         "2",
+				"nvidia.com/gpu.shared.sharing-strategy": "time-slicing",
+				"nvidia.com/gpu.shared.product":          "MOCKMODEL-MIG-1g.300gb",
+			},
+		},
 		{
 			description:  "mig mixed appends shared",
 			resourceName: "nvidia.com/mig-1g.1gb",
@@ -425,7 +681,11 @@ func TestMigResourceLabeler(t *testing.T) {
 					TimeSlicing: tc.timeSlicing,
 				},
 			}
-			l, err := NewMIGResourceLabeler(tc.resourceName, config, device, tc.count)
+			selected := tc.count
+			if tc.selected != nil {
+				selected = *tc.selected
+			}
+			l, err := NewMIGResourceLabeler(tc.resourceName, config, device, tc.count, selected)
 			require.NoError(t, err)
 
 			labels, err := l.Labels()
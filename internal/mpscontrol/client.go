@@ -0,0 +1,350 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+// Package mpscontrol implements a client for the control pipe of an MPS
+// control daemon.
+//
+// By default, commands are sent to the 'control' socket in the pipe directory
+// of the daemon directly. A single command terminated by a newline is sent per
+// connection and the response is read until the daemon closes the connection.
+// Commands that succeed without producing output return an empty response; any
+// other response of such a command is an error message.
+//
+// As a fallback, commands can be sent by running nvidia-cuda-mps-control with
+// the pipe directory of the daemon and writing the command to its stdin, which
+// is the documented interface of the control daemon. Since this starts a
+// process per command, this is only used if requested explicitly.
+package mpscontrol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// SocketName is the name of the control socket in the pipe directory.
+	SocketName = "control"
+
+	// DefaultTimeout is the default timeout for a command, including the
+	// time required to connect to the daemon.
+	DefaultTimeout = 5 * time.Second
+
+	// minPinnedMemoryLimit is the smallest pinned memory limit that can be
+	// set since limits are sent in MiB and a limit of 0 disables the limit.
+	minPinnedMemoryLimit = 1024 * 1024
+
+	dialRetryInterval = 100 * time.Millisecond
+)
+
+// CommandError is returned if the daemon rejects a command.
+type CommandError struct {
+	Command string
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command %q failed: %s", e.Command, e.Message)
+}
+
+// Client sends commands to an MPS control daemon.
+type Client struct {
+	pipeDir string
+	timeout time.Duration
+	// controlBin is the executable used to send commands. If this is empty,
+	// commands are sent to the control socket directly.
+	controlBin string
+}
+
+// Option defines a functional option for the client.
+type Option func(*Client)
+
+// WithTimeout sets the timeout for each command.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithControlBin sends commands by running the specified
+// nvidia-cuda-mps-control executable for each command instead of connecting to
+// the control socket of the daemon.
+func WithControlBin(controlBin string) Option {
+	return func(c *Client) {
+		c.controlBin = controlBin
+	}
+}
+
+// New creates a client for the MPS control daemon with the specified pipe
+// directory.
+func New(pipeDir string, opts ...Option) *Client {
+	c := &Client{
+		pipeDir: pipeDir,
+		timeout: DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SetDefaultActiveThreadPercentage sets the default active thread percentage
+// for the MPS servers started by the daemon.
+func (c *Client) SetDefaultActiveThreadPercentage(percentage int) error {
+	return c.exec(fmt.Sprintf("set_default_active_thread_percentage %d", percentage))
+}
+
+// GetDefaultActiveThreadPercentage returns the default active thread
+// percentage of the daemon.
+func (c *Client) GetDefaultActiveThreadPercentage() (float64, error) {
+	command := "get_default_active_thread_percentage"
+	response, err := c.query(command)
+	if err != nil {
+		return 0, err
+	}
+	percentage, err := strconv.ParseFloat(response, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected response to %q: %q", command, response)
+	}
+	return percentage, nil
+}
+
+// SetDefaultDevicePinnedMemoryLimit sets the default pinned memory limit in
+// bytes for the device with the specified ordinal. The limit is rounded down
+// to MiB. Since a limit of 0M disables the limit, limits of less than 1 MiB
+// are rejected.
+func (c *Client) SetDefaultDevicePinnedMemoryLimit(device int, limit uint64) error {
+	if limit < minPinnedMemoryLimit {
+		return fmt.Errorf("pinned memory limit of %d bytes for device %d is less than 1M", limit, device)
+	}
+	return c.exec(fmt.Sprintf("set_default_device_pinned_mem_limit %d %dM", device, limit/1024/1024))
+}
+
+// GetDefaultDevicePinnedMemoryLimit returns the default pinned memory limit in
+// bytes for the device with the specified ordinal. A limit of 0 means that the
+// memory of the device is not limited.
+func (c *Client) GetDefaultDevicePinnedMemoryLimit(device int) (uint64, error) {
+	command := fmt.Sprintf("get_default_device_pinned_mem_limit %d", device)
+	response, err := c.query(command)
+	if err != nil {
+		return 0, err
+	}
+	limit, err := parseMemoryLimit(response)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected response to %q: %w", command, err)
+	}
+	return limit, nil
+}
+
+// GetServerList returns the PIDs of the MPS servers started by the daemon.
+func (c *Client) GetServerList() ([]int, error) {
+	return c.queryPIDs("get_server_list")
+}
+
+// GetClientList returns the PIDs of the clients of the MPS server with the
+// specified PID.
+func (c *Client) GetClientList(serverPID int) ([]int, error) {
+	return c.queryPIDs(fmt.Sprintf("get_client_list %d", serverPID))
+}
+
+// GetDeviceClientList returns the PIDs of the clients of the MPS server with
+// the specified PID by the UUID of the device that they enumerated. Each line
+// of the response lists a device UUID followed by the PIDs of its clients.
+func (c *Client) GetDeviceClientList(serverPID int) (map[string][]int, error) {
+	command := fmt.Sprintf("get_device_client_list %d", serverPID)
+	response, err := c.send(command)
+	if err != nil {
+		return nil, err
+	}
+	clients := make(map[string][]int)
+	for _, line := range strings.Split(response, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pids := []int{}
+		for _, field := range fields[1:] {
+			pid, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, &CommandError{Command: command, Message: response}
+			}
+			pids = append(pids, pid)
+		}
+		clients[fields[0]] = pids
+	}
+	return clients, nil
+}
+
+// Quit shuts down the daemon and the MPS servers started by it.
+func (c *Client) Quit() error {
+	return c.exec("quit")
+}
+
+// exec sends a command that produces no output on success.
+func (c *Client) exec(command string) error {
+	response, err := c.send(command)
+	if err != nil {
+		return err
+	}
+	if response != "" {
+		return &CommandError{Command: command, Message: response}
+	}
+	return nil
+}
+
+// query sends a command that produces output and returns the output.
+func (c *Client) query(command string) (string, error) {
+	response, err := c.send(command)
+	if err != nil {
+		return "", err
+	}
+	if response == "" {
+		return "", &CommandError{Command: command, Message: "no response"}
+	}
+	return response, nil
+}
+
+// queryPIDs sends a command that produces a (possibly empty) list of PIDs
+// with one PID per line.
+func (c *Client) queryPIDs(command string) ([]int, error) {
+	response, err := c.send(command)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, line := range strings.Fields(response) {
+		pid, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, &CommandError{Command: command, Message: response}
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// send sends the specified command to the daemon and returns its response
+// with surrounding whitespace removed.
+func (c *Client) send(command string) (string, error) {
+	var response string
+	var err error
+	if c.controlBin != "" {
+		response, err = c.sendWithControlBin(command)
+	} else {
+		response, err = c.sendToSocket(command)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response), nil
+}
+
+// sendWithControlBin sends the specified command by running the control
+// executable for the pipe directory of the daemon.
+func (c *Client) sendWithControlBin(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.controlBin)
+	cmd.Env = []string{"CUDA_MPS_PIPE_DIRECTORY=" + c.pipeDir}
+	cmd.Stdin = strings.NewReader(command + "\n")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return "", fmt.Errorf("failed to send command %q to MPS control daemon: %w: %s", command, err, strings.TrimSpace(stdout.String()))
+	}
+	return stdout.String(), nil
+}
+
+// sendToSocket sends the specified command to the control socket of the
+// daemon.
+func (c *Client) sendToSocket(command string) (string, error) {
+	deadline := time.Now().Add(c.timeout)
+
+	conn, err := c.dial(deadline)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to MPS control daemon: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", fmt.Errorf("failed to set deadline: %w", err)
+	}
+	if _, err := io.WriteString(conn, command+"\n"); err != nil {
+		return "", fmt.Errorf("failed to send command %q: %w", command, err)
+	}
+	if err := conn.(*net.UnixConn).CloseWrite(); err != nil {
+		return "", fmt.Errorf("failed to send command %q: %w", command, err)
+	}
+	response, err := io.ReadAll(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response to %q: %w", command, err)
+	}
+	return string(response), nil
+}
+
+// dial connects to the control socket. Since a daemon that was just started
+// may not be listening yet, connecting is retried until the deadline if the
+// socket does not exist or refuses the connection.
+func (c *Client) dial(deadline time.Time) (net.Conn, error) {
+	socket := filepath.Join(c.pipeDir, SocketName)
+	for {
+		conn, err := net.DialTimeout("unix", socket, time.Until(deadline))
+		if err == nil {
+			return conn, nil
+		}
+		if !errors.Is(err, syscall.ENOENT) && !errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, err
+		}
+		if time.Now().Add(dialRetryInterval).After(deadline) {
+			return nil, err
+		}
+		time.Sleep(dialRetryInterval)
+	}
+}
+
+// parseMemoryLimit parses a memory limit as reported by the daemon, e.g.
+// "2G" or "512M". Units are binary and a limit without a unit is in bytes.
+func parseMemoryLimit(value string) (uint64, error) {
+	multiplier := uint64(1)
+	switch {
+	case strings.HasSuffix(value, "K"):
+		multiplier = 1024
+	case strings.HasSuffix(value, "M"):
+		multiplier = 1024 * 1024
+	case strings.HasSuffix(value, "G"):
+		multiplier = 1024 * 1024 * 1024
+	}
+	if multiplier != 1 {
+		value = value[:len(value)-1]
+	}
+	limit, err := strconv.ParseFloat(value, 64)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid memory limit %q", value)
+	}
+	return uint64(limit * float64(multiplier)), nil
+}
@@ -0,0 +1,317 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package mpscontrol
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDaemon serves the control socket of an MPS control daemon. It records
+// the commands it receives and replies with the configured responses.
+type fakeDaemon struct {
+	sync.Mutex
+	listener  net.Listener
+	responses map[string]string
+	commands  []string
+	// delay delays each response.
+	delay time.Duration
+}
+
+func newFakeDaemon(t *testing.T, pipeDir string, responses map[string]string) *fakeDaemon {
+	listener, err := net.Listen("unix", filepath.Join(pipeDir, SocketName))
+	require.NoError(t, err)
+	d := &fakeDaemon{
+		listener:  listener,
+		responses: responses,
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go d.serve()
+	return d
+}
+
+func (d *fakeDaemon) serve() {
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			return
+		}
+		go d.handle(conn)
+	}
+}
+
+func (d *fakeDaemon) handle(conn net.Conn) {
+	defer conn.Close()
+	command, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	command = strings.TrimSuffix(command, "\n")
+
+	d.Lock()
+	d.commands = append(d.commands, command)
+	response := d.responses[command]
+	delay := d.delay
+	d.Unlock()
+
+	time.Sleep(delay)
+	_, _ = conn.Write([]byte(response))
+}
+
+func (d *fakeDaemon) received() []string {
+	d.Lock()
+	defer d.Unlock()
+	return append([]string{}, d.commands...)
+}
+
+func TestClient(t *testing.T) {
+	pipeDir := t.TempDir()
+	daemon := newFakeDaemon(t, pipeDir, map[string]string{
+		"get_default_active_thread_percentage":     "25.0\n",
+		"get_default_device_pinned_mem_limit 0":    "2G\n",
+		"get_default_device_pinned_mem_limit 1":    "512M\n",
+		"get_server_list":                          "1234\n5678\n",
+		"get_client_list 1234":                     "42\n",
+		"get_client_list 5678":                     "",
+		"get_device_client_list 1234":              "GPU-0 42 43\nGPU-1\n",
+		"set_default_active_thread_percentage 200": "Invalid value\n",
+	})
+
+	c := New(pipeDir)
+
+	require.NoError(t, c.SetDefaultActiveThreadPercentage(25))
+	require.NoError(t, c.SetDefaultDevicePinnedMemoryLimit(1, 512*1024*1024))
+
+	percentage, err := c.GetDefaultActiveThreadPercentage()
+	require.NoError(t, err)
+	require.Equal(t, 25.0, percentage)
+
+	limit, err := c.GetDefaultDevicePinnedMemoryLimit(0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2*1024*1024*1024), limit)
+
+	limit, err = c.GetDefaultDevicePinnedMemoryLimit(1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(512*1024*1024), limit)
+
+	servers, err := c.GetServerList()
+	require.NoError(t, err)
+	require.Equal(t, []int{1234, 5678}, servers)
+
+	clients, err := c.GetClientList(1234)
+	require.NoError(t, err)
+	require.Equal(t, []int{42}, clients)
+
+	clients, err = c.GetClientList(5678)
+	require.NoError(t, err)
+	require.Empty(t, clients)
+
+	deviceClients, err := c.GetDeviceClientList(1234)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]int{"GPU-0": {42, 43}, "GPU-1": {}}, deviceClients)
+
+	err = c.SetDefaultActiveThreadPercentage(200)
+	var commandErr *CommandError
+	require.ErrorAs(t, err, &commandErr)
+	require.Equal(t, "Invalid value", commandErr.Message)
+
+	_, err = c.GetDefaultDevicePinnedMemoryLimit(2)
+	require.ErrorAs(t, err, &commandErr)
+
+	require.NoError(t, c.Quit())
+
+	require.Equal(t,
+		[]string{
+			"set_default_active_thread_percentage 25",
+			"set_default_device_pinned_mem_limit 1 512M",
+			"get_default_active_thread_percentage",
+			"get_default_device_pinned_mem_limit 0",
+			"get_default_device_pinned_mem_limit 1",
+			"get_server_list",
+			"get_client_list 1234",
+			"get_client_list 5678",
+			"get_device_client_list 1234",
+			"set_default_active_thread_percentage 200",
+			"get_default_device_pinned_mem_limit 2",
+			"quit",
+		},
+		daemon.received(),
+	)
+}
+
+func TestClientTimeout(t *testing.T) {
+	t.Run("daemon not listening", func(t *testing.T) {
+		c := New(t.TempDir(), WithTimeout(300*time.Millisecond))
+		start := time.Now()
+		_, err := c.GetDefaultActiveThreadPercentage()
+		require.Error(t, err)
+		require.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("daemon not responding", func(t *testing.T) {
+		pipeDir := t.TempDir()
+		daemon := newFakeDaemon(t, pipeDir, map[string]string{
+			"get_default_active_thread_percentage": "100.0\n",
+		})
+		daemon.delay = time.Second
+
+		c := New(pipeDir, WithTimeout(200*time.Millisecond))
+		_, err := c.GetDefaultActiveThreadPercentage()
+		var netErr net.Error
+		require.ErrorAs(t, err, &netErr)
+		require.True(t, netErr.Timeout())
+	})
+
+	t.Run("daemon starts listening", func(t *testing.T) {
+		pipeDir := t.TempDir()
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			newFakeDaemon(t, pipeDir, map[string]string{
+				"get_default_active_thread_percentage": "100.0\n",
+			})
+		}()
+
+		c := New(pipeDir, WithTimeout(2*time.Second))
+		percentage, err := c.GetDefaultActiveThreadPercentage()
+		require.NoError(t, err)
+		require.Equal(t, 100.0, percentage)
+	})
+}
+
+// newFakeControlBin creates a script that replaces nvidia-cuda-mps-control. It
+// appends the pipe directory and the command that it reads from stdin to the
+// returned log file and replies with the specified output.
+func newFakeControlBin(t *testing.T, output string, exitCode int) (string, string) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "commands.log")
+	bin := filepath.Join(dir, "nvidia-cuda-mps-control")
+	script := fmt.Sprintf("#!/bin/sh\nread command\necho \"$CUDA_MPS_PIPE_DIRECTORY $command\" >> %s\nprintf '%s'\nexit %d\n", log, output, exitCode)
+	require.NoError(t, os.WriteFile(bin, []byte(script), 0755))
+	return bin, log
+}
+
+func TestClientControlBin(t *testing.T) {
+	pipeDir := t.TempDir()
+
+	t.Run("query", func(t *testing.T) {
+		bin, log := newFakeControlBin(t, "50.0\\n", 0)
+		c := New(pipeDir, WithControlBin(bin))
+
+		percentage, err := c.GetDefaultActiveThreadPercentage()
+		require.NoError(t, err)
+		require.Equal(t, 50.0, percentage)
+
+		commands, err := os.ReadFile(log)
+		require.NoError(t, err)
+		require.Equal(t, pipeDir+" get_default_active_thread_percentage\n", string(commands))
+	})
+
+	t.Run("command", func(t *testing.T) {
+		bin, log := newFakeControlBin(t, "", 0)
+		c := New(pipeDir, WithControlBin(bin))
+
+		require.NoError(t, c.SetDefaultDevicePinnedMemoryLimit(0, 2*1024*1024*1024))
+
+		commands, err := os.ReadFile(log)
+		require.NoError(t, err)
+		require.Equal(t, pipeDir+" set_default_device_pinned_mem_limit 0 2048M\n", string(commands))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		bin, _ := newFakeControlBin(t, "Cannot find MPS control daemon process", 1)
+		c := New(pipeDir, WithControlBin(bin))
+
+		err := c.Quit()
+		require.ErrorContains(t, err, "Cannot find MPS control daemon process")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		bin := filepath.Join(t.TempDir(), "nvidia-cuda-mps-control")
+		require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\nexec sleep 10\n"), 0755))
+		c := New(pipeDir, WithControlBin(bin), WithTimeout(200*time.Millisecond))
+
+		start := time.Now()
+		require.ErrorIs(t, c.Quit(), context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func TestClientDefaultsToSocket(t *testing.T) {
+	pipeDir := t.TempDir()
+	daemon := newFakeDaemon(t, pipeDir, map[string]string{
+		"get_default_active_thread_percentage": "100.0\n",
+	})
+
+	// A control executable in the PATH must not be used unless requested.
+	bin, log := newFakeControlBin(t, "50.0\n", 0)
+	t.Setenv("PATH", filepath.Dir(bin)+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	c := New(pipeDir)
+	percentage, err := c.GetDefaultActiveThreadPercentage()
+	require.NoError(t, err)
+	require.Equal(t, 100.0, percentage)
+	require.Equal(t, []string{"get_default_active_thread_percentage"}, daemon.received())
+	require.NoFileExists(t, log)
+}
+
+func TestSetDefaultDevicePinnedMemoryLimitTooSmall(t *testing.T) {
+	bin, log := newFakeControlBin(t, "", 0)
+	c := New(t.TempDir(), WithControlBin(bin))
+
+	require.ErrorContains(t, c.SetDefaultDevicePinnedMemoryLimit(0, 512*1024), "is less than 1M")
+	require.ErrorContains(t, c.SetDefaultDevicePinnedMemoryLimit(0, 0), "is less than 1M")
+	require.NoFileExists(t, log)
+
+	require.NoError(t, c.SetDefaultDevicePinnedMemoryLimit(0, 1024*1024))
+}
+
+func TestParseMemoryLimit(t *testing.T) {
+	testCases := []struct {
+		value         string
+		expected      uint64
+		expectedError bool
+	}{
+		{value: "0", expected: 0},
+		{value: "1024", expected: 1024},
+		{value: "4K", expected: 4 * 1024},
+		{value: "512M", expected: 512 * 1024 * 1024},
+		{value: "1.5G", expected: 1536 * 1024 * 1024},
+		{value: "-1M", expectedError: true},
+		{value: "many", expectedError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			limit, err := parseMemoryLimit(tc.value)
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, limit)
+		})
+	}
+}
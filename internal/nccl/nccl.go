@@ -0,0 +1,203 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package nccl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"k8s.io/klog/v2"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+const (
+	// ContainerPath is the path at which the topology file is mounted into
+	// containers.
+	ContainerPath = "/var/run/nvidia-device-plugin/nccl-topo.xml"
+
+	// gcInterval is the minimum time between two garbage collections.
+	gcInterval = time.Minute
+	// gcGracePeriod is the time for which a topology file is kept even if no
+	// allocation refers to it. The kubelet only reports an allocation once
+	// the Allocate call for it has completed.
+	gcGracePeriod = 5 * time.Minute
+)
+
+// Allocation identifies the devices of a resource that are allocated to a
+// container.
+type Allocation struct {
+	Resource  string
+	DeviceIDs []string
+}
+
+// AllocationLister lists the allocations that are currently in use.
+type AllocationLister interface {
+	List(ctx context.Context) ([]Allocation, error)
+}
+
+// Manager writes NCCL topology files for allocations to a directory and
+// removes the files of allocations that no longer exist.
+// A nil Manager is valid and writes no files.
+type Manager struct {
+	sync.Mutex
+	nvmllib nvml.Interface
+	dir     string
+	lister  AllocationLister
+
+	lastGC time.Time
+	now    func() time.Time
+}
+
+// New creates an NCCL topology manager from the specified flags.
+// If no topology directory is configured, a nil manager is returned.
+func New(nvmllib nvml.Interface, flags *spec.NCCLTopologyFlags) (*Manager, error) {
+	if flags == nil || flags.Dir == nil || *flags.Dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(*flags.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating NCCL topology directory: %w", err)
+	}
+
+	m := &Manager{
+		nvmllib: nvmllib,
+		dir:     *flags.Dir,
+		now:     time.Now,
+	}
+	if flags.PodResourcesSocket != nil && *flags.PodResourcesSocket != "" {
+		m.lister = NewPodResourcesLister(*flags.PodResourcesSocket)
+	}
+	return m, nil
+}
+
+// Write writes the topology file for the specified allocation of the GPUs with
+// the specified UUIDs and returns its path. The path is the same on the host
+// and in the plugin container.
+func (m *Manager) Write(allocation Allocation, uuids []string) (string, error) {
+	if ret := m.nvmllib.Init(); ret != nvml.SUCCESS {
+		return "", fmt.Errorf("error initializing NVML: %v", ret)
+	}
+	defer func() {
+		_ = m.nvmllib.Shutdown()
+	}()
+
+	gpus, err := getGPUs(m.nvmllib, uuids)
+	if err != nil {
+		return "", err
+	}
+	return m.write(allocation, gpus)
+}
+
+func (m *Manager) write(allocation Allocation, gpus []GPU) (string, error) {
+	topology, err := NewTopology(gpus)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(m.dir, fileName(allocation))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, topology, 0644); err != nil {
+		return "", fmt.Errorf("error writing topology file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("error writing topology file: %w", err)
+	}
+
+	m.maybeCollectGarbage()
+	return path, nil
+}
+
+// maybeCollectGarbage removes the topology files of allocations that no longer
+// exist in the background. Garbage is collected at most once per gcInterval
+// and only if the allocations in use can be listed.
+func (m *Manager) maybeCollectGarbage() {
+	if m.lister == nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.now().Sub(m.lastGC) < gcInterval {
+		return
+	}
+	m.lastGC = m.now()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := m.collectGarbage(ctx); err != nil {
+			klog.Warningf("Failed to remove unused NCCL topology files: %v", err)
+		}
+	}()
+}
+
+// collectGarbage removes all topology files that are older than the grace
+// period and do not belong to an allocation that is in use.
+func (m *Manager) collectGarbage(ctx context.Context) error {
+	allocations, err := m.lister.List(ctx)
+	if err != nil {
+		return err
+	}
+	inUse := make(map[string]bool)
+	for _, allocation := range allocations {
+		inUse[fileName(allocation)] = true
+	}
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return fmt.Errorf("error reading NCCL topology directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".xml") || inUse[entry.Name()] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || m.now().Sub(info.ModTime()) < gcGracePeriod {
+			continue
+		}
+		path := filepath.Join(m.dir, entry.Name())
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			klog.Warningf("Failed to remove NCCL topology file %v: %v", path, err)
+			continue
+		}
+		klog.V(4).Infof("Removed unused NCCL topology file %v", path)
+	}
+	return nil
+}
+
+// fileName returns the name of the topology file of an allocation. The name
+// only depends on the resource and the set of device IDs so that files can be
+// matched to the allocations reported by the kubelet.
+func fileName(allocation Allocation) string {
+	ids := append([]string{}, allocation.DeviceIDs...)
+	sort.Strings(ids)
+	hash := sha256.Sum256([]byte(allocation.Resource + "\n" + strings.Join(ids, ",")))
+
+	_, name, _ := strings.Cut(allocation.Resource, "/")
+	if name == "" {
+		name = allocation.Resource
+	}
+	return fmt.Sprintf("%s-%s.xml", name, hex.EncodeToString(hash[:8]))
+}
@@ -0,0 +1,75 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package nccl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type allocationLister []Allocation
+
+func (l allocationLister) List(context.Context) ([]Allocation, error) {
+	return l, nil
+}
+
+func TestFileName(t *testing.T) {
+	a := Allocation{Resource: "nvidia.com/gpu", DeviceIDs: []string{"GPU-1::0", "GPU-0::1"}}
+	b := Allocation{Resource: "nvidia.com/gpu", DeviceIDs: []string{"GPU-0::1", "GPU-1::0"}}
+	c := Allocation{Resource: "nvidia.com/gpu.shared", DeviceIDs: []string{"GPU-0::1", "GPU-1::0"}}
+
+	require.Equal(t, fileName(a), fileName(b))
+	require.NotEqual(t, fileName(a), fileName(c))
+	require.Regexp(t, `^gpu-[0-9a-f]{16}\.xml$`, fileName(a))
+}
+
+func TestCollectGarbage(t *testing.T) {
+	now := time.Now()
+	inUse := Allocation{Resource: "nvidia.com/gpu", DeviceIDs: []string{"GPU-0"}}
+	released := Allocation{Resource: "nvidia.com/gpu", DeviceIDs: []string{"GPU-1"}}
+	recent := Allocation{Resource: "nvidia.com/gpu", DeviceIDs: []string{"GPU-2"}}
+
+	m := &Manager{
+		dir:    t.TempDir(),
+		lister: allocationLister{inUse},
+		now:    func() time.Time { return now },
+		// Prevent the background collection triggered by write.
+		lastGC: now,
+	}
+	for _, a := range []Allocation{inUse, released, recent} {
+		path, err := m.write(a, []GPU{{BusID: "0000:07:00.0"}})
+		require.NoError(t, err)
+		if a.DeviceIDs[0] != recent.DeviceIDs[0] {
+			old := now.Add(-2 * gcGracePeriod)
+			require.NoError(t, os.Chtimes(path, old, old))
+		}
+	}
+	unrelated := filepath.Join(m.dir, "unrelated.txt")
+	require.NoError(t, os.WriteFile(unrelated, nil, 0644))
+
+	require.NoError(t, m.collectGarbage(context.Background()))
+
+	require.FileExists(t, filepath.Join(m.dir, fileName(inUse)))
+	require.FileExists(t, filepath.Join(m.dir, fileName(recent)))
+	require.NoFileExists(t, filepath.Join(m.dir, fileName(released)))
+	require.FileExists(t, unrelated)
+}
@@ -0,0 +1,75 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package nccl
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+// podResourcesLister lists allocations using the kubelet PodResources API.
+type podResourcesLister struct {
+	socket string
+}
+
+var _ AllocationLister = (*podResourcesLister)(nil)
+
+// NewPodResourcesLister creates an AllocationLister that queries the kubelet
+// PodResources API at the specified unix socket.
+func NewPodResourcesLister(socket string) AllocationLister {
+	return &podResourcesLister{
+		socket: socket,
+	}
+}
+
+// List returns the devices allocated to each container per resource.
+func (l *podResourcesLister) List(ctx context.Context) ([]Allocation, error) {
+	conn, err := grpc.NewClient("unix://"+l.socket,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", l.socket)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %v: %w", l.socket, err)
+	}
+	defer conn.Close()
+
+	client := podresourcesapi.NewPodResourcesListerClient(conn)
+	resp, err := client.List(ctx, &podresourcesapi.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pod resources: %w", err)
+	}
+
+	var allocations []Allocation
+	for _, pod := range resp.GetPodResources() {
+		for _, container := range pod.GetContainers() {
+			for _, devices := range container.GetDevices() {
+				allocations = append(allocations, Allocation{
+					Resource:  devices.GetResourceName(),
+					DeviceIDs: devices.GetDeviceIds(),
+				})
+			}
+		}
+	}
+	return allocations, nil
+}
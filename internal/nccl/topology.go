@@ -0,0 +1,261 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package nccl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+const (
+	// pciClassGPU is the PCI class of a 3D controller.
+	pciClassGPU = "0x030200"
+	// pciClassNVSwitch is the PCI class of an NVSwitch bridge.
+	pciClassNVSwitch = "0x068000"
+	// pciVendorNVIDIA is the PCI vendor ID of NVIDIA.
+	pciVendorNVIDIA = "0x10de"
+)
+
+// GPU holds the information about a GPU that is included in a topology.
+type GPU struct {
+	BusID string
+	// NUMANode is the NUMA node that the GPU is attached to or -1 if unknown.
+	NUMANode int
+	// SM is the compute capability of the GPU as <major><minor>, e.g. 80.
+	SM int
+	// PCIeGeneration and PCIeWidth describe the current PCIe link of the
+	// GPU. Zero values indicate that these are unknown.
+	PCIeGeneration int
+	PCIeWidth      int
+	// NVLinks maps the bus IDs of the remote ends of the GPU's active NVLinks
+	// to the number of links.
+	NVLinks map[string]int
+	// NVSwitchLinks maps the bus IDs of the NVSwitches that the GPU is
+	// connected to to the number of links.
+	NVSwitchLinks map[string]int
+}
+
+// system is the root element of an NCCL topology file.
+type system struct {
+	XMLName xml.Name `xml:"system"`
+	Version int      `xml:"version,attr"`
+	CPUs    []cpu    `xml:"cpu"`
+}
+
+type cpu struct {
+	NUMAID int   `xml:"numaid,attr"`
+	PCIs   []pci `xml:"pci"`
+}
+
+type pci struct {
+	BusID     string `xml:"busid,attr"`
+	Class     string `xml:"class,attr"`
+	Vendor    string `xml:"vendor,attr"`
+	LinkSpeed string `xml:"link_speed,attr,omitempty"`
+	LinkWidth string `xml:"link_width,attr,omitempty"`
+	GPU       gpu    `xml:"gpu"`
+}
+
+type gpu struct {
+	Dev     int      `xml:"dev,attr"`
+	SM      int      `xml:"sm,attr"`
+	NVLinks []nvlink `xml:"nvlink"`
+}
+
+type nvlink struct {
+	Target string `xml:"target,attr"`
+	Count  int    `xml:"count,attr"`
+	TClass string `xml:"tclass,attr"`
+}
+
+// NewTopology returns the NCCL topology XML for the specified GPUs. The GPUs
+// are numbered in the order specified, which is expected to match the order in
+// which they are visible in the container. NVLinks to GPUs that are not
+// included are omitted.
+func NewTopology(gpus []GPU) ([]byte, error) {
+	included := make(map[string]bool)
+	for _, g := range gpus {
+		included[g.BusID] = true
+	}
+
+	byNode := make(map[int][]pci)
+	for i, g := range gpus {
+		p := pci{
+			BusID:  g.BusID,
+			Class:  pciClassGPU,
+			Vendor: pciVendorNVIDIA,
+			GPU: gpu{
+				Dev: i,
+				SM:  g.SM,
+			},
+		}
+		if speed := pcieLinkSpeed(g.PCIeGeneration); speed != "" {
+			p.LinkSpeed = speed
+		}
+		if g.PCIeWidth > 0 {
+			p.LinkWidth = strconv.Itoa(g.PCIeWidth)
+		}
+		for _, target := range sortedKeys(g.NVLinks) {
+			if !included[target] {
+				continue
+			}
+			p.GPU.NVLinks = append(p.GPU.NVLinks, nvlink{Target: target, Count: g.NVLinks[target], TClass: pciClassGPU})
+		}
+		for _, target := range sortedKeys(g.NVSwitchLinks) {
+			p.GPU.NVLinks = append(p.GPU.NVLinks, nvlink{Target: target, Count: g.NVSwitchLinks[target], TClass: pciClassNVSwitch})
+		}
+		byNode[g.NUMANode] = append(byNode[g.NUMANode], p)
+	}
+
+	var nodes []int
+	for node := range byNode {
+		nodes = append(nodes, node)
+	}
+	sort.Ints(nodes)
+
+	s := system{Version: 1}
+	for _, node := range nodes {
+		s.CPUs = append(s.CPUs, cpu{NUMAID: node, PCIs: byNode[node]})
+	}
+
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(s); err != nil {
+		return nil, fmt.Errorf("error encoding topology: %w", err)
+	}
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+// getGPUs queries NVML for the topology information of the GPUs with the
+// specified UUIDs.
+func getGPUs(nvmllib nvml.Interface, uuids []string) ([]GPU, error) {
+	var gpus []GPU
+	for _, uuid := range uuids {
+		d, ret := nvmllib.DeviceGetHandleByUUID(uuid)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device handle for %v: %v", uuid, ret)
+		}
+		pciInfo, ret := d.GetPciInfo()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting PCI info for %v: %v", uuid, ret)
+		}
+		major, minor, ret := d.GetCudaComputeCapability()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting compute capability for %v: %v", uuid, ret)
+		}
+
+		g := GPU{
+			BusID:         busID(pciInfo),
+			SM:            major*10 + minor,
+			NVLinks:       make(map[string]int),
+			NVSwitchLinks: make(map[string]int),
+		}
+		g.NUMANode = numaNode(g.BusID)
+		if generation, ret := d.GetCurrPcieLinkGeneration(); ret == nvml.SUCCESS {
+			g.PCIeGeneration = generation
+		}
+		if width, ret := d.GetCurrPcieLinkWidth(); ret == nvml.SUCCESS {
+			g.PCIeWidth = width
+		}
+
+		for link := 0; link < nvml.NVLINK_MAX_LINKS; link++ {
+			state, ret := d.GetNvLinkState(link)
+			if ret != nvml.SUCCESS || state != nvml.FEATURE_ENABLED {
+				continue
+			}
+			remote, ret := d.GetNvLinkRemotePciInfo(link)
+			if ret != nvml.SUCCESS {
+				continue
+			}
+			remoteType, ret := d.GetNvLinkRemoteDeviceType(link)
+			if ret == nvml.SUCCESS && remoteType == nvml.NVLINK_DEVICE_TYPE_SWITCH {
+				g.NVSwitchLinks[busID(remote)]++
+				continue
+			}
+			g.NVLinks[busID(remote)]++
+		}
+		gpus = append(gpus, g)
+	}
+	return gpus, nil
+}
+
+// busID returns the bus ID of a PCI device in the form used by sysfs and NCCL,
+// e.g. 0000:07:00.0.
+func busID(info nvml.PciInfo) string {
+	var id []byte
+	for _, b := range info.BusId {
+		if byte(b) == '\x00' {
+			break
+		}
+		id = append(id, byte(b))
+	}
+	// NVML reports an eight digit PCI domain.
+	return strings.ToLower(strings.TrimPrefix(string(id), "0000"))
+}
+
+// numaNode returns the NUMA node of the PCI device with the specified bus ID
+// or -1 if this is unknown.
+func numaNode(busID string) int {
+	b, err := os.ReadFile(fmt.Sprintf("/sys/bus/pci/devices/%s/numa_node", busID))
+	if err != nil {
+		return -1
+	}
+	node, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return -1
+	}
+	return node
+}
+
+// pcieLinkSpeed returns the NCCL representation of the speed of a PCIe link of
+// the specified generation.
+func pcieLinkSpeed(generation int) string {
+	switch generation {
+	case 1:
+		return "2.5 GT/s PCIe"
+	case 2:
+		return "5.0 GT/s PCIe"
+	case 3:
+		return "8.0 GT/s PCIe"
+	case 4:
+		return "16.0 GT/s PCIe"
+	case 5:
+		return "32.0 GT/s PCIe"
+	case 6:
+		return "64.0 GT/s PCIe"
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]int) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
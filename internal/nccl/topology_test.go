@@ -0,0 +1,150 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package nccl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTopology(t *testing.T) {
+	testCases := []struct {
+		description string
+		gpus        []GPU
+		expected    string
+	}{
+		{
+			description: "single GPU without links",
+			gpus: []GPU{
+				{BusID: "0000:07:00.0", NUMANode: 0, SM: 80, PCIeGeneration: 4, PCIeWidth: 16},
+			},
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<system version="1">
+  <cpu numaid="0">
+    <pci busid="0000:07:00.0" class="0x030200" vendor="0x10de" link_speed="16.0 GT/s PCIe" link_width="16">
+      <gpu dev="0" sm="80"></gpu>
+    </pci>
+  </cpu>
+</system>
+`,
+		},
+		{
+			description: "links to GPUs that are not included are omitted",
+			gpus: []GPU{
+				{
+					BusID:         "0000:07:00.0",
+					NUMANode:      1,
+					SM:            90,
+					NVLinks:       map[string]int{"0000:0f:00.0": 4, "0000:47:00.0": 4},
+					NVSwitchLinks: map[string]int{"0000:c7:00.0": 2},
+				},
+				{
+					BusID:    "0000:0f:00.0",
+					NUMANode: 0,
+					SM:       90,
+					NVLinks:  map[string]int{"0000:07:00.0": 4},
+				},
+			},
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<system version="1">
+  <cpu numaid="0">
+    <pci busid="0000:0f:00.0" class="0x030200" vendor="0x10de">
+      <gpu dev="1" sm="90">
+        <nvlink target="0000:07:00.0" count="4" tclass="0x030200"></nvlink>
+      </gpu>
+    </pci>
+  </cpu>
+  <cpu numaid="1">
+    <pci busid="0000:07:00.0" class="0x030200" vendor="0x10de">
+      <gpu dev="0" sm="90">
+        <nvlink target="0000:0f:00.0" count="4" tclass="0x030200"></nvlink>
+        <nvlink target="0000:c7:00.0" count="2" tclass="0x068000"></nvlink>
+      </gpu>
+    </pci>
+  </cpu>
+</system>
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			topology, err := NewTopology(tc.gpus)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(topology))
+		})
+	}
+}
+
+func TestGetGPUs(t *testing.T) {
+	server := dgxa100.New()
+	pciInfo := func(i int) nvml.PciInfo {
+		info := nvml.PciInfo{}
+		copy(info.BusId[:], fmt.Sprintf("00000000:%02X:00.0", i))
+		return info
+	}
+	// GPU 0 is connected to GPU 1 through two NVLinks and to an NVSwitch
+	// through a third.
+	remotes := map[int][]int{0: {1, 1, 0xc0}, 1: {0, 0}}
+	for i, sd := range server.Devices {
+		d := sd.(*dgxa100.Device)
+		d.GetPciInfoFunc = func() (nvml.PciInfo, nvml.Return) {
+			return pciInfo(i), nvml.SUCCESS
+		}
+		d.GetCurrPcieLinkGenerationFunc = func() (int, nvml.Return) {
+			return 4, nvml.SUCCESS
+		}
+		d.GetCurrPcieLinkWidthFunc = func() (int, nvml.Return) {
+			return 16, nvml.SUCCESS
+		}
+		d.GetNvLinkStateFunc = func(link int) (nvml.EnableState, nvml.Return) {
+			if link >= len(remotes[i]) {
+				return nvml.FEATURE_DISABLED, nvml.SUCCESS
+			}
+			return nvml.FEATURE_ENABLED, nvml.SUCCESS
+		}
+		d.GetNvLinkRemotePciInfoFunc = func(link int) (nvml.PciInfo, nvml.Return) {
+			return pciInfo(remotes[i][link]), nvml.SUCCESS
+		}
+		d.GetNvLinkRemoteDeviceTypeFunc = func(link int) (nvml.IntNvLinkDeviceType, nvml.Return) {
+			if remotes[i][link] == 0xc0 {
+				return nvml.NVLINK_DEVICE_TYPE_SWITCH, nvml.SUCCESS
+			}
+			return nvml.NVLINK_DEVICE_TYPE_GPU, nvml.SUCCESS
+		}
+	}
+
+	var uuids []string
+	for _, sd := range server.Devices[:2] {
+		uuids = append(uuids, sd.(*dgxa100.Device).UUID)
+	}
+	gpus, err := getGPUs(server, uuids)
+	require.NoError(t, err)
+	require.Len(t, gpus, 2)
+	require.Equal(t, "0000:00:00.0", gpus[0].BusID)
+	require.Equal(t, 80, gpus[0].SM)
+	require.Equal(t, 4, gpus[0].PCIeGeneration)
+	require.Equal(t, 16, gpus[0].PCIeWidth)
+	require.Equal(t, map[string]int{"0000:01:00.0": 2}, gpus[0].NVLinks)
+	require.Equal(t, map[string]int{"0000:c0:00.0": 1}, gpus[0].NVSwitchLinks)
+	require.Equal(t, map[string]int{"0000:00:00.0": 2}, gpus[1].NVLinks)
+	require.Empty(t, gpus[1].NVSwitchLinks)
+}
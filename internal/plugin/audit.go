@@ -0,0 +1,115 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package plugin
+
+import (
+	"context"
+	"strings"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/NVIDIA/k8s-device-plugin/internal/audit"
+)
+
+// auditAllocate records the outcome of an Allocate request for a single container.
+// The validation error is recorded separately from any error constructing the response.
+func (plugin *nvidiaDevicePlugin) auditAllocate(ctx context.Context, requestIDs []string, validationErr error, response *pluginapi.ContainerAllocateResponse, err error) {
+	if plugin.auditLogger == nil {
+		return
+	}
+
+	entry := &audit.Entry{
+		Operation:    audit.OperationAllocate,
+		Resource:     string(plugin.rm.Resource()),
+		RequestedIDs: requestIDs,
+		Validation:   "ok",
+	}
+	if validationErr != nil {
+		entry.Validation = validationErr.Error()
+		plugin.auditLogger.Record(ctx, entry)
+		return
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	devices := plugin.rm.Devices().Subset(requestIDs)
+	entry.DeviceIDs = plugin.uniqueDeviceIDsFromAnnotatedDeviceIDs(requestIDs)
+	entry.UUIDs = devices.GetUUIDs()
+	entry.Indices = unique(devices.GetIndices())
+
+	if response != nil {
+		entry.Envs = response.Envs
+		entry.CDIDevices = plugin.cdiDevicesFromResponse(response)
+	}
+
+	plugin.auditLogger.Record(ctx, entry)
+}
+
+// auditPreferredAllocation records the outcome of a GetPreferredAllocation request for a single container.
+func (plugin *nvidiaDevicePlugin) auditPreferredAllocation(ctx context.Context, req *pluginapi.ContainerPreferredAllocationRequest, preferred []string, err error) {
+	if plugin.auditLogger == nil {
+		return
+	}
+
+	entry := &audit.Entry{
+		Operation:      audit.OperationGetPreferredAllocation,
+		Resource:       string(plugin.rm.Resource()),
+		AvailableIDs:   req.AvailableDeviceIDs,
+		MustIncludeIDs: req.MustIncludeDeviceIDs,
+		AllocationSize: int(req.AllocationSize),
+		PreferredIDs:   preferred,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	plugin.auditLogger.Record(ctx, entry)
+}
+
+// cdiDevicesFromResponse extracts the fully-qualified CDI device names that
+// were requested through either the CDI CRI field or CDI annotations.
+func (plugin *nvidiaDevicePlugin) cdiDevicesFromResponse(response *pluginapi.ContainerAllocateResponse) []string {
+	var devices []string
+	for _, d := range response.CdiDevices {
+		devices = append(devices, d.Name)
+	}
+	if len(devices) > 0 {
+		return devices
+	}
+	for k, v := range response.Annotations {
+		if !strings.HasPrefix(k, plugin.cdiAnnotationPrefix) {
+			continue
+		}
+		devices = append(devices, strings.Split(v, ",")...)
+	}
+	return devices
+}
+
+// unique returns the unique elements of s, preserving their order.
+func unique(s []string) []string {
+	var res []string
+	seen := make(map[string]bool)
+	for _, e := range s {
+		if seen[e] {
+			continue
+		}
+		seen[e] = true
+		res = append(res, e)
+	}
+	return res
+}
@@ -26,8 +26,10 @@ import (
 	"k8s.io/klog/v2"
 
 	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/internal/audit"
 	"github.com/NVIDIA/k8s-device-plugin/internal/cdi"
 	"github.com/NVIDIA/k8s-device-plugin/internal/imex"
+	"github.com/NVIDIA/k8s-device-plugin/internal/nccl"
 	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
 )
 
@@ -44,6 +46,10 @@ type options struct {
 	deviceListStrategies spec.DeviceListStrategies
 
 	imexChannels imex.Channels
+
+	auditLogger *audit.Logger
+
+	ncclTopology *nccl.Manager
 }
 
 // New a new set of plugins with the supplied options.
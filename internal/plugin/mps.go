@@ -17,8 +17,10 @@
 package plugin
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
@@ -28,11 +30,23 @@ import (
 	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
 )
 
+const (
+	// mpsReadyTimeout is the time that the plugin waits for the MPS daemons
+	// of a resource to become ready.
+	mpsReadyTimeout = 5 * time.Minute
+	// mpsReadyMinInterval and mpsReadyMaxInterval bound the interval between
+	// checks of the ready files of the MPS daemons.
+	mpsReadyMinInterval = 100 * time.Millisecond
+	mpsReadyMaxInterval = 10 * time.Second
+)
+
 type mpsOptions struct {
 	enabled      bool
 	resourceName spec.ResourceName
-	daemon       *mps.Daemon
+	daemons      []*mps.Daemon
 	hostRoot     mps.Root
+	// readyTimeout is the time to wait for the daemons to become ready.
+	readyTimeout time.Duration
 }
 
 // getMPSOptions returns the MPS options specified for the resource manager.
@@ -42,18 +56,19 @@ func (o *options) getMPSOptions(resourceManager rm.ResourceManager) (mpsOptions,
 		return mpsOptions{}, nil
 	}
 
-	// TODO: It might make sense to pull this logic into a resource manager.
-	for _, device := range resourceManager.Devices() {
-		if device.IsMigDevice() {
-			return mpsOptions{}, errors.New("sharing using MPS is not supported for MIG devices")
-		}
+	// MPS daemons are only started for resources that are shared. Other
+	// resources (e.g. those with devices that are not selected for sharing)
+	// are handled as if MPS were not enabled.
+	if !rm.AnnotatedIDs(resourceManager.Devices().GetIDs()).AnyHasAnnotations() {
+		return mpsOptions{}, nil
 	}
 
 	m := mpsOptions{
 		enabled:      true,
 		resourceName: resourceManager.Resource(),
-		daemon:       mps.NewDaemon(resourceManager, mps.ContainerRoot),
+		daemons:      mps.NewDaemonsForResource(resourceManager, mps.ContainerRootFor(o.config)),
 		hostRoot:     mps.Root(*o.config.Flags.MpsRoot),
+		readyTimeout: mpsReadyTimeout,
 	}
 	return m, nil
 }
@@ -62,30 +77,115 @@ func (m *mpsOptions) waitForDaemon() error {
 	if m == nil || !m.enabled {
 		return nil
 	}
-	// TODO: Check the .ready file here.
-	// TODO: Have some retry strategy here.
-	if err := m.daemon.AssertHealthy(); err != nil {
-		return fmt.Errorf("error checking MPS daemon health: %w", err)
+	ctx, cancel := context.WithTimeout(context.Background(), m.readyTimeout)
+	defer cancel()
+
+	for _, daemon := range m.daemons {
+		status, err := waitForReadyFile(ctx, daemon)
+		if err != nil {
+			return fmt.Errorf("error waiting for %v: %w", daemon.ReadyFile(), err)
+		}
+		klog.InfoS("MPS daemon is ready", "resource", m.resourceName, "pid", status.PID, "activeThreadPercentage", status.ActiveThreadPercentage, "pinnedMemoryLimits", status.PinnedMemoryLimits)
+		if err := daemon.AssertHealthy(); err != nil {
+			return fmt.Errorf("error checking MPS daemon health: %w", err)
+		}
 	}
 	klog.InfoS("MPS daemon is healthy", "resource", m.resourceName)
 	return nil
 }
 
-func (m *mpsOptions) updateReponse(response *pluginapi.ContainerAllocateResponse) {
+// waitForReadyFile waits for the ready file of the daemon to be written with
+// an exponential backoff until the context is done.
+func waitForReadyFile(ctx context.Context, daemon *mps.Daemon) (*mps.ReadyStatus, error) {
+	interval := mpsReadyMinInterval
+	for {
+		status, err := daemon.ReadStatus()
+		if err == nil {
+			return status, nil
+		}
+		klog.V(4).InfoS("MPS daemon is not ready", "readyFile", daemon.ReadyFile(), "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-time.After(interval):
+		}
+		interval = min(2*interval, mpsReadyMaxInterval)
+	}
+}
+
+// daemonFor returns the daemon that controls all of the specified devices.
+// Since each MIG device is controlled by a daemon of its own, a request for
+// replicas of different MIG devices cannot be served.
+func (m *mpsOptions) daemonFor(devices rm.Devices) (*mps.Daemon, error) {
+	for _, daemon := range m.daemons {
+		if daemon.Devices().Contains(devices.GetIDs()...) {
+			return daemon, nil
+		}
+	}
+	return nil, fmt.Errorf("requested devices %v are not controlled by a single MPS daemon", devices.GetIDs())
+}
+
+func (m *mpsOptions) updateReponse(response *pluginapi.ContainerAllocateResponse, devices rm.Devices) error {
 	if m == nil || !m.enabled {
-		return
+		return nil
+	}
+	daemon, err := m.daemonFor(devices)
+	if err != nil {
+		return err
 	}
 	// TODO: We should check that the deviceIDs are shared using MPS.
-	response.Envs["CUDA_MPS_PIPE_DIRECTORY"] = m.daemon.PipeDir()
+	response.Envs["CUDA_MPS_PIPE_DIRECTORY"] = daemon.PipeDir()
+
+	// A daemon that runs as a specific user only serves clients running as
+	// the same user. The user is passed to the container so that workloads
+	// can check that they run as the expected user.
+	user, err := daemon.User()
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		response.Envs["NVIDIA_MPS_UID"] = fmt.Sprintf("%d", user.UID)
+		response.Envs["NVIDIA_MPS_GID"] = fmt.Sprintf("%d", user.GID)
+	}
+
+	// Replicas of replica classes are limited to the memory and active thread
+	// percentage of their class, and replicas with explicit MPS limits to
+	// these limits. Since a client has a single active thread percentage, the
+	// smallest percentage of the requested replicas is used. Requests for
+	// memory units are limited to the total memory of the requested units.
+	var limits []string
+	var threadPercentage int
+	for i, uuid := range devices.GetUUIDs() {
+		var memory uint64
+		for _, device := range devices {
+			if device.GetUUID() != uuid {
+				continue
+			}
+			memory += device.ReplicaMemory
+			if p := device.ActiveThreadPercentage; p != 0 && (threadPercentage == 0 || p < threadPercentage) {
+				threadPercentage = p
+			}
+		}
+		if memory != 0 {
+			limits = append(limits, fmt.Sprintf("%d=%dM", i, memory/1024/1024))
+		}
+	}
+	if len(limits) > 0 {
+		response.Envs["CUDA_MPS_PINNED_DEVICE_MEM_LIMIT"] = strings.Join(limits, ",")
+	}
+	if threadPercentage > 0 {
+		response.Envs["CUDA_MPS_ACTIVE_THREAD_PERCENTAGE"] = fmt.Sprintf("%d", threadPercentage)
+	}
 
 	response.Mounts = append(response.Mounts,
 		&pluginapi.Mount{
-			ContainerPath: m.daemon.PipeDir(),
-			HostPath:      m.hostRoot.PipeDir(m.resourceName),
+			ContainerPath: daemon.PipeDir(),
+			HostPath:      daemon.HostPipeDir(m.hostRoot),
 		},
 		&pluginapi.Mount{
-			ContainerPath: m.daemon.ShmDir(),
-			HostPath:      m.hostRoot.ShmDir(m.resourceName),
+			ContainerPath: daemon.ShmDir(),
+			HostPath:      m.hostRoot.ShmDir(daemon.Resource()),
 		},
 	)
+	return nil
 }
@@ -0,0 +1,72 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package plugin
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	v1 "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/cmd/mps-control-daemon/mps"
+	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
+)
+
+func TestWaitForReadyFile(t *testing.T) {
+	devices := rm.Devices{
+		"GPU-0::0": &rm.Device{
+			Device:   pluginapi.Device{ID: "GPU-0::0"},
+			Replicas: 1,
+		},
+	}
+	root := mps.Root(t.TempDir())
+	daemons := mps.NewDaemonsForResource(&rm.ResourceManagerMock{
+		DevicesFunc: func() rm.Devices {
+			return devices
+		},
+		ResourceFunc: func() v1.ResourceName {
+			return "nvidia.com/gpu"
+		},
+	}, root)
+	require.Len(t, daemons, 1)
+	daemon := daemons[0]
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		_, err := waitForReadyFile(ctx, daemon)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("ready file is written", func(t *testing.T) {
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			_ = os.MkdirAll(root.Path("nvidia.com/gpu"), 0755)
+			_ = os.WriteFile(daemon.ReadyFile(), []byte(`{"pid":42,"activeThreadPercentage":100}`), 0644)
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		status, err := waitForReadyFile(ctx, daemon)
+		require.NoError(t, err)
+		require.Equal(t, &mps.ReadyStatus{PID: 42, ActiveThreadPercentage: 100}, status)
+	})
+}
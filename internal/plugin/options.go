@@ -21,8 +21,10 @@ import (
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
 	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/internal/audit"
 	"github.com/NVIDIA/k8s-device-plugin/internal/cdi"
 	"github.com/NVIDIA/k8s-device-plugin/internal/imex"
+	"github.com/NVIDIA/k8s-device-plugin/internal/nccl"
 )
 
 // Option is a function that configures a options
@@ -76,3 +78,17 @@ func WithImexChannels(imexChannels imex.Channels) Option {
 		m.imexChannels = imexChannels
 	}
 }
+
+// WithAuditLogger sets the logger used to record allocation decisions.
+func WithAuditLogger(auditLogger *audit.Logger) Option {
+	return func(m *options) {
+		m.auditLogger = auditLogger
+	}
+}
+
+// WithNCCLTopology sets the manager used to write NCCL topology files for allocations.
+func WithNCCLTopology(ncclTopology *nccl.Manager) Option {
+	return func(m *options) {
+		m.ncclTopology = ncclTopology
+	}
+}
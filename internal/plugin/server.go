@@ -30,8 +30,10 @@ import (
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
 
 	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/internal/audit"
 	"github.com/NVIDIA/k8s-device-plugin/internal/cdi"
 	"github.com/NVIDIA/k8s-device-plugin/internal/imex"
+	"github.com/NVIDIA/k8s-device-plugin/internal/nccl"
 	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
 
 	"github.com/google/uuid"
@@ -67,6 +69,10 @@ type nvidiaDevicePlugin struct {
 	imexChannels imex.Channels
 
 	mps mpsOptions
+
+	auditLogger *audit.Logger
+
+	ncclTopology *nccl.Manager
 }
 
 // devicePluginForResource creates a device plugin for the specified resource.
@@ -89,6 +95,10 @@ func (o *options) devicePluginForResource(ctx context.Context, resourceManager r
 
 		mps: mpsOptions,
 
+		auditLogger: o.auditLogger,
+
+		ncclTopology: o.ncclTopology,
+
 		socket: getPluginSocketPath(resourceManager.Resource()),
 		// These will be reinitialized every
 		// time the plugin server is restarted.
@@ -289,6 +299,7 @@ func (plugin *nvidiaDevicePlugin) GetPreferredAllocation(ctx context.Context, r
 	response := &pluginapi.PreferredAllocationResponse{}
 	for _, req := range r.ContainerRequests {
 		devices, err := plugin.rm.GetPreferredAllocation(req.AvailableDeviceIDs, req.MustIncludeDeviceIDs, int(req.AllocationSize))
+		plugin.auditPreferredAllocation(ctx, req, devices, err)
 		if err != nil {
 			return nil, fmt.Errorf("error getting list of preferred allocation devices: %v", err)
 		}
@@ -307,9 +318,11 @@ func (plugin *nvidiaDevicePlugin) Allocate(ctx context.Context, reqs *pluginapi.
 	responses := pluginapi.AllocateResponse{}
 	for _, req := range reqs.ContainerRequests {
 		if err := plugin.rm.ValidateRequest(req.DevicesIds); err != nil {
+			plugin.auditAllocate(ctx, req.DevicesIds, err, nil, nil)
 			return nil, fmt.Errorf("invalid allocation request for %q: %w", plugin.rm.Resource(), err)
 		}
 		response, err := plugin.getAllocateResponse(req.DevicesIds)
+		plugin.auditAllocate(ctx, req.DevicesIds, nil, response, err)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get allocate response: %v", err)
 		}
@@ -333,15 +346,65 @@ func (plugin *nvidiaDevicePlugin) getAllocateResponse(requestIds []string) (*plu
 		}
 	}
 	if plugin.mps.enabled {
-		plugin.updateResponseForMPS(response)
+		if err := plugin.updateResponseForMPS(response, requestIds); err != nil {
+			return nil, fmt.Errorf("failed to get allocate response for MPS: %w", err)
+		}
+	}
+	plugin.updateResponseForMemoryUnits(response, requestIds)
+	if err := plugin.updateResponseForMemoryHints(response, requestIds); err != nil {
+		return nil, fmt.Errorf("failed to get memory hints: %w", err)
 	}
 
 	// The following modifications are only made if at least one non-CDI device
 	// list strategy is selected.
-	if plugin.deviceListStrategies.AllCDIEnabled() {
-		return response, nil
+	if !plugin.deviceListStrategies.AllCDIEnabled() {
+		plugin.updateResponseForNonCDI(response, deviceIDs, requestIds)
 	}
 
+	if err := plugin.updateResponseForNCCLTopology(response, requestIds); err != nil {
+		return nil, fmt.Errorf("failed to write NCCL topology: %w", err)
+	}
+
+	if err := plugin.updateResponseForContainerEdits(response, requestIds); err != nil {
+		return nil, fmt.Errorf("failed to apply container edits: %w", err)
+	}
+	return response, nil
+}
+
+// updateResponseForNCCLTopology writes an NCCL topology file that only includes
+// the requested GPUs and mounts it into the container. No topology file is
+// written for MIG devices since these do not support NVLink.
+func (plugin *nvidiaDevicePlugin) updateResponseForNCCLTopology(response *pluginapi.ContainerAllocateResponse, requestIds []string) error {
+	if plugin.ncclTopology == nil {
+		return nil
+	}
+	devices := plugin.rm.Devices().Subset(requestIds)
+	for _, device := range devices {
+		if device.MigProfile != "" {
+			return nil
+		}
+	}
+
+	allocation := nccl.Allocation{
+		Resource:  string(plugin.rm.Resource()),
+		DeviceIDs: requestIds,
+	}
+	path, err := plugin.ncclTopology.Write(allocation, devices.GetUUIDs())
+	if err != nil {
+		return err
+	}
+	response.Mounts = append(response.Mounts, &pluginapi.Mount{
+		HostPath:      path,
+		ContainerPath: nccl.ContainerPath,
+		ReadOnly:      true,
+	})
+	response.Envs["NCCL_TOPO_FILE"] = nccl.ContainerPath
+	return nil
+}
+
+// updateResponseForNonCDI updates the response for the device list strategies
+// that are not based on CDI.
+func (plugin *nvidiaDevicePlugin) updateResponseForNonCDI(response *pluginapi.ContainerAllocateResponse, deviceIDs []string, requestIds []string) {
 	if plugin.deviceListStrategies.Includes(spec.DeviceListStrategyEnvVar) {
 		plugin.updateResponseForDeviceListEnvVar(response, deviceIDs...)
 		plugin.updateResponseForImexChannelsEnvVar(response)
@@ -361,14 +424,141 @@ func (plugin *nvidiaDevicePlugin) getAllocateResponse(requestIds []string) (*plu
 	if plugin.config.Flags.MOFEDEnabled != nil && *plugin.config.Flags.MOFEDEnabled {
 		response.Envs["NVIDIA_MOFED"] = "enabled"
 	}
-	return response, nil
 }
 
 // updateResponseForMPS ensures that the ContainerAllocate response contains the information required to use MPS.
 // This includes per-resource pipe and log directories as well as a global daemon-specific shm
 // and assumes that an MPS control daemon has already been started.
-func (plugin nvidiaDevicePlugin) updateResponseForMPS(response *pluginapi.ContainerAllocateResponse) {
-	plugin.mps.updateReponse(response)
+func (plugin nvidiaDevicePlugin) updateResponseForMPS(response *pluginapi.ContainerAllocateResponse, requestIds []string) error {
+	return plugin.mps.updateReponse(response, plugin.rm.Devices().Subset(requestIds))
+}
+
+// updateResponseForMemoryUnits sets a hint for the amount of GPU memory that
+// the container may use if memory units were requested. Frameworks that do not
+// run under MPS are expected to limit their memory usage accordingly.
+func (plugin *nvidiaDevicePlugin) updateResponseForMemoryUnits(response *pluginapi.ContainerAllocateResponse, requestIds []string) {
+	var memory uint64
+	for _, device := range plugin.rm.Devices().Subset(requestIds) {
+		if device.MemoryUnit {
+			memory += device.ReplicaMemory
+		}
+	}
+	if memory == 0 {
+		return
+	}
+	response.Envs["NVIDIA_GPU_MEMORY_LIMIT_MIB"] = fmt.Sprintf("%d", memory/1024/1024)
+}
+
+// updateResponseForMemoryHints sets the configured memory hints if time-sliced
+// replicas were requested. The hints are derived from the share of the memory
+// of a GPU that the requested replicas represent. If replicas of more than one
+// GPU were requested, the smallest share is used.
+func (plugin *nvidiaDevicePlugin) updateResponseForMemoryHints(response *pluginapi.ContainerAllocateResponse, requestIds []string) error {
+	hints := plugin.config.Sharing.TimeSlicing.MemoryHints
+	if hints == nil || plugin.config.Sharing.SharingStrategy() != spec.SharingStrategyTimeSlicing {
+		return nil
+	}
+
+	requested := make(map[string]int)
+	devices := plugin.rm.Devices().Subset(requestIds)
+	for _, device := range devices {
+		if device.MemoryUnit || device.Replicas <= 1 || device.TotalMemory == 0 {
+			continue
+		}
+		requested[device.GetUUID()]++
+	}
+
+	var data *spec.MemoryHintData
+	for _, device := range devices {
+		count, ok := requested[device.GetUUID()]
+		if !ok {
+			continue
+		}
+		d := spec.NewMemoryHintData(device.TotalMemory, device.ReplicaMemory, count, device.Replicas)
+		if data == nil || d.MemoryMiB < data.MemoryMiB {
+			data = &d
+		}
+	}
+	if data == nil {
+		return nil
+	}
+
+	envs, err := hints.Evaluate(*data)
+	if err != nil {
+		return err
+	}
+	for name, value := range envs {
+		response.Envs[name] = value
+	}
+	return nil
+}
+
+// updateResponseForContainerEdits applies the container edits configured for
+// the resource of the plugin. These take precedence over the envvars and
+// annotations that are otherwise set.
+func (plugin *nvidiaDevicePlugin) updateResponseForContainerEdits(response *pluginapi.ContainerAllocateResponse, requestIds []string) error {
+	var edits []spec.ContainerEdits
+	for _, e := range plugin.config.ContainerEdits {
+		if e.Name == plugin.rm.Resource() {
+			edits = append(edits, e)
+		}
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+
+	devices := plugin.rm.Devices().Subset(requestIds)
+	data := spec.ContainerEditsData{
+		Resource:  string(plugin.rm.Resource()),
+		DeviceIDs: requestIds,
+		UUIDs:     devices.GetUUIDs(),
+	}
+	seen := make(map[string]bool)
+	for _, index := range devices.GetIndices() {
+		if !seen[index] {
+			seen[index] = true
+			data.Indices = append(data.Indices, index)
+		}
+	}
+	seen = make(map[string]bool)
+	for _, id := range data.UUIDs {
+		for _, device := range devices {
+			if device.GetUUID() != id {
+				continue
+			}
+			if device.Replicas > 1 {
+				data.Replicas = device.Replicas
+			}
+			if device.MigProfile != "" && !seen[id] {
+				seen[id] = true
+				data.MigProfiles = append(data.MigProfiles, device.MigProfile)
+			}
+		}
+	}
+
+	for _, e := range edits {
+		evaluated, err := e.Evaluate(data)
+		if err != nil {
+			return err
+		}
+		for name, value := range evaluated.Envs {
+			response.Envs[name] = value
+		}
+		if len(evaluated.Annotations) > 0 && response.Annotations == nil {
+			response.Annotations = make(map[string]string)
+		}
+		for name, value := range evaluated.Annotations {
+			response.Annotations[name] = value
+		}
+		for _, mount := range evaluated.Mounts {
+			response.Mounts = append(response.Mounts, &pluginapi.Mount{
+				HostPath:      mount.HostPath,
+				ContainerPath: mount.ContainerPath,
+				ReadOnly:      mount.ReadOnly,
+			})
+		}
+	}
+	return nil
 }
 
 // updateResponseForCDI updates the specified response for the given device IDs.
@@ -460,6 +650,9 @@ func (plugin *nvidiaDevicePlugin) uniqueDeviceIDsFromAnnotatedDeviceIDs(ids []st
 	if *plugin.config.Flags.Plugin.DeviceIDStrategy == spec.DeviceIDStrategyIndex {
 		deviceIDs = plugin.rm.Devices().Subset(ids).GetIndices()
 	}
+	if *plugin.config.Flags.Plugin.DeviceIDStrategy == spec.DeviceIDStrategyPCIBusID {
+		deviceIDs = plugin.rm.Devices().Subset(ids).GetPciBusIDs()
+	}
 	var uniqueIDs []string
 	seen := make(map[string]bool)
 	for _, id := range deviceIDs {
@@ -18,14 +18,25 @@ package plugin
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
 	"github.com/stretchr/testify/require"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	v1 "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+	"github.com/NVIDIA/k8s-device-plugin/cmd/mps-control-daemon/mps"
+	"github.com/NVIDIA/k8s-device-plugin/internal/audit"
 	"github.com/NVIDIA/k8s-device-plugin/internal/cdi"
 	"github.com/NVIDIA/k8s-device-plugin/internal/imex"
+	"github.com/NVIDIA/k8s-device-plugin/internal/nccl"
 	"github.com/NVIDIA/k8s-device-plugin/internal/rm"
 )
 
@@ -108,6 +119,79 @@ func TestAllocate(t *testing.T) {
 	}
 }
 
+func TestAllocateAudit(t *testing.T) {
+	auditLog := filepath.Join(t.TempDir(), "audit.log")
+	auditLogger, err := audit.New(&v1.AuditLogFlags{Path: &auditLog})
+	require.NoError(t, err)
+
+	plugin := nvidiaDevicePlugin{
+		rm: &rm.ResourceManagerMock{
+			ResourceFunc: func() v1.ResourceName {
+				return "nvidia.com/gpu"
+			},
+			DevicesFunc: func() rm.Devices {
+				return rm.Devices{
+					"foo::0": {Device: pluginapi.Device{ID: "foo::0"}, Index: "0"},
+					"foo::1": {Device: pluginapi.Device{ID: "foo::1"}, Index: "0"},
+				}
+			},
+			ValidateRequestFunc: func(annotatedIDs rm.AnnotatedIDs) error {
+				if len(annotatedIDs) > 1 {
+					return errors.New("too many devices")
+				}
+				return nil
+			},
+		},
+		config: &v1.Config{
+			Flags: v1.Flags{
+				CommandLineFlags: v1.CommandLineFlags{
+					Plugin: &v1.PluginCommandLineFlags{
+						DeviceIDStrategy: ptr(v1.DeviceIDStrategyUUID),
+					},
+				},
+			},
+		},
+		deviceListStrategies: v1.DeviceListStrategies{"envvar": true},
+		auditLogger:          auditLogger,
+	}
+
+	_, err = plugin.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+			{DevicesIds: []string{"foo::1"}},
+		},
+	})
+	require.NoError(t, err)
+	_, err = plugin.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+			{DevicesIds: []string{"foo::0", "foo::1"}},
+		},
+	})
+	require.Error(t, err)
+
+	contents, err := os.ReadFile(auditLog)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	require.Len(t, lines, 2)
+
+	var entries []audit.Entry
+	for _, line := range lines {
+		var entry audit.Entry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	require.Equal(t, audit.OperationAllocate, entries[0].Operation)
+	require.Equal(t, "nvidia.com/gpu", entries[0].Resource)
+	require.Equal(t, "ok", entries[0].Validation)
+	require.Equal(t, []string{"foo"}, entries[0].DeviceIDs)
+	require.Equal(t, []string{"foo"}, entries[0].UUIDs)
+	require.Equal(t, []string{"0"}, entries[0].Indices)
+	require.Equal(t, map[string]string{"NVIDIA_VISIBLE_DEVICES": "foo"}, entries[0].Envs)
+
+	require.Equal(t, "too many devices", entries[1].Validation)
+	require.Empty(t, entries[1].Envs)
+}
+
 func TestCDIAllocateResponse(t *testing.T) {
 	testCases := []struct {
 		description          string
@@ -254,3 +338,606 @@ func TestCDIAllocateResponse(t *testing.T) {
 func ptr[T any](x T) *T {
 	return &x
 }
+
+func TestAllocateMemoryUnits(t *testing.T) {
+	devices := make(rm.Devices)
+	for i := 0; i < 4; i++ {
+		id := string(rm.NewAnnotatedID("GPU-0", i))
+		devices[id] = &rm.Device{
+			Device:        pluginapi.Device{ID: id},
+			Index:         "0",
+			ReplicaMemory: 1024 * 1024 * 1024,
+			MemoryUnit:    true,
+		}
+	}
+
+	plugin := nvidiaDevicePlugin{
+		rm: &rm.ResourceManagerMock{
+			DevicesFunc: func() rm.Devices {
+				return devices
+			},
+			ValidateRequestFunc: func(annotatedIDs rm.AnnotatedIDs) error {
+				return nil
+			},
+		},
+		config: &v1.Config{
+			Flags: v1.Flags{
+				CommandLineFlags: v1.CommandLineFlags{
+					Plugin: &v1.PluginCommandLineFlags{
+						DeviceIDStrategy: ptr(v1.DeviceIDStrategyUUID),
+					},
+				},
+			},
+		},
+		deviceListStrategies: v1.DeviceListStrategies{"envvar": true},
+	}
+
+	response, err := plugin.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+			{DevicesIds: []string{"GPU-0::1", "GPU-0::3"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t,
+		map[string]string{
+			"NVIDIA_VISIBLE_DEVICES":      "GPU-0",
+			"NVIDIA_GPU_MEMORY_LIMIT_MIB": "2048",
+		},
+		response.ContainerResponses[0].Envs,
+	)
+}
+
+func TestAllocateMPSMigDevices(t *testing.T) {
+	devices := make(rm.Devices)
+	for _, uuid := range []string{"MIG-a", "MIG-b"} {
+		for i := 0; i < 2; i++ {
+			id := string(rm.NewAnnotatedID(uuid, i))
+			devices[id] = &rm.Device{
+				Device:      pluginapi.Device{ID: id},
+				Index:       "0:0",
+				TotalMemory: 10240 * 1024 * 1024,
+				Replicas:    2,
+			}
+		}
+	}
+	resourceManager := &rm.ResourceManagerMock{
+		DevicesFunc: func() rm.Devices {
+			return devices
+		},
+		ResourceFunc: func() v1.ResourceName {
+			return "nvidia.com/mig-1g.10gb"
+		},
+		ValidateRequestFunc: func(annotatedIDs rm.AnnotatedIDs) error {
+			return nil
+		},
+	}
+
+	plugin := nvidiaDevicePlugin{
+		rm: resourceManager,
+		config: &v1.Config{
+			Flags: v1.Flags{
+				CommandLineFlags: v1.CommandLineFlags{
+					Plugin: &v1.PluginCommandLineFlags{
+						DeviceIDStrategy: ptr(v1.DeviceIDStrategyUUID),
+					},
+				},
+			},
+		},
+		deviceListStrategies: v1.DeviceListStrategies{"envvar": true},
+		mps: mpsOptions{
+			enabled:      true,
+			resourceName: "nvidia.com/mig-1g.10gb",
+			daemons:      mps.NewDaemonsForResource(resourceManager, mps.ContainerRoot),
+			hostRoot:     "/run/nvidia/mps",
+		},
+	}
+
+	testCases := []struct {
+		description      string
+		request          []string
+		expectedError    bool
+		expectedPipeDir  string
+		expectedHostPath string
+	}{
+		{
+			description:      "replicas of a single MIG device",
+			request:          []string{"MIG-b::0", "MIG-b::1"},
+			expectedPipeDir:  "/mps/nvidia.com/mig-1g.10gb/MIG-b/pipe",
+			expectedHostPath: "/run/nvidia/mps/nvidia.com/mig-1g.10gb/MIG-b/pipe",
+		},
+		{
+			description:   "replicas of multiple MIG devices",
+			request:       []string{"MIG-a::0", "MIG-b::0"},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			response, err := plugin.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+				ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+					{DevicesIds: tc.request},
+				},
+			})
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			container := response.ContainerResponses[0]
+			require.Equal(t, tc.expectedPipeDir, container.Envs["CUDA_MPS_PIPE_DIRECTORY"])
+			require.Contains(t, container.Mounts, &pluginapi.Mount{
+				ContainerPath: tc.expectedPipeDir,
+				HostPath:      tc.expectedHostPath,
+			})
+		})
+	}
+}
+
+func TestAllocateMPSMultiGPU(t *testing.T) {
+	devices := make(rm.Devices)
+	for index, uuid := range []string{"GPU-a", "GPU-b"} {
+		for i := 0; i < 2; i++ {
+			id := string(rm.NewAnnotatedID(uuid, i))
+			devices[id] = &rm.Device{
+				Device:        pluginapi.Device{ID: id},
+				Index:         fmt.Sprintf("%d", index),
+				TotalMemory:   10240 * 1024 * 1024,
+				ReplicaMemory: 5120 * 1024 * 1024,
+				Replicas:      2,
+			}
+		}
+	}
+	resourceManager := &rm.ResourceManagerMock{
+		DevicesFunc: func() rm.Devices {
+			return devices
+		},
+		ResourceFunc: func() v1.ResourceName {
+			return "nvidia.com/gpu"
+		},
+		ValidateRequestFunc: func(annotatedIDs rm.AnnotatedIDs) error {
+			return nil
+		},
+	}
+
+	plugin := nvidiaDevicePlugin{
+		rm: resourceManager,
+		config: &v1.Config{
+			Flags: v1.Flags{
+				CommandLineFlags: v1.CommandLineFlags{
+					Plugin: &v1.PluginCommandLineFlags{
+						DeviceIDStrategy: ptr(v1.DeviceIDStrategyUUID),
+					},
+				},
+			},
+		},
+		deviceListStrategies: v1.DeviceListStrategies{"envvar": true},
+		mps: mpsOptions{
+			enabled:      true,
+			resourceName: "nvidia.com/gpu",
+			daemons:      mps.NewDaemonsForResource(resourceManager, mps.ContainerRoot),
+			hostRoot:     "/run/nvidia/mps",
+		},
+	}
+
+	response, err := plugin.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+			{DevicesIds: []string{"GPU-b::1", "GPU-a::0"}},
+		},
+	})
+	require.NoError(t, err)
+
+	// Both GPUs are controlled by the single daemon of the resource.
+	container := response.ContainerResponses[0]
+	require.Equal(t, "/mps/nvidia.com/gpu/pipe", container.Envs["CUDA_MPS_PIPE_DIRECTORY"])
+	require.Equal(t, "0=5120M,1=5120M", container.Envs["CUDA_MPS_PINNED_DEVICE_MEM_LIMIT"])
+	require.Contains(t, container.Mounts, &pluginapi.Mount{
+		ContainerPath: "/mps/nvidia.com/gpu/pipe",
+		HostPath:      "/run/nvidia/mps/nvidia.com/gpu/pipe",
+	})
+}
+
+func TestAllocateMPSUser(t *testing.T) {
+	user := &v1.MPSUser{UID: 1000, GID: 2000}
+	devices := make(rm.Devices)
+	for i := 0; i < 2; i++ {
+		id := string(rm.NewAnnotatedID("GPU-a", i))
+		devices[id] = &rm.Device{
+			Device:   pluginapi.Device{ID: id},
+			Index:    "0",
+			Replicas: 2,
+			MPSUser:  user,
+		}
+	}
+	resourceManager := &rm.ResourceManagerMock{
+		DevicesFunc: func() rm.Devices {
+			return devices
+		},
+		ResourceFunc: func() v1.ResourceName {
+			return "nvidia.com/gpu"
+		},
+		ValidateRequestFunc: func(annotatedIDs rm.AnnotatedIDs) error {
+			return nil
+		},
+	}
+
+	plugin := nvidiaDevicePlugin{
+		rm: resourceManager,
+		config: &v1.Config{
+			Flags: v1.Flags{
+				CommandLineFlags: v1.CommandLineFlags{
+					Plugin: &v1.PluginCommandLineFlags{
+						DeviceIDStrategy: ptr(v1.DeviceIDStrategyUUID),
+					},
+				},
+			},
+		},
+		deviceListStrategies: v1.DeviceListStrategies{"envvar": true},
+		mps: mpsOptions{
+			enabled:      true,
+			resourceName: "nvidia.com/gpu",
+			daemons:      mps.NewDaemonsForResource(resourceManager, mps.ContainerRoot),
+			hostRoot:     "/run/nvidia/mps",
+		},
+	}
+
+	response, err := plugin.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+			{DevicesIds: []string{"GPU-a::1"}},
+		},
+	})
+	require.NoError(t, err)
+
+	container := response.ContainerResponses[0]
+	require.Equal(t, "1000", container.Envs["NVIDIA_MPS_UID"])
+	require.Equal(t, "2000", container.Envs["NVIDIA_MPS_GID"])
+}
+
+func TestAllocateMemoryHints(t *testing.T) {
+	devices := make(rm.Devices)
+	for i := 0; i < 4; i++ {
+		id := string(rm.NewAnnotatedID("GPU-0", i))
+		devices[id] = &rm.Device{
+			Device:      pluginapi.Device{ID: id},
+			Index:       "0",
+			TotalMemory: 16 * 1024 * 1024 * 1024,
+			Replicas:    4,
+		}
+	}
+	sharing := v1.Sharing{
+		TimeSlicing: v1.ReplicatedResources{
+			Resources: []v1.ReplicatedResource{
+				{Name: "nvidia.com/gpu", Replicas: 4},
+			},
+			MemoryHints: &v1.MemoryHints{
+				Presets: []v1.MemoryHintPreset{v1.MemoryHintPresetGeneric, v1.MemoryHintPresetPyTorch},
+				Envs: map[string]string{
+					"MY_GPU_MEMORY": "{{ .MemoryMiB }}MiB",
+				},
+			},
+		},
+	}
+	expectedHints := map[string]string{
+		"NVIDIA_GPU_MEMORY_LIMIT_MIB": "8192",
+		"NVIDIA_GPU_MEMORY_FRACTION":  "0.50",
+		"PYTORCH_CUDA_ALLOC_CONF":     "garbage_collection_threshold:0.50",
+		"MY_GPU_MEMORY":               "8192MiB",
+	}
+
+	testCases := []struct {
+		description          string
+		deviceListStrategies []string
+		sharing              v1.Sharing
+		expectedEnvs         map[string]string
+	}{
+		{
+			description:          "envvar",
+			deviceListStrategies: []string{"envvar"},
+			sharing:              sharing,
+			expectedEnvs:         map[string]string{"NVIDIA_VISIBLE_DEVICES": "GPU-0"},
+		},
+		{
+			description:          "volume-mounts",
+			deviceListStrategies: []string{"volume-mounts"},
+			sharing:              sharing,
+			expectedEnvs:         map[string]string{"NVIDIA_VISIBLE_DEVICES": deviceListAsVolumeMountsContainerPathRoot},
+		},
+		{
+			description:          "cdi-annotations",
+			deviceListStrategies: []string{"cdi-annotations"},
+			sharing:              sharing,
+			expectedEnvs:         map[string]string{},
+		},
+		{
+			description:          "no memory hints",
+			deviceListStrategies: []string{"envvar"},
+			sharing: v1.Sharing{
+				TimeSlicing: v1.ReplicatedResources{
+					Resources: []v1.ReplicatedResource{
+						{Name: "nvidia.com/gpu", Replicas: 4},
+					},
+				},
+			},
+			expectedEnvs: map[string]string{"NVIDIA_VISIBLE_DEVICES": "GPU-0"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			deviceListStrategies, err := v1.NewDeviceListStrategies(tc.deviceListStrategies)
+			require.NoError(t, err)
+			plugin := nvidiaDevicePlugin{
+				rm: &rm.ResourceManagerMock{
+					DevicesFunc: func() rm.Devices {
+						return devices
+					},
+					ValidateRequestFunc: func(annotatedIDs rm.AnnotatedIDs) error {
+						return nil
+					},
+				},
+				config: &v1.Config{
+					Flags: v1.Flags{
+						CommandLineFlags: v1.CommandLineFlags{
+							Plugin: &v1.PluginCommandLineFlags{
+								DeviceIDStrategy: ptr(v1.DeviceIDStrategyUUID),
+							},
+						},
+					},
+					Sharing: tc.sharing,
+				},
+				cdiHandler: &cdi.InterfaceMock{
+					QualifiedNameFunc: func(c string, s string) string {
+						return "nvidia.com/" + c + "=" + s
+					},
+				},
+				deviceListStrategies: deviceListStrategies,
+				cdiAnnotationPrefix:  "cdi.k8s.io/",
+			}
+
+			response, err := plugin.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+				ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+					{DevicesIds: []string{"GPU-0::1", "GPU-0::2"}},
+				},
+			})
+			require.NoError(t, err)
+
+			expectedEnvs := tc.expectedEnvs
+			if tc.sharing.TimeSlicing.MemoryHints != nil {
+				for name, value := range expectedHints {
+					expectedEnvs[name] = value
+				}
+			}
+			require.Equal(t, expectedEnvs, response.ContainerResponses[0].Envs)
+		})
+	}
+}
+
+func TestAllocateContainerEdits(t *testing.T) {
+	devices := make(rm.Devices)
+	for i := 0; i < 2; i++ {
+		id := string(rm.NewAnnotatedID("GPU-0", i))
+		devices[id] = &rm.Device{
+			Device:   pluginapi.Device{ID: id},
+			Index:    "0",
+			Replicas: 2,
+		}
+	}
+	containerEdits := []v1.ContainerEdits{
+		{
+			Name:               "nvidia.com/gpu.shared",
+			Envs:               map[string]string{"CUDA_DEVICE_ORDER": "PCI_BUS_ID"},
+			Annotations:        map[string]string{"example.com/gpus": `{{ join "," .UUIDs }}`},
+			Mounts:             []v1.ContainerMount{{HostPath: "/opt/models", ContainerPath: "/models", ReadOnly: true}},
+			DriverCapabilities: []string{"compute", "utility"},
+		},
+		{
+			Name: "nvidia.com/gpu",
+			Envs: map[string]string{"UNUSED": "true"},
+		},
+	}
+
+	plugin := nvidiaDevicePlugin{
+		rm: &rm.ResourceManagerMock{
+			ResourceFunc: func() v1.ResourceName {
+				return "nvidia.com/gpu.shared"
+			},
+			DevicesFunc: func() rm.Devices {
+				return devices
+			},
+			ValidateRequestFunc: func(annotatedIDs rm.AnnotatedIDs) error {
+				return nil
+			},
+		},
+		config: &v1.Config{
+			Flags: v1.Flags{
+				CommandLineFlags: v1.CommandLineFlags{
+					Plugin: &v1.PluginCommandLineFlags{
+						DeviceIDStrategy: ptr(v1.DeviceIDStrategyUUID),
+					},
+				},
+			},
+			ContainerEdits: containerEdits,
+		},
+		deviceListStrategies: v1.DeviceListStrategies{"envvar": true},
+	}
+
+	response, err := plugin.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+			{DevicesIds: []string{"GPU-0::1"}},
+		},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t,
+		&pluginapi.ContainerAllocateResponse{
+			Envs: map[string]string{
+				"NVIDIA_VISIBLE_DEVICES":     "GPU-0",
+				"CUDA_DEVICE_ORDER":          "PCI_BUS_ID",
+				"NVIDIA_DRIVER_CAPABILITIES": "compute,utility",
+			},
+			Annotations: map[string]string{
+				"example.com/gpus": "GPU-0",
+			},
+			Mounts: []*pluginapi.Mount{
+				{HostPath: "/opt/models", ContainerPath: "/models", ReadOnly: true},
+			},
+		},
+		response.ContainerResponses[0],
+	)
+}
+
+func TestAllocateNCCLTopology(t *testing.T) {
+	server := dgxa100.New()
+	devices := make(rm.Devices)
+	for i, sd := range server.Devices[:2] {
+		d := sd.(*dgxa100.Device)
+		d.GetCurrPcieLinkGenerationFunc = func() (int, nvml.Return) {
+			return 0, nvml.ERROR_NOT_SUPPORTED
+		}
+		d.GetCurrPcieLinkWidthFunc = func() (int, nvml.Return) {
+			return 0, nvml.ERROR_NOT_SUPPORTED
+		}
+		d.GetNvLinkStateFunc = func(link int) (nvml.EnableState, nvml.Return) {
+			return nvml.FEATURE_DISABLED, nvml.SUCCESS
+		}
+		devices[d.UUID] = &rm.Device{
+			Device: pluginapi.Device{ID: d.UUID},
+			Index:  fmt.Sprintf("%d", i),
+		}
+	}
+
+	dir := t.TempDir()
+	ncclTopology, err := nccl.New(server, &v1.NCCLTopologyFlags{Dir: &dir})
+	require.NoError(t, err)
+
+	plugin := nvidiaDevicePlugin{
+		rm: &rm.ResourceManagerMock{
+			ResourceFunc: func() v1.ResourceName {
+				return "nvidia.com/gpu"
+			},
+			DevicesFunc: func() rm.Devices {
+				return devices
+			},
+			ValidateRequestFunc: func(annotatedIDs rm.AnnotatedIDs) error {
+				return nil
+			},
+		},
+		config: &v1.Config{
+			Flags: v1.Flags{
+				CommandLineFlags: v1.CommandLineFlags{
+					Plugin: &v1.PluginCommandLineFlags{
+						DeviceIDStrategy: ptr(v1.DeviceIDStrategyUUID),
+					},
+				},
+			},
+		},
+		deviceListStrategies: v1.DeviceListStrategies{"envvar": true},
+		ncclTopology:         ncclTopology,
+	}
+
+	var requestIds []string
+	for id := range devices {
+		requestIds = append(requestIds, id)
+	}
+	response, err := plugin.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+			{DevicesIds: requestIds},
+		},
+	})
+	require.NoError(t, err)
+
+	containerResponse := response.ContainerResponses[0]
+	require.Equal(t, nccl.ContainerPath, containerResponse.Envs["NCCL_TOPO_FILE"])
+	require.Len(t, containerResponse.Mounts, 1)
+	require.Equal(t, nccl.ContainerPath, containerResponse.Mounts[0].ContainerPath)
+	require.True(t, containerResponse.Mounts[0].ReadOnly)
+	require.Equal(t, dir, filepath.Dir(containerResponse.Mounts[0].HostPath))
+
+	topology, err := os.ReadFile(containerResponse.Mounts[0].HostPath)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(topology), "<gpu "))
+}
+
+func TestAllocatePCIBusID(t *testing.T) {
+	devices := rm.Devices{
+		"GPU-0": {Device: pluginapi.Device{ID: "GPU-0"}, Index: "0", PciBusID: "0000:07:00.0"},
+		"GPU-1": {Device: pluginapi.Device{ID: "GPU-1"}, Index: "1", PciBusID: "0000:0f:00.0"},
+	}
+
+	testCases := []struct {
+		description          string
+		deviceListStrategies v1.DeviceListStrategies
+		expectedResponse     *pluginapi.ContainerAllocateResponse
+	}{
+		{
+			description:          "envvar",
+			deviceListStrategies: v1.DeviceListStrategies{"envvar": true},
+			expectedResponse: &pluginapi.ContainerAllocateResponse{
+				Envs: map[string]string{
+					"NVIDIA_VISIBLE_DEVICES": "0000:07:00.0,0000:0f:00.0",
+				},
+			},
+		},
+		{
+			description:          "volume-mounts",
+			deviceListStrategies: v1.DeviceListStrategies{"volume-mounts": true},
+			expectedResponse: &pluginapi.ContainerAllocateResponse{
+				Envs: map[string]string{
+					"NVIDIA_VISIBLE_DEVICES": "/var/run/nvidia-container-devices",
+				},
+				Mounts: []*pluginapi.Mount{
+					{HostPath: "/dev/null", ContainerPath: "/var/run/nvidia-container-devices/0000:07:00.0"},
+					{HostPath: "/dev/null", ContainerPath: "/var/run/nvidia-container-devices/0000:0f:00.0"},
+				},
+			},
+		},
+		{
+			description:          "cdi-cri",
+			deviceListStrategies: v1.DeviceListStrategies{"cdi-cri": true},
+			expectedResponse: &pluginapi.ContainerAllocateResponse{
+				Envs: map[string]string{},
+				CdiDevices: []*pluginapi.CDIDevice{
+					{Name: "nvidia.com/gpu=0000:07:00.0"},
+					{Name: "nvidia.com/gpu=0000:0f:00.0"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			plugin := nvidiaDevicePlugin{
+				rm: &rm.ResourceManagerMock{
+					DevicesFunc: func() rm.Devices {
+						return devices
+					},
+					ValidateRequestFunc: func(annotatedIDs rm.AnnotatedIDs) error {
+						return nil
+					},
+				},
+				config: &v1.Config{
+					Flags: v1.Flags{
+						CommandLineFlags: v1.CommandLineFlags{
+							Plugin: &v1.PluginCommandLineFlags{
+								DeviceIDStrategy: ptr(v1.DeviceIDStrategyPCIBusID),
+							},
+						},
+					},
+				},
+				cdiHandler: &cdi.InterfaceMock{
+					QualifiedNameFunc: func(c string, s string) string {
+						return "nvidia.com/" + c + "=" + s
+					},
+				},
+				deviceListStrategies: tc.deviceListStrategies,
+			}
+
+			response, err := plugin.Allocate(context.TODO(), &pluginapi.AllocateRequest{
+				ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+					{DevicesIds: []string{"GPU-1", "GPU-0"}},
+				},
+			})
+			require.NoError(t, err)
+			require.EqualValues(t, tc.expectedResponse, response.ContainerResponses[0])
+		})
+	}
+}
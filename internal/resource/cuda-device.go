@@ -101,6 +101,16 @@ func (d *cudaDevice) GetPCIClass() (uint32, error) {
 	return 0, nil
 }
 
+// GetPCIDeviceID is unsupported for CUDA devices
+func (d *cudaDevice) GetPCIDeviceID() (uint16, error) {
+	return 0, fmt.Errorf("GetPCIDeviceID is unsupported for CUDA devices")
+}
+
+// GetNumaNode is unsupported for CUDA devices
+func (d *cudaDevice) GetNumaNode() (bool, int, error) {
+	return false, 0, fmt.Errorf("GetNumaNode is unsupported for CUDA devices")
+}
+
 func (d *cudaDevice) IsFabricAttached() (bool, error) {
 	return false, nil
 }
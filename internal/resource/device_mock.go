@@ -35,12 +35,21 @@ var _ Device = &DeviceMock{}
 //			GetNameFunc: func() (string, error) {
 //				panic("mock out the GetName method")
 //			},
+//			GetNumaNodeFunc: func() (bool, int, error) {
+//				panic("mock out the GetNumaNode method")
+//			},
 //			GetPCIClassFunc: func() (uint32, error) {
 //				panic("mock out the GetPCIClass method")
 //			},
+//			GetPCIDeviceIDFunc: func() (uint16, error) {
+//				panic("mock out the GetPCIDeviceID method")
+//			},
 //			GetTotalMemoryMiBFunc: func() (uint64, error) {
 //				panic("mock out the GetTotalMemoryMiB method")
 //			},
+//			GetUUIDFunc: func() (string, error) {
+//				panic("mock out the GetUUID method")
+//			},
 //			IsFabricAttachedFunc: func() (bool, error) {
 //				panic("mock out the IsFabricAttached method")
 //			},
@@ -75,12 +84,21 @@ type DeviceMock struct {
 	// GetNameFunc mocks the GetName method.
 	GetNameFunc func() (string, error)
 
+	// GetNumaNodeFunc mocks the GetNumaNode method.
+	GetNumaNodeFunc func() (bool, int, error)
+
 	// GetPCIClassFunc mocks the GetPCIClass method.
 	GetPCIClassFunc func() (uint32, error)
 
+	// GetPCIDeviceIDFunc mocks the GetPCIDeviceID method.
+	GetPCIDeviceIDFunc func() (uint16, error)
+
 	// GetTotalMemoryMiBFunc mocks the GetTotalMemoryMiB method.
 	GetTotalMemoryMiBFunc func() (uint64, error)
 
+	// GetUUIDFunc mocks the GetUUID method.
+	GetUUIDFunc func() (string, error)
+
 	// IsFabricAttachedFunc mocks the IsFabricAttached method.
 	IsFabricAttachedFunc func() (bool, error)
 
@@ -110,12 +128,21 @@ type DeviceMock struct {
 		// GetName holds details about calls to the GetName method.
 		GetName []struct {
 		}
+		// GetNumaNode holds details about calls to the GetNumaNode method.
+		GetNumaNode []struct {
+		}
 		// GetPCIClass holds details about calls to the GetPCIClass method.
 		GetPCIClass []struct {
 		}
+		// GetPCIDeviceID holds details about calls to the GetPCIDeviceID method.
+		GetPCIDeviceID []struct {
+		}
 		// GetTotalMemoryMiB holds details about calls to the GetTotalMemoryMiB method.
 		GetTotalMemoryMiB []struct {
 		}
+		// GetUUID holds details about calls to the GetUUID method.
+		GetUUID []struct {
+		}
 		// IsFabricAttached holds details about calls to the IsFabricAttached method.
 		IsFabricAttached []struct {
 		}
@@ -132,8 +159,11 @@ type DeviceMock struct {
 	lockGetFabricIDs                       sync.RWMutex
 	lockGetMigDevices                      sync.RWMutex
 	lockGetName                            sync.RWMutex
+	lockGetNumaNode                        sync.RWMutex
 	lockGetPCIClass                        sync.RWMutex
+	lockGetPCIDeviceID                     sync.RWMutex
 	lockGetTotalMemoryMiB                  sync.RWMutex
+	lockGetUUID                            sync.RWMutex
 	lockIsFabricAttached                   sync.RWMutex
 	lockIsMigCapable                       sync.RWMutex
 	lockIsMigEnabled                       sync.RWMutex
@@ -301,6 +331,33 @@ func (mock *DeviceMock) GetNameCalls() []struct {
 	return calls
 }
 
+// GetNumaNode calls GetNumaNodeFunc.
+func (mock *DeviceMock) GetNumaNode() (bool, int, error) {
+	if mock.GetNumaNodeFunc == nil {
+		panic("DeviceMock.GetNumaNodeFunc: method is nil but Device.GetNumaNode was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockGetNumaNode.Lock()
+	mock.calls.GetNumaNode = append(mock.calls.GetNumaNode, callInfo)
+	mock.lockGetNumaNode.Unlock()
+	return mock.GetNumaNodeFunc()
+}
+
+// GetNumaNodeCalls gets all the calls that were made to GetNumaNode.
+// Check the length with:
+//
+//	len(mockedDevice.GetNumaNodeCalls())
+func (mock *DeviceMock) GetNumaNodeCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockGetNumaNode.RLock()
+	calls = mock.calls.GetNumaNode
+	mock.lockGetNumaNode.RUnlock()
+	return calls
+}
+
 // GetPCIClass calls GetPCIClassFunc.
 func (mock *DeviceMock) GetPCIClass() (uint32, error) {
 	if mock.GetPCIClassFunc == nil {
@@ -328,6 +385,33 @@ func (mock *DeviceMock) GetPCIClassCalls() []struct {
 	return calls
 }
 
+// GetPCIDeviceID calls GetPCIDeviceIDFunc.
+func (mock *DeviceMock) GetPCIDeviceID() (uint16, error) {
+	if mock.GetPCIDeviceIDFunc == nil {
+		panic("DeviceMock.GetPCIDeviceIDFunc: method is nil but Device.GetPCIDeviceID was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockGetPCIDeviceID.Lock()
+	mock.calls.GetPCIDeviceID = append(mock.calls.GetPCIDeviceID, callInfo)
+	mock.lockGetPCIDeviceID.Unlock()
+	return mock.GetPCIDeviceIDFunc()
+}
+
+// GetPCIDeviceIDCalls gets all the calls that were made to GetPCIDeviceID.
+// Check the length with:
+//
+//	len(mockedDevice.GetPCIDeviceIDCalls())
+func (mock *DeviceMock) GetPCIDeviceIDCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockGetPCIDeviceID.RLock()
+	calls = mock.calls.GetPCIDeviceID
+	mock.lockGetPCIDeviceID.RUnlock()
+	return calls
+}
+
 // GetTotalMemoryMiB calls GetTotalMemoryMiBFunc.
 func (mock *DeviceMock) GetTotalMemoryMiB() (uint64, error) {
 	if mock.GetTotalMemoryMiBFunc == nil {
@@ -355,6 +439,33 @@ func (mock *DeviceMock) GetTotalMemoryMiBCalls() []struct {
 	return calls
 }
 
+// GetUUID calls GetUUIDFunc.
+func (mock *DeviceMock) GetUUID() (string, error) {
+	if mock.GetUUIDFunc == nil {
+		panic("DeviceMock.GetUUIDFunc: method is nil but Device.GetUUID was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockGetUUID.Lock()
+	mock.calls.GetUUID = append(mock.calls.GetUUID, callInfo)
+	mock.lockGetUUID.Unlock()
+	return mock.GetUUIDFunc()
+}
+
+// GetUUIDCalls gets all the calls that were made to GetUUID.
+// Check the length with:
+//
+//	len(mockedDevice.GetUUIDCalls())
+func (mock *DeviceMock) GetUUIDCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockGetUUID.RLock()
+	calls = mock.calls.GetUUID
+	mock.lockGetUUID.RUnlock()
+	return calls
+}
+
 // IsFabricAttached calls IsFabricAttachedFunc.
 func (mock *DeviceMock) IsFabricAttached() (bool, error) {
 	if mock.IsFabricAttachedFunc == nil {
@@ -17,7 +17,10 @@
 package resource
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"strconv"
 
 	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
 	"github.com/NVIDIA/go-nvlib/pkg/nvpci"
@@ -81,6 +84,15 @@ func (d nvmlDevice) GetName() (string, error) {
 	return name, nil
 }
 
+// GetUUID returns the UUID of the device.
+func (d nvmlDevice) GetUUID() (string, error) {
+	id, ret := d.Device.GetUUID()
+	if ret != nvml.SUCCESS {
+		return "", ret
+	}
+	return id, nil
+}
+
 // GetTotalMemoryMiB returns the total memory on a device in mebibytes (2^20 bytes)
 func (d nvmlDevice) GetTotalMemoryMiB() (uint64, error) {
 	info, ret := d.GetMemoryInfo()
@@ -102,6 +114,40 @@ func (d nvmlDevice) GetPCIClass() (uint32, error) {
 	return nvDevice.Class, nil
 }
 
+// GetPCIDeviceID returns the PCI device ID of the device.
+func (d nvmlDevice) GetPCIDeviceID() (uint16, error) {
+	info, ret := d.GetPciInfo()
+	if ret != nvml.SUCCESS {
+		return 0, ret
+	}
+	return uint16(info.PciDeviceId >> 16), nil
+}
+
+// GetNumaNode returns the NUMA node associated with the device. The returned
+// bool is false if the device is not associated with a NUMA node.
+func (d nvmlDevice) GetNumaNode() (bool, int, error) {
+	busID, err := d.GetPCIBusID()
+	if err != nil {
+		return false, 0, err
+	}
+
+	b, err := os.ReadFile(fmt.Sprintf("/sys/bus/pci/devices/%s/numa_node", busID))
+	if err != nil {
+		return false, 0, nil
+	}
+
+	node, err := strconv.Atoi(string(bytes.TrimSpace(b)))
+	if err != nil {
+		return false, 0, fmt.Errorf("error parsing value for NUMA node: %v", err)
+	}
+
+	if node < 0 {
+		return false, 0, nil
+	}
+
+	return true, node, nil
+}
+
 func (d nvmlDevice) GetFabricIDs() (string, string, error) {
 	info, ret := d.GetGpuFabricInfo()
 	if ret != nvml.SUCCESS {
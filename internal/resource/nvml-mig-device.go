@@ -104,6 +104,15 @@ func (d nvmlMigDevice) GetName() (string, error) {
 	return resourceName, nil
 }
 
+// GetUUID returns the UUID of the MIG device.
+func (d nvmlMigDevice) GetUUID() (string, error) {
+	id, ret := d.MigDevice.GetUUID()
+	if ret != nvml.SUCCESS {
+		return "", ret
+	}
+	return id, nil
+}
+
 // GetTotalMemoryMiB returns the total memory on a device in mebibytes (2^20 bytes)
 func (d nvmlMigDevice) GetTotalMemoryMiB() (uint64, error) {
 	attr, err := d.GetAttributes()
@@ -143,6 +152,16 @@ func (d nvmlMigDevice) GetPCIClass() (uint32, error) {
 	return nvpci.PCI3dControllerClass, nil
 }
 
+// GetPCIDeviceID is not supported for MIG devices
+func (d nvmlMigDevice) GetPCIDeviceID() (uint16, error) {
+	return 0, fmt.Errorf("GetPCIDeviceID is not supported for MIG devices")
+}
+
+// GetNumaNode is not supported for MIG devices
+func (d nvmlMigDevice) GetNumaNode() (bool, int, error) {
+	return false, 0, fmt.Errorf("GetNumaNode is not supported for MIG devices")
+}
+
 func (d nvmlMigDevice) IsFabricAttached() (bool, error) {
 	return false, fmt.Errorf("IsFabricAttached is not supported for MIG devices")
 }
@@ -51,6 +51,11 @@ func (d vfioDevice) GetName() (string, error) {
 	return d.nvidiaPCIDevice.DeviceName, nil
 }
 
+// GetUUID is not supported for GPU devices with vfio pci driver.
+func (d vfioDevice) GetUUID() (string, error) {
+	return "", fmt.Errorf("GetUUID is not supported for vfio devices")
+}
+
 // GetTotalMemoryMiB returns the total memory on a device in mebibytes (2^20 bytes)
 func (d vfioDevice) GetTotalMemoryMiB() (uint64, error) {
 	_, val := d.nvidiaPCIDevice.Resources.GetTotalAddressableMemory(true)
@@ -69,6 +74,20 @@ func (d vfioDevice) GetPCIClass() (uint32, error) {
 	return d.nvidiaPCIDevice.Class, nil
 }
 
+// GetPCIDeviceID returns the PCI device ID of the device.
+func (d vfioDevice) GetPCIDeviceID() (uint16, error) {
+	return d.nvidiaPCIDevice.Device, nil
+}
+
+// GetNumaNode returns the NUMA node associated with the device. The returned
+// bool is false if the device is not associated with a NUMA node.
+func (d vfioDevice) GetNumaNode() (bool, int, error) {
+	if d.nvidiaPCIDevice.NumaNode < 0 {
+		return false, 0, nil
+	}
+	return true, d.nvidiaPCIDevice.NumaNode, nil
+}
+
 func (d vfioDevice) IsFabricAttached() (bool, error) {
 	return false, nil
 }
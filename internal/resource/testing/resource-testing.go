@@ -41,6 +41,7 @@ func NewMigEnabledDevice(migs ...*resource.DeviceMock) resource.Device {
 func NewDeviceMock(migEnabled bool) *DeviceMock {
 	d := DeviceMock{resource.DeviceMock{
 		GetNameFunc: func() (string, error) { return "MOCKMODEL", nil },
+		GetUUIDFunc: func() (string, error) { return "GPU-MOCK", nil },
 		GetCudaComputeCapabilityFunc: func() (int, int, error) {
 			if migEnabled {
 				return 0, 0, nil
@@ -53,6 +54,8 @@ func NewDeviceMock(migEnabled bool) *DeviceMock {
 		IsMigCapableFunc:      func() (bool, error) { return migEnabled, nil },
 		GetMigDevicesFunc:     func() ([]resource.Device, error) { return nil, nil },
 		GetPCIClassFunc:       func() (uint32, error) { return 0, nil },
+		GetPCIDeviceIDFunc:    func() (uint16, error) { return 0x20B0, nil },
+		GetNumaNodeFunc:       func() (bool, int, error) { return true, 0, nil },
 	}}
 	return &d
 }
@@ -85,8 +88,10 @@ func NewMigDevice(gi int, ci int, gb uint64, attributes ...map[string]interface{
 	}
 
 	return &resource.DeviceMock{
-		GetNameFunc:       func() (string, error) { return fmt.Sprintf("%dg.%dgb", gi, gb), nil },
-		GetAttributesFunc: func() (map[string]interface{}, error) { return defaultAttributes, nil },
+		GetNameFunc:           func() (string, error) { return fmt.Sprintf("%dg.%dgb", gi, gb), nil },
+		GetUUIDFunc:           func() (string, error) { return "MIG-MOCK", nil },
+		GetTotalMemoryMiBFunc: func() (uint64, error) { return gb * 1024, nil },
+		GetAttributesFunc:     func() (map[string]interface{}, error) { return defaultAttributes, nil },
 	}
 }
 
@@ -37,9 +37,12 @@ type Device interface {
 	GetMigDevices() ([]Device, error)
 	GetAttributes() (map[string]interface{}, error)
 	GetName() (string, error)
+	GetUUID() (string, error)
 	GetTotalMemoryMiB() (uint64, error)
 	GetDeviceHandleFromMigDeviceHandle() (Device, error)
 	GetCudaComputeCapability() (int, int, error)
 	GetPCIClass() (uint32, error)
+	GetPCIDeviceID() (uint16, error)
+	GetNumaNode() (bool, int, error)
 	GetFabricIDs() (string, string, error)
 }
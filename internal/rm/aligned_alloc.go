@@ -0,0 +1,210 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// The weights used to score a pair of MIG devices in the same allocation.
+const (
+	migSameParentScore = 2
+	migSameNUMAScore   = 1
+)
+
+// migAlignment records the number of pairs of devices in an allocation that
+// share a parent GPU or a NUMA node.
+type migAlignment struct {
+	sameParent int
+	sameNUMA   int
+}
+
+func (a migAlignment) score() int {
+	return migSameParentScore*a.sameParent + migSameNUMAScore*a.sameNUMA
+}
+
+func (a migAlignment) String() string {
+	return fmt.Sprintf("score=%d (same parent GPU: %d pairs, same NUMA node: %d pairs)", a.score(), a.sameParent, a.sameNUMA)
+}
+
+// add updates the alignment for a device being added to a set of selected devices.
+func (a migAlignment) add(d *Device, selected []*Device) migAlignment {
+	for _, s := range selected {
+		switch {
+		case d.parentIndex() == s.parentIndex():
+			a.sameParent++
+		case d.numaNode() != -1 && d.numaNode() == s.numaNode():
+			a.sameNUMA++
+		}
+	}
+	return a
+}
+
+// parentIndex returns the index of the parent GPU of a MIG device or the
+// index of the device itself for a full GPU.
+func (d *Device) parentIndex() string {
+	return strings.SplitN(d.Index, ":", 2)[0]
+}
+
+// migAlignedAlloc selects MIG devices such that they are placed on the same
+// parent GPU where possible, and on the same NUMA node otherwise. Devices are
+// added to the allocation one at a time, always selecting the candidate that
+// is best aligned with the devices selected so far. If no devices are
+// required, an allocation is constructed starting from each parent GPU and the
+// best aligned of these is returned.
+func migAlignedAlloc(devices Devices, available, required []string, size int) ([]string, error) {
+	candidates, needed, err := getCandidates(devices, available, required, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var requiredDevices []*Device
+	for _, id := range required {
+		if d, exists := devices[id]; exists {
+			requiredDevices = append(requiredDevices, d)
+		}
+	}
+
+	seeds := [][]*Device{requiredDevices}
+	if len(requiredDevices) == 0 && needed > 0 {
+		seeds = nil
+		seen := make(map[string]bool)
+		for _, c := range candidates {
+			if seen[c.parentIndex()] {
+				continue
+			}
+			seen[c.parentIndex()] = true
+			seeds = append(seeds, []*Device{c})
+		}
+	}
+
+	var best []*Device
+	var bestAlignment migAlignment
+	for i, seed := range seeds {
+		selected, alignment := alignMigDevices(seed, candidates, len(requiredDevices)+needed)
+		klog.V(4).Infof("MIG aligned allocation candidate %v: %v", deviceIndices(selected), alignment)
+		if i == 0 || alignment.score() > bestAlignment.score() {
+			best, bestAlignment = selected, alignment
+		}
+	}
+	klog.V(4).Infof("Selected MIG aligned allocation %v: %v", deviceIndices(best), bestAlignment)
+
+	return appendIDs(required, best[len(requiredDevices):]), nil
+}
+
+// alignMigDevices greedily extends the selected devices from the candidates
+// until size devices have been selected. The alignment of the resulting set of
+// devices is also returned.
+func alignMigDevices(selected []*Device, candidates []*Device, size int) ([]*Device, migAlignment) {
+	var alignment migAlignment
+	for i, d := range selected {
+		alignment = alignment.add(d, selected[:i])
+	}
+	selected = append([]*Device{}, selected...)
+
+	used := make(map[string]bool)
+	for _, d := range selected {
+		used[d.ID] = true
+	}
+
+	for len(selected) < size {
+		var next *Device
+		var nextAlignment migAlignment
+		for _, c := range candidates {
+			if used[c.ID] {
+				continue
+			}
+			a := alignment.add(c, selected)
+			if next == nil || a.score() > nextAlignment.score() {
+				next, nextAlignment = c, a
+			}
+		}
+		if next == nil {
+			break
+		}
+		used[next.ID] = true
+		selected = append(selected, next)
+		alignment = nextAlignment
+	}
+	return selected, alignment
+}
+
+// replicaAlignedAlloc selects replicas from distinct GPUs, using the aligned
+// allocator to select a set of NVLink-connected GPUs. GPUs that the required
+// replicas are on are always included. If there are not enough distinct GPUs
+// available, the replicas are distributed across GPUs instead.
+func replicaAlignedAlloc(alignedAlloc alignedAllocFunc, devices Devices, available, required []string, size int) ([]string, error) {
+	candidates, needed, err := getCandidates(devices, available, required, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var requiredGPUs []string
+	isRequiredGPU := make(map[string]bool)
+	for _, id := range required {
+		uuid := AnnotatedID(id).GetID()
+		if isRequiredGPU[uuid] {
+			continue
+		}
+		isRequiredGPU[uuid] = true
+		requiredGPUs = append(requiredGPUs, uuid)
+	}
+
+	// Select the replica with the lowest replica number of each GPU that
+	// does not already have a replica included in the allocation.
+	replicas := make(map[string]*Device)
+	availableGPUs := append([]string{}, requiredGPUs...)
+	for _, c := range candidates {
+		uuid := c.GetUUID()
+		if isRequiredGPU[uuid] || replicas[uuid] != nil {
+			continue
+		}
+		replicas[uuid] = c
+		availableGPUs = append(availableGPUs, uuid)
+	}
+
+	if len(replicas) < needed {
+		klog.V(4).Infof("Distributing replicas: %d distinct GPUs are available, %d are required", len(replicas), needed)
+		return distributedAlloc(devices, available, required, size)
+	}
+
+	gpus, err := alignedAlloc(availableGPUs, requiredGPUs, len(requiredGPUs)+needed)
+	if err != nil {
+		return nil, err
+	}
+	klog.V(4).Infof("Selected GPUs %v for replicas from available GPUs %v with required GPUs %v", gpus, availableGPUs, requiredGPUs)
+
+	var selected []*Device
+	for _, uuid := range gpus {
+		if r := replicas[uuid]; r != nil {
+			selected = append(selected, r)
+		}
+	}
+	return appendIDs(required, selected), nil
+}
+
+// deviceIndices returns the indices of the specified devices.
+func deviceIndices(devices []*Device) []string {
+	var indices []string
+	for _, d := range devices {
+		indices = append(indices, d.Index)
+	}
+	return indices
+}
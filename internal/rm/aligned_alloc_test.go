@@ -0,0 +1,171 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+// newTestMigDevices creates migDevices MIG devices for each parent GPU. GPU i
+// is placed on NUMA node numaNodes[i]. MIG device j of GPU i has the ID
+// MIG-i-j.
+func newTestMigDevices(migDevices int, numaNodes ...int64) Devices {
+	devices := make(Devices)
+	for i, node := range numaNodes {
+		for j := 0; j < migDevices; j++ {
+			id := fmt.Sprintf("MIG-%d-%d", i, j)
+			devices[id] = &Device{
+				Device: pluginapi.Device{
+					ID:       id,
+					Topology: &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: node}}},
+				},
+				Index: fmt.Sprintf("%d:%d", i, j),
+			}
+		}
+	}
+	return devices
+}
+
+func TestMigAlignedAlloc(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     Devices
+		available   []string
+		required    []string
+		size        int
+		expected    []string
+	}{
+		{
+			description: "prefers devices on the same parent GPU",
+			devices:     newTestMigDevices(3, 0, 0),
+			available:   []string{"MIG-0-0", "MIG-0-1", "MIG-1-0", "MIG-1-1", "MIG-1-2"},
+			size:        3,
+			expected:    []string{"MIG-1-0", "MIG-1-1", "MIG-1-2"},
+		},
+		{
+			description: "prefers parent GPU of required device",
+			devices:     newTestMigDevices(3, 0, 0),
+			available:   []string{"MIG-0-0", "MIG-0-1", "MIG-1-0", "MIG-1-1", "MIG-1-2"},
+			required:    []string{"MIG-0-1"},
+			size:        2,
+			expected:    []string{"MIG-0-1", "MIG-0-0"},
+		},
+		{
+			description: "falls back to devices on the same NUMA node",
+			devices:     newTestMigDevices(2, 0, 1, 1),
+			available:   []string{"MIG-0-0", "MIG-0-1", "MIG-1-0", "MIG-2-1"},
+			required:    []string{"MIG-1-0"},
+			size:        3,
+			expected:    []string{"MIG-1-0", "MIG-2-1", "MIG-0-0"},
+		},
+		{
+			description: "selects best aligned parent GPU without required devices",
+			devices:     newTestMigDevices(2, 0, 1, 1),
+			available:   []string{"MIG-0-0", "MIG-1-0", "MIG-2-0", "MIG-2-1"},
+			size:        3,
+			expected:    []string{"MIG-1-0", "MIG-2-0", "MIG-2-1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			policy, err := NewAllocationPolicy(spec.AllocationPolicyBestEffort)
+			require.NoError(t, err)
+
+			allocated, err := policy.Allocate(tc.devices, tc.available, tc.required, tc.size)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, allocated)
+		})
+	}
+}
+
+func TestReplicaAlignedAlloc(t *testing.T) {
+	// alignedAlloc selects the required GPUs followed by the available GPUs
+	// in reverse order. This ensures that the selection is not simply the
+	// order of the available GPUs.
+	alignedAlloc := func(available, required []string, size int) ([]string, error) {
+		res := append([]string{}, required...)
+		for i := len(available) - 1; i >= 0 && len(res) < size; i-- {
+			if !slices.Contains(required, available[i]) {
+				res = append(res, available[i])
+			}
+		}
+		return res, nil
+	}
+
+	testCases := []struct {
+		description   string
+		alignReplicas bool
+		devices       Devices
+		available     []string
+		required      []string
+		size          int
+		expected      []string
+	}{
+		{
+			description:   "selects replicas on distinct aligned GPUs",
+			alignReplicas: true,
+			devices:       newTestDevices(2, 0, 0, 0),
+			available:     []string{"GPU-0::0", "GPU-0::1", "GPU-1::0", "GPU-1::1", "GPU-2::1"},
+			size:          2,
+			expected:      []string{"GPU-2::1", "GPU-1::0"},
+		},
+		{
+			description:   "includes GPUs of required replicas",
+			alignReplicas: true,
+			devices:       newTestDevices(2, 0, 0, 0),
+			available:     []string{"GPU-0::0", "GPU-0::1", "GPU-1::0", "GPU-1::1", "GPU-2::1"},
+			required:      []string{"GPU-0::1"},
+			size:          2,
+			expected:      []string{"GPU-0::1", "GPU-2::1"},
+		},
+		{
+			description:   "distributes replicas if not enough GPUs are available",
+			alignReplicas: true,
+			devices:       newTestDevices(2, 0, 0),
+			available:     []string{"GPU-0::0", "GPU-0::1", "GPU-1::0", "GPU-1::1"},
+			size:          3,
+			expected:      []string{"GPU-0::0", "GPU-1::0", "GPU-0::1"},
+		},
+		{
+			description:   "replicas are spread if alignment is disabled",
+			alignReplicas: false,
+			devices:       newTestDevices(2, 0, 0, 0),
+			available:     []string{"GPU-0::0", "GPU-0::1", "GPU-1::0", "GPU-1::1", "GPU-2::1"},
+			size:          2,
+			expected:      []string{"GPU-0::0", "GPU-1::0"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			policy, err := newAllocationPolicy(spec.AllocationPolicyBestEffort, alignedAlloc, tc.alignReplicas)
+			require.NoError(t, err)
+
+			allocated, err := policy.Allocate(tc.devices, tc.available, tc.required, tc.size)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, allocated)
+		})
+	}
+}
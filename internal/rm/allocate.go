@@ -17,64 +17,170 @@
 package rm
 
 import (
+	"container/heap"
 	"fmt"
 	"sort"
 )
 
+// replicaBucket holds the candidate replicas of a single GPU.
+type replicaBucket struct {
+	id string
+	// order holds the parsed index of the GPU and is used to break ties.
+	order deviceOrder
+	// total is the total number of replicas of the GPU.
+	total int
+	// candidates holds the candidate replicas of the GPU ordered by replica number.
+	candidates []parsedID
+}
+
+// parsedID stores an annotated ID along with its parsed replica number.
+type parsedID struct {
+	annotated string
+	replica   int
+}
+
+// used returns the number of replicas of the GPU that are not candidates
+// (i.e. that are either allocated already or have been selected).
+func (b *replicaBucket) used() int {
+	return b.total - len(b.candidates)
+}
+
+// replicaHeap is a min-heap of buckets ordered by the number of used replicas.
+type replicaHeap []*replicaBucket
+
+func (h replicaHeap) Len() int { return len(h) }
+func (h replicaHeap) Less(i, j int) bool {
+	if ui, uj := h[i].used(), h[j].used(); ui != uj {
+		return ui < uj
+	}
+	return h[i].order.less(h[j].order)
+}
+func (h replicaHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *replicaHeap) Push(x any)   { *h = append(*h, x.(*replicaBucket)) }
+func (h *replicaHeap) Pop() any {
+	old := *h
+	n := len(old)
+	b := old[n-1]
+	*h = old[:n-1]
+	return b
+}
+
 // distributedAlloc returns a list of devices such that any replicated
 // devices are distributed across all replicated GPUs equally. It takes into
 // account already allocated replicas to ensure a proper balance across them.
-func (r *resourceManager) distributedAlloc(available, required []string, size int) ([]string, error) {
-	// Get the set of candidate devices as the difference between available and required.
-	candidates := r.devices.Subset(available).Difference(r.devices.Subset(required)).GetIDs()
-	needed := size - len(required)
+//
+// The candidate replicas are grouped into a bucket per GPU, and the buckets
+// are kept in a min-heap ordered by the number of replicas of that GPU that
+// are in use. Each device is selected from the bucket at the top of the heap,
+// meaning that the GPU with the fewest replicas in use is always selected
+// next. Ties are broken by GPU index, and replicas within a GPU are selected
+// in order of their replica number.
+func distributedAlloc(allDevices Devices, available, required []string, size int) ([]string, error) {
+	seen := make(map[string]bool, len(required)+len(available))
+	for _, id := range required {
+		seen[id] = true
+	}
 
-	if len(candidates) < needed {
+	// Build a bucket for each GPU with candidate replicas. Candidates are
+	// the known (unique) available devices that are not required.
+	buckets := make(map[string]*replicaBucket)
+	numCandidates := 0
+	for _, c := range available {
+		d, exists := allDevices[c]
+		if !exists || seen[c] {
+			continue
+		}
+		seen[c] = true
+		id, replica := AnnotatedID(c).Split()
+		b, exists := buckets[id]
+		if !exists {
+			b = &replicaBucket{id: id, order: deviceOrder{index: parseIndex(d.Index), id: id}}
+			buckets[id] = b
+		}
+		b.candidates = append(b.candidates, parsedID{annotated: c, replica: replica})
+		numCandidates++
+	}
+
+	needed := size - len(required)
+	if numCandidates < needed {
 		return nil, fmt.Errorf("not enough available devices to satisfy allocation")
 	}
 
-	// For each candidate device, build a mapping of (stripped) device ID to
-	// total / available replicas for that device.
-	replicas := make(map[string]*struct{ total, available int })
-	for _, c := range candidates {
-		id := AnnotatedID(c).GetID()
-		if _, exists := replicas[id]; !exists {
-			replicas[id] = &struct{ total, available int }{}
+	for d := range allDevices {
+		if b, exists := buckets[AnnotatedID(d).GetID()]; exists {
+			b.total++
 		}
-		replicas[id].available++
 	}
-	for d := range r.devices {
-		id := AnnotatedID(d).GetID()
-		if _, exists := replicas[id]; !exists {
+
+	h := make(replicaHeap, 0, len(buckets))
+	for _, b := range buckets {
+		sort.Slice(b.candidates, func(i, j int) bool {
+			return b.candidates[i].replica < b.candidates[j].replica
+		})
+		h = append(h, b)
+	}
+	heap.Init(&h)
+
+	devices := make([]string, 0, size)
+	devices = append(devices, required...)
+	for i := 0; i < needed; i++ {
+		b := h[0]
+		devices = append(devices, b.candidates[0].annotated)
+		b.candidates = b.candidates[1:]
+		if len(b.candidates) == 0 {
+			heap.Pop(&h)
 			continue
 		}
-		replicas[id].total++
+		heap.Fix(&h, 0)
 	}
 
-	// Grab the set of 'needed' devices one-by-one from the candidates list.
-	// Before selecting each candidate, first sort the candidate list using the
-	// replicas map above. After sorting, the first element in the list will
-	// contain the device with the least difference between total and available
-	// replications (based on what's already been allocated). Add this device
-	// to the list of devices to allocate, remove it from the candidate list,
-	// down its available count in the replicas map, and repeat.
-	var devices []string
-	for i := 0; i < needed; i++ {
-		sort.Slice(candidates, func(i, j int) bool {
-			iid := AnnotatedID(candidates[i]).GetID()
-			jid := AnnotatedID(candidates[j]).GetID()
-			idiff := replicas[iid].total - replicas[iid].available
-			jdiff := replicas[jid].total - replicas[jid].available
-			return idiff < jdiff
-		})
-		id := AnnotatedID(candidates[0]).GetID()
-		replicas[id].available--
-		devices = append(devices, candidates[0])
-		candidates = candidates[1:]
+	return devices, nil
+}
+
+// distinctGPUAlloc returns a list of replicas such that each replica is on a
+// distinct GPU. The allocation returned by the specified allocator is used if
+// it satisfies this. Otherwise, replicas are selected one at a time using the
+// allocator, with the replicas of GPUs that are already part of the
+// allocation removed from the available devices.
+func distinctGPUAlloc(alloc func(available, required []string, size int) ([]string, error), available, required []string, size int) ([]string, error) {
+	devices, err := alloc(available, required, size)
+	if err == nil && onDistinctGPUs(devices) {
+		return devices, nil
 	}
 
-	// Add the set of required devices to this list and return it.
-	devices = append(required, devices...)
+	allocated := append([]string{}, required...)
+	for len(allocated) < size {
+		gpus := make(map[string]bool)
+		for _, id := range allocated {
+			gpus[AnnotatedID(id).GetID()] = true
+		}
+		candidates := append([]string{}, allocated...)
+		for _, id := range available {
+			if !gpus[AnnotatedID(id).GetID()] {
+				candidates = append(candidates, id)
+			}
+		}
+		if len(candidates) == len(allocated) {
+			return nil, fmt.Errorf("not enough distinct GPUs available to satisfy allocation")
+		}
+		allocated, err = alloc(candidates, allocated, len(allocated)+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return allocated, nil
+}
 
-	return devices, nil
+// onDistinctGPUs checks whether each of the specified devices is on a
+// distinct GPU.
+func onDistinctGPUs(ids []string) bool {
+	gpus := make(map[string]bool)
+	for _, id := range ids {
+		gpu := AnnotatedID(id).GetID()
+		if gpus[gpu] {
+			return false
+		}
+		gpus[gpu] = true
+	}
+	return true
 }
@@ -0,0 +1,231 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sortedDistributedAlloc is the original implementation of distributedAlloc
+// that re-sorts all candidates for every selected device. It is used as a
+// reference for the balancing semantics and as a baseline for benchmarks.
+func sortedDistributedAlloc(allDevices Devices, available, required []string, size int) ([]string, error) {
+	candidates := allDevices.Subset(available).Difference(allDevices.Subset(required)).GetIDs()
+	needed := size - len(required)
+
+	if len(candidates) < needed {
+		return nil, fmt.Errorf("not enough available devices to satisfy allocation")
+	}
+
+	replicas := make(map[string]*struct{ total, available int })
+	for _, c := range candidates {
+		id := AnnotatedID(c).GetID()
+		if _, exists := replicas[id]; !exists {
+			replicas[id] = &struct{ total, available int }{}
+		}
+		replicas[id].available++
+	}
+	for d := range allDevices {
+		id := AnnotatedID(d).GetID()
+		if _, exists := replicas[id]; !exists {
+			continue
+		}
+		replicas[id].total++
+	}
+
+	var devices []string
+	for i := 0; i < needed; i++ {
+		sort.Slice(candidates, func(i, j int) bool {
+			iid := AnnotatedID(candidates[i]).GetID()
+			jid := AnnotatedID(candidates[j]).GetID()
+			idiff := replicas[iid].total - replicas[iid].available
+			jdiff := replicas[jid].total - replicas[jid].available
+			return idiff < jdiff
+		})
+		id := AnnotatedID(candidates[0]).GetID()
+		replicas[id].available--
+		devices = append(devices, candidates[0])
+		candidates = candidates[1:]
+	}
+
+	devices = append(required, devices...)
+
+	return devices, nil
+}
+
+// replicasPerGPU counts the number of selected replicas of each GPU.
+func replicasPerGPU(ids []string) map[string]int {
+	counts := make(map[string]int)
+	for _, id := range ids {
+		counts[AnnotatedID(id).GetID()]++
+	}
+	return counts
+}
+
+// sortedUsage returns the number of replicas in use per GPU in ascending
+// order. Since ties between GPUs may be broken differently, two allocations
+// are equally balanced if these are equal.
+func sortedUsage(devices Devices, available, allocated []string) []int {
+	used := make(map[string]int)
+	for id := range devices {
+		used[AnnotatedID(id).GetID()]++
+	}
+	for id, n := range replicasPerGPU(available) {
+		used[id] -= n
+	}
+	for id, n := range replicasPerGPU(allocated) {
+		used[id] += n
+	}
+	var usage []int
+	for _, n := range used {
+		usage = append(usage, n)
+	}
+	sort.Ints(usage)
+	return usage
+}
+
+func TestDistributedAlloc(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     Devices
+		available   []string
+		required    []string
+		size        int
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			description: "selects least used GPU",
+			devices:     newTestDevices(2, 0, 0),
+			available:   []string{"GPU-0::0", "GPU-0::1", "GPU-1::1"},
+			size:        1,
+			expected:    []string{"GPU-0::0"},
+		},
+		{
+			description: "alternates between GPUs",
+			devices:     newTestDevices(3, 0, 0),
+			available:   []string{"GPU-1::2", "GPU-1::1", "GPU-1::0", "GPU-0::2", "GPU-0::1", "GPU-0::0"},
+			size:        4,
+			expected:    []string{"GPU-0::0", "GPU-1::0", "GPU-0::1", "GPU-1::1"},
+		},
+		{
+			description: "includes required devices",
+			devices:     newTestDevices(2, 0, 0),
+			available:   []string{"GPU-0::0", "GPU-0::1", "GPU-1::0", "GPU-1::1"},
+			required:    []string{"GPU-0::0"},
+			size:        2,
+			expected:    []string{"GPU-0::0", "GPU-1::0"},
+		},
+		{
+			description: "ignores unknown and duplicate devices",
+			devices:     newTestDevices(2, 0),
+			available:   []string{"GPU-0::0", "GPU-0::0", "GPU-5::0"},
+			size:        2,
+			expectedErr: true,
+		},
+		{
+			description: "fails if not enough devices",
+			devices:     newTestDevices(2, 0, 0),
+			available:   []string{"GPU-0::0"},
+			size:        2,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			allocated, err := distributedAlloc(tc.devices, tc.available, tc.required, tc.size)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, allocated)
+		})
+	}
+}
+
+func TestDistributedAllocMatchesSortedAlloc(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		numGPUs := 1 + r.Intn(8)
+		devices := newTestDevices(2+r.Intn(10), make([]int64, numGPUs)...)
+
+		var available []string
+		for id := range devices {
+			if r.Intn(3) != 0 {
+				available = append(available, id)
+			}
+		}
+		if len(available) == 0 {
+			continue
+		}
+		size := 1 + r.Intn(len(available))
+
+		expected, err := sortedDistributedAlloc(devices, available, nil, size)
+		require.NoError(t, err)
+		allocated, err := distributedAlloc(devices, available, nil, size)
+		require.NoError(t, err)
+
+		require.Len(t, allocated, size)
+		require.Subset(t, available, allocated)
+		require.Equal(t,
+			sortedUsage(devices, available, expected),
+			sortedUsage(devices, available, allocated),
+		)
+	}
+}
+
+func benchmarkDistributedAlloc(b *testing.B, alloc func(Devices, []string, []string, int) ([]string, error), numGPUs, replicas, size int) {
+	devices := newTestDevices(replicas, make([]int64, numGPUs)...)
+	available := devices.GetIDs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := alloc(devices, available, nil, size); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDistributedAlloc(b *testing.B) {
+	benchmarks := []struct {
+		numGPUs  int
+		replicas int
+		size     int
+	}{
+		{8, 4, 4},
+		{8, 48, 1},
+		{8, 48, 16},
+		{8, 48, 192},
+	}
+
+	for _, bm := range benchmarks {
+		name := fmt.Sprintf("gpus=%d/replicas=%d/size=%d", bm.numGPUs, bm.replicas, bm.size)
+		b.Run("heap/"+name, func(b *testing.B) {
+			benchmarkDistributedAlloc(b, distributedAlloc, bm.numGPUs, bm.replicas, bm.size)
+		})
+		b.Run("sort/"+name, func(b *testing.B) {
+			benchmarkDistributedAlloc(b, sortedDistributedAlloc, bm.numGPUs, bm.replicas, bm.size)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	"fmt"
+	"sort"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+// AllocationPolicy selects a preferred set of devices from the devices that
+// are available. Policies only rely on the information stored in Devices so
+// that they can be used by any resource manager.
+type AllocationPolicy interface {
+	// Allocate returns 'size' device IDs from 'available' that include all
+	// of the 'required' IDs. The 'devices' are the full set of devices
+	// managed for the resource.
+	Allocate(devices Devices, available, required []string, size int) ([]string, error)
+}
+
+// alignedAllocFunc calculates an allocation aligned to the topology of full GPUs.
+type alignedAllocFunc func(available, required []string, size int) ([]string, error)
+
+type bestEffortPolicy struct {
+	alignedAlloc  alignedAllocFunc
+	alignReplicas bool
+}
+type packPolicy struct{}
+type spreadPolicy struct{}
+type numaFirstPolicy struct{}
+type simplePolicy struct{}
+
+// NewAllocationPolicy returns the allocation policy with the specified name.
+// Since no topology information is available, the best-effort policy
+// returned here always spreads devices across GPUs.
+func NewAllocationPolicy(name spec.AllocationPolicy) (AllocationPolicy, error) {
+	return newAllocationPolicy(name, nil, false)
+}
+
+// newAllocationPolicy returns the allocation policy with the specified name.
+// If an aligned allocator is specified, it is used by the best-effort policy
+// for requests consisting of full GPUs, and, if alignReplicas is set, to
+// select distinct GPUs for requests consisting of multiple replicas.
+func newAllocationPolicy(name spec.AllocationPolicy, alignedAlloc alignedAllocFunc, alignReplicas bool) (AllocationPolicy, error) {
+	switch name {
+	case "", spec.AllocationPolicyBestEffort:
+		return &bestEffortPolicy{alignedAlloc: alignedAlloc, alignReplicas: alignReplicas}, nil
+	case spec.AllocationPolicyPack:
+		return &packPolicy{}, nil
+	case spec.AllocationPolicySpread:
+		return &spreadPolicy{}, nil
+	case spec.AllocationPolicyNUMAFirst:
+		return &numaFirstPolicy{}, nil
+	case spec.AllocationPolicySimple:
+		return &simplePolicy{}, nil
+	}
+	return nil, fmt.Errorf("unknown allocation policy: %q", name)
+}
+
+// Allocate performs an NVLink-aware allocation if all devices are full GPUs
+// and an aligned allocator is available. MIG devices are selected from the
+// same parent GPU or NUMA node where possible. Replicas are selected from
+// distinct NVLink-connected GPUs if replica alignment is enabled, and are
+// spread across GPUs otherwise.
+func (p *bestEffortPolicy) Allocate(devices Devices, available, required []string, size int) ([]string, error) {
+	hasAnnotations := AnnotatedIDs(available).AnyHasAnnotations()
+	alignedAllocationSupported := p.alignedAlloc != nil && devices.AlignedAllocationSupported()
+	switch {
+	case !hasAnnotations && alignedAllocationSupported:
+		return p.alignedAlloc(available, required, size)
+	case !hasAnnotations && devices.Subset(available).anyMigDevices():
+		return migAlignedAlloc(devices, available, required, size)
+	case hasAnnotations && alignedAllocationSupported && p.alignReplicas && size > 1:
+		return replicaAlignedAlloc(p.alignedAlloc, devices, available, required, size)
+	}
+	return distributedAlloc(devices, available, required, size)
+}
+
+// Allocate distributes devices evenly across all replicated GPUs.
+func (p *spreadPolicy) Allocate(devices Devices, available, required []string, size int) ([]string, error) {
+	return distributedAlloc(devices, available, required, size)
+}
+
+// Allocate selects the available devices with the lowest indices.
+func (p *simplePolicy) Allocate(devices Devices, available, required []string, size int) ([]string, error) {
+	candidates, needed, err := getCandidates(devices, available, required, size)
+	if err != nil {
+		return nil, err
+	}
+	return appendIDs(required, candidates[:needed]), nil
+}
+
+// Allocate fills the replicas of a single GPU before moving on to the next.
+// GPUs that already have replicas allocated (or that include required
+// devices) are filled first so that as many GPUs as possible are kept free.
+func (p *packPolicy) Allocate(devices Devices, available, required []string, size int) ([]string, error) {
+	candidates, needed, err := getCandidates(devices, available, required, size)
+	if err != nil {
+		return nil, err
+	}
+
+	total := make(map[string]int)
+	for _, d := range devices {
+		total[d.GetUUID()]++
+	}
+	free := make(map[string]int)
+	for _, d := range candidates {
+		free[d.GetUUID()]++
+	}
+	hasRequired := make(map[string]bool)
+	for _, d := range devices.Subset(required) {
+		hasRequired[d.GetUUID()] = true
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iid, jid := candidates[i].GetUUID(), candidates[j].GetUUID()
+		if hasRequired[iid] != hasRequired[jid] {
+			return hasRequired[iid]
+		}
+		// Prefer the GPU with the most replicas in use.
+		iused, jused := total[iid]-free[iid], total[jid]-free[jid]
+		if iused != jused {
+			return iused > jused
+		}
+		return free[iid] < free[jid]
+	})
+
+	return appendIDs(required, candidates[:needed]), nil
+}
+
+// Allocate selects devices from a single NUMA node if possible. The NUMA node
+// of the required devices is preferred, followed by the node with the fewest
+// free devices that can satisfy the request. If no single node can satisfy
+// the request, nodes are used in order of decreasing free devices.
+func (p *numaFirstPolicy) Allocate(devices Devices, available, required []string, size int) ([]string, error) {
+	candidates, needed, err := getCandidates(devices, available, required, size)
+	if err != nil {
+		return nil, err
+	}
+
+	byNode := make(map[int64]Devices)
+	for _, d := range candidates {
+		node := d.numaNode()
+		if byNode[node] == nil {
+			byNode[node] = make(Devices)
+		}
+		byNode[node][d.ID] = d
+	}
+	requiredNodes := make(map[int64]bool)
+	for _, d := range devices.Subset(required) {
+		requiredNodes[d.numaNode()] = true
+	}
+
+	var nodes []int64
+	for node := range byNode {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		ni, nj := nodes[i], nodes[j]
+		if requiredNodes[ni] != requiredNodes[nj] {
+			return requiredNodes[ni]
+		}
+		fitsi, fitsj := len(byNode[ni]) >= needed, len(byNode[nj]) >= needed
+		if fitsi != fitsj {
+			return fitsi
+		}
+		if fitsi && len(byNode[ni]) != len(byNode[nj]) {
+			return len(byNode[ni]) < len(byNode[nj])
+		}
+		if !fitsi && len(byNode[ni]) != len(byNode[nj]) {
+			return len(byNode[ni]) > len(byNode[nj])
+		}
+		return ni < nj
+	})
+
+	allocated := required
+	for _, node := range nodes {
+		if needed == 0 {
+			break
+		}
+		n := min(needed, len(byNode[node]))
+		ids, err := preferredWithinNode(devices, byNode[node], allocated, len(allocated)+n)
+		if err != nil {
+			return nil, err
+		}
+		allocated = ids
+		needed -= n
+	}
+	return allocated, nil
+}
+
+// preferredWithinNode selects devices from the candidates of a single NUMA
+// node. Replicas are spread across the GPUs of the node while full GPUs are
+// selected by index.
+func preferredWithinNode(devices Devices, candidates Devices, required []string, size int) ([]string, error) {
+	available := append(candidates.GetIDs(), required...)
+	if AnnotatedIDs(available).AnyHasAnnotations() {
+		return distributedAlloc(devices, available, required, size)
+	}
+	return (&simplePolicy{}).Allocate(devices, available, required, size)
+}
+
+// getCandidates returns the available devices that are not required, ordered
+// by index, as well as the number of these that are needed to satisfy the
+// allocation.
+func getCandidates(devices Devices, available, required []string, size int) ([]*Device, int, error) {
+	candidates := devices.Subset(available).Difference(devices.Subset(required)).sorted()
+	needed := size - len(required)
+	if len(candidates) < needed {
+		return nil, 0, fmt.Errorf("not enough available devices to satisfy allocation")
+	}
+	if needed < 0 {
+		needed = 0
+	}
+	return candidates, needed, nil
+}
+
+// appendIDs returns the specified IDs followed by the IDs of the devices.
+func appendIDs(ids []string, devices []*Device) []string {
+	res := append([]string{}, ids...)
+	for _, d := range devices {
+		res = append(res, d.ID)
+	}
+	return res
+}
+
+// anyMigDevices checks whether any of the devices are MIG devices.
+func (ds Devices) anyMigDevices() bool {
+	for _, d := range ds {
+		if d.IsMigDevice() {
+			return true
+		}
+	}
+	return false
+}
+
+// numaNode returns the NUMA node of the device or -1 if this is unknown.
+func (d *Device) numaNode() int64 {
+	if d.Topology == nil || len(d.Topology.Nodes) == 0 {
+		return -1
+	}
+	return d.Topology.Nodes[0].ID
+}
@@ -0,0 +1,270 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+// newTestDevices creates a set of devices with the specified number of
+// replicas for each GPU. GPU i is placed on NUMA node numaNodes[i].
+func newTestDevices(replicas int, numaNodes ...int64) Devices {
+	devices := make(Devices)
+	for i, node := range numaNodes {
+		uuid := fmt.Sprintf("GPU-%d", i)
+		topology := &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: node}}}
+		if replicas < 2 {
+			devices[uuid] = &Device{
+				Device: pluginapi.Device{ID: uuid, Topology: topology},
+				Index:  fmt.Sprintf("%d", i),
+			}
+			continue
+		}
+		for r := 0; r < replicas; r++ {
+			id := string(NewAnnotatedID(uuid, r))
+			devices[id] = &Device{
+				Device:   pluginapi.Device{ID: id, Topology: topology},
+				Index:    fmt.Sprintf("%d", i),
+				Replicas: replicas,
+			}
+		}
+	}
+	return devices
+}
+
+func TestAllocationPolicies(t *testing.T) {
+	testCases := []struct {
+		description string
+		policy      spec.AllocationPolicy
+		devices     Devices
+		available   []string
+		required    []string
+		size        int
+		expected    []string
+		// expectedUUIDs is checked instead of expected where the selected
+		// replica of a GPU is not significant.
+		expectedUUIDs []string
+		expectedErr   bool
+	}{
+		{
+			description: "simple selects lowest indices",
+			policy:      spec.AllocationPolicySimple,
+			devices:     newTestDevices(1, 0, 0, 0, 0),
+			available:   []string{"GPU-3", "GPU-1", "GPU-2"},
+			size:        2,
+			expected:    []string{"GPU-1", "GPU-2"},
+		},
+		{
+			description: "simple includes required devices",
+			policy:      spec.AllocationPolicySimple,
+			devices:     newTestDevices(1, 0, 0, 0, 0),
+			available:   []string{"GPU-3", "GPU-1", "GPU-2"},
+			required:    []string{"GPU-3"},
+			size:        2,
+			expected:    []string{"GPU-3", "GPU-1"},
+		},
+		{
+			description: "simple fails if not enough devices",
+			policy:      spec.AllocationPolicySimple,
+			devices:     newTestDevices(1, 0, 0),
+			available:   []string{"GPU-1"},
+			size:        2,
+			expectedErr: true,
+		},
+		{
+			description: "pack fills partially used GPU first",
+			policy:      spec.AllocationPolicyPack,
+			devices:     newTestDevices(2, 0, 0),
+			available:   []string{"GPU-0::0", "GPU-0::1", "GPU-1::1"},
+			size:        1,
+			expected:    []string{"GPU-1::1"},
+		},
+		{
+			description: "pack fills a single GPU before the next",
+			policy:      spec.AllocationPolicyPack,
+			devices:     newTestDevices(2, 0, 0),
+			available:   []string{"GPU-0::0", "GPU-0::1", "GPU-1::0", "GPU-1::1"},
+			size:        2,
+			expected:    []string{"GPU-0::0", "GPU-0::1"},
+		},
+		{
+			description:   "spread distributes replicas across GPUs",
+			policy:        spec.AllocationPolicySpread,
+			devices:       newTestDevices(2, 0, 0),
+			available:     []string{"GPU-0::0", "GPU-0::1", "GPU-1::1"},
+			size:          1,
+			expectedUUIDs: []string{"GPU-0"},
+		},
+		{
+			description: "numa-first selects the smallest node that fits",
+			policy:      spec.AllocationPolicyNUMAFirst,
+			devices:     newTestDevices(1, 0, 0, 0, 1, 1),
+			available:   []string{"GPU-0", "GPU-1", "GPU-2", "GPU-3", "GPU-4"},
+			size:        2,
+			expected:    []string{"GPU-3", "GPU-4"},
+		},
+		{
+			description: "numa-first prefers node of required device",
+			policy:      spec.AllocationPolicyNUMAFirst,
+			devices:     newTestDevices(1, 0, 0, 0, 1, 1),
+			available:   []string{"GPU-0", "GPU-1", "GPU-2", "GPU-3", "GPU-4"},
+			required:    []string{"GPU-2"},
+			size:        2,
+			expected:    []string{"GPU-2", "GPU-0"},
+		},
+		{
+			description: "numa-first spans nodes if required",
+			policy:      spec.AllocationPolicyNUMAFirst,
+			devices:     newTestDevices(1, 0, 0, 1),
+			available:   []string{"GPU-0", "GPU-1", "GPU-2"},
+			size:        3,
+			expected:    []string{"GPU-0", "GPU-1", "GPU-2"},
+		},
+		{
+			description:   "best-effort without topology spreads replicas",
+			policy:        spec.AllocationPolicyBestEffort,
+			devices:       newTestDevices(2, 0, 0),
+			available:     []string{"GPU-0::0", "GPU-0::1", "GPU-1::1"},
+			size:          1,
+			expectedUUIDs: []string{"GPU-0"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			policy, err := NewAllocationPolicy(tc.policy)
+			require.NoError(t, err)
+
+			allocated, err := policy.Allocate(tc.devices, tc.available, tc.required, tc.size)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expectedUUIDs != nil {
+				require.Equal(t, tc.expectedUUIDs, AnnotatedIDs(allocated).GetIDs())
+				return
+			}
+			require.Equal(t, tc.expected, allocated)
+		})
+	}
+}
+
+func TestBestEffortPolicyUsesAlignedAllocation(t *testing.T) {
+	var called bool
+	policy, err := newAllocationPolicy(spec.AllocationPolicyBestEffort, func(available, required []string, size int) ([]string, error) {
+		called = true
+		return available[:size], nil
+	}, false)
+	require.NoError(t, err)
+
+	_, err = policy.Allocate(newTestDevices(1, 0, 0), []string{"GPU-0", "GPU-1"}, nil, 1)
+	require.NoError(t, err)
+	require.True(t, called)
+
+	called = false
+	_, err = policy.Allocate(newTestDevices(2, 0), []string{"GPU-0::0", "GPU-0::1"}, nil, 1)
+	require.NoError(t, err)
+	require.False(t, called)
+}
+
+func TestNewAllocationPolicyUnknown(t *testing.T) {
+	_, err := NewAllocationPolicy("unknown")
+	require.Error(t, err)
+}
+
+func TestPreferredAllocationOnDistinctGPUs(t *testing.T) {
+	config := &spec.Config{
+		Sharing: spec.Sharing{
+			MPS: &spec.ReplicatedResources{
+				AllowMultiGPURequests: true,
+				Resources: []spec.ReplicatedResource{
+					{Name: "nvidia.com/gpu", Replicas: 4},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		description string
+		available   []string
+		required    []string
+		size        int
+		expectedErr bool
+	}{
+		{
+			description: "all replicas available",
+			size:        3,
+		},
+		{
+			description: "required replica",
+			required:    []string{"GPU-1::2"},
+			size:        2,
+		},
+		{
+			description: "single GPU with free replicas",
+			available:   []string{"GPU-0::0", "GPU-0::1", "GPU-1::0", "GPU-2::0", "GPU-2::1"},
+			size:        3,
+		},
+		{
+			description: "not enough distinct GPUs",
+			available:   []string{"GPU-0::0", "GPU-0::1", "GPU-1::0", "GPU-1::1"},
+			size:        3,
+			expectedErr: true,
+		},
+	}
+
+	for _, name := range []spec.AllocationPolicy{
+		spec.AllocationPolicyBestEffort,
+		spec.AllocationPolicyPack,
+		spec.AllocationPolicySpread,
+		spec.AllocationPolicyNUMAFirst,
+		spec.AllocationPolicySimple,
+	} {
+		for _, tc := range testCases {
+			t.Run(string(name)+"/"+tc.description, func(t *testing.T) {
+				policy, err := NewAllocationPolicy(name)
+				require.NoError(t, err)
+				r := resourceManager{
+					config:           config,
+					devices:          newTestDevices(4, 0, 0, 1, 1),
+					allocationPolicy: policy,
+				}
+
+				available := tc.available
+				if available == nil {
+					available = r.devices.GetIDs()
+				}
+				ids, err := r.getPreferredAllocation(available, tc.required, tc.size)
+				if tc.expectedErr {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				require.Len(t, ids, tc.size)
+				require.Subset(t, ids, tc.required)
+				require.NoError(t, r.ValidateRequest(ids))
+			})
+		}
+	}
+}
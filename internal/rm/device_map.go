@@ -33,6 +33,9 @@ type deviceMapBuilder struct {
 	migStrategy         *string
 	resources           *spec.Resources
 	replicatedResources *spec.ReplicatedResources
+	sharingStrategy     spec.SharingStrategy
+	memoryResource      *spec.MemoryResource
+	memoryResourceName  spec.ResourceName
 
 	newGPUDevice func(i int, gpu nvml.Device) (string, deviceInfo)
 }
@@ -47,8 +50,13 @@ func NewDeviceMap(infolib info.Interface, devicelib device.Interface, config *sp
 		migStrategy:         config.Flags.MigStrategy,
 		resources:           &config.Resources,
 		replicatedResources: config.Sharing.ReplicatedResources(),
+		sharingStrategy:     config.Sharing.SharingStrategy(),
+		memoryResource:      config.MemoryResource,
 		newGPUDevice:        newNvmlGPUDevice,
 	}
+	if config.MemoryResource != nil {
+		b.memoryResourceName = config.MemoryResource.ResourceName(config.GetResourceNamePrefix())
+	}
 
 	if infolib.ResolvePlatform() == info.PlatformWSL {
 		b.newGPUDevice = newWslGPUDevice
@@ -67,9 +75,33 @@ func (b *deviceMapBuilder) build() (DeviceMap, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error updating device map with replicas from replicatedResources config: %v", err)
 	}
+	devices, err = updateDeviceMapWithMemoryUnits(b.memoryResource, b.memoryResourceName, devices)
+	if err != nil {
+		return nil, fmt.Errorf("error updating device map with memory units from memoryResource config: %w", err)
+	}
+	// An MPS daemon is started for each shared resource and controls all
+	// devices of that resource. Resources must therefore not mix shared and
+	// non-shared devices.
+	if b.sharingStrategy == spec.SharingStrategyMPS {
+		if err := devices.assertSharingIsUniform(); err != nil {
+			return nil, fmt.Errorf("invalid MPS configuration: %w", err)
+		}
+	}
 	return devices, nil
 }
 
+// assertSharingIsUniform checks that the devices of each resource are either
+// all shared or all not shared.
+func (d DeviceMap) assertSharingIsUniform() error {
+	for name, devices := range d {
+		ids := AnnotatedIDs(devices.GetIDs())
+		if ids.AnyHasAnnotations() && !ids.AllHaveAnnotations() {
+			return fmt.Errorf("resource %v includes both shared and non-shared devices; set 'rename' for the shared devices", name)
+		}
+	}
+	return nil
+}
+
 // buildDeviceMapFromConfigResources builds a map of resource names to devices from spec.Config.Resources
 func (b *deviceMapBuilder) buildDeviceMapFromConfigResources() (DeviceMap, error) {
 	deviceMap, err := b.buildGPUDeviceMap()
@@ -121,13 +153,25 @@ func (b *deviceMapBuilder) buildGPUDeviceMap() (DeviceMap, error) {
 		if migEnabled && *b.migStrategy != spec.MigStrategyNone {
 			return nil
 		}
-		for _, resource := range b.resources.GPUs {
-			if resource.Pattern.Matches(name) {
-				index, info := b.newGPUDevice(i, gpu)
-				return devices.setEntry(resource.Name, index, info)
-			}
+		index, info := b.newGPUDevice(i, gpu)
+		dev, err := BuildDevice(index, info)
+		if err != nil {
+			return fmt.Errorf("error building Device: %v", err)
+		}
+		attrs, err := b.deviceAttributes(name, dev, gpu)
+		if err != nil {
+			return fmt.Errorf("error getting attributes for GPU %v: %w", i, err)
+		}
+		attrs.Product = name
+		resourceName, matched, err := b.resources.GPUResourceName(attrs)
+		if err != nil {
+			return fmt.Errorf("error getting resource name for GPU %v: %w", i, err)
 		}
-		return fmt.Errorf("GPU name '%v' does not match any resource patterns", name)
+		if !matched {
+			return fmt.Errorf("GPU name '%v' does not match any resource patterns", name)
+		}
+		devices.insert(resourceName, dev)
+		return nil
 	})
 	return devices, err
 }
@@ -140,17 +184,68 @@ func (b *deviceMapBuilder) buildMigDeviceMap() (DeviceMap, error) {
 		if err != nil {
 			return fmt.Errorf("error getting MIG profile for MIG device at index '(%v, %v)': %v", i, j, err)
 		}
-		for _, resource := range b.resources.MIGs {
-			if resource.Pattern.Matches(migProfile.String()) {
-				index, info := newMigDevice(i, j, mig)
-				return devices.setEntry(resource.Name, index, info)
-			}
+		index, info := newMigDevice(i, j, mig)
+		dev, err := BuildDevice(index, info)
+		if err != nil {
+			return fmt.Errorf("error building Device: %v", err)
+		}
+		attrs, err := b.deviceAttributes(migProfile.String(), dev, d)
+		if err != nil {
+			return fmt.Errorf("error getting attributes for MIG device at index '(%v, %v)': %w", i, j, err)
+		}
+		attrs.Profile = migProfile.String()
+		dev.MigProfile = migProfile.String()
+		product, ret := d.GetName()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting product name for GPU: %v", ret)
+		}
+		attrs.Product = product
+		resourceName, matched, err := b.resources.MIGResourceName(attrs)
+		if err != nil {
+			return fmt.Errorf("error getting resource name for MIG device at index '(%v, %v)': %w", i, j, err)
+		}
+		if !matched {
+			return fmt.Errorf("MIG profile '%v' does not match any resource patterns", migProfile)
 		}
-		return fmt.Errorf("MIG profile '%v' does not match any resource patterns", migProfile)
+		devices.insert(resourceName, dev)
+		return nil
 	})
 	return devices, err
 }
 
+// deviceAttributes returns the attributes that config.resources are matched
+// against for the specified device. The PCI device ID and fabric state are
+// queried from the (parent) GPU only if a resource uses a selector.
+func (b *deviceMapBuilder) deviceAttributes(name string, dev *Device, gpu device.Device) (spec.DeviceAttributes, error) {
+	attrs := spec.DeviceAttributes{
+		Name:              name,
+		UUID:              dev.ID,
+		Index:             dev.Index,
+		TotalMemory:       dev.TotalMemory,
+		ComputeCapability: dev.ComputeCapability,
+	}
+	if numa := int(dev.numaNode()); numa != -1 {
+		attrs.NUMANode = &numa
+	}
+	if !b.resources.HasSelectors() {
+		return attrs, nil
+	}
+
+	pciInfo, ret := gpu.GetPciInfo()
+	if ret != nvml.SUCCESS {
+		return attrs, fmt.Errorf("error getting PCI info: %v", ret)
+	}
+	attrs.PCIDeviceID = uint16(pciInfo.PciDeviceId >> 16)
+
+	fabricAttached, err := gpu.IsFabricAttached()
+	if err != nil {
+		return attrs, fmt.Errorf("error checking if GPU is fabric attached: %w", err)
+	}
+	attrs.FabricAttached = &fabricAttached
+
+	return attrs, nil
+}
+
 // assertAllMigDevicesAreValid ensures that each MIG-enabled device has at least one MIG device
 // associated with it.
 func (b *deviceMapBuilder) assertAllMigDevicesAreValid(uniform bool) error {
@@ -235,7 +330,7 @@ func (d DeviceMap) isEmpty() bool {
 	return true
 }
 
-// getIDsOfDevicesToReplicate returns a list of dervice IDs that we want to replicate.
+// getIDsOfDevicesToReplicate returns a list of device IDs that we want to replicate.
 func (d DeviceMap) getIDsOfDevicesToReplicate(r *spec.ReplicatedResource) ([]string, error) {
 	devices, exists := d[r.Name]
 	if !exists {
@@ -248,6 +343,8 @@ func (d DeviceMap) getIDsOfDevicesToReplicate(r *spec.ReplicatedResource) ([]str
 	}
 
 	// If a specific number of devices for this resource type are to be replicated.
+	// The devices with the lowest indices are selected so that the same
+	// devices are replicated across restarts.
 	if r.Devices.Count > 0 {
 		if r.Devices.Count > len(devices) {
 			return nil, fmt.Errorf("requested %d devices to be replicated, but only %d devices available", r.Devices.Count, len(devices))
@@ -316,6 +413,13 @@ func updateDeviceMapWithReplicas(replicatedResources *spec.ReplicatedResources,
 			devices.insert(r.Name, d)
 		}
 
+		if len(r.Classes) > 0 {
+			if err := devices.insertReplicaClasses(&r, oDevices[r.Name].Subset(ids)); err != nil {
+				return nil, fmt.Errorf("unable to create replica classes for '%v' resource: %w", r.Name, err)
+			}
+			continue
+		}
+
 		// Create replicated devices add them to the device map.
 		// Rename the resource for replicated devices as requested.
 		name := r.Name
@@ -323,9 +427,10 @@ func updateDeviceMapWithReplicas(replicatedResources *spec.ReplicatedResources,
 			name = r.Rename
 		}
 		for _, id := range ids {
+			original := oDevices[r.Name][id]
+			limits := getMPSLimits(replicatedResources.Limits, r.Name, original)
 			for i := 0; i < r.Replicas; i++ {
 				annotatedID := string(NewAnnotatedID(id, i))
-				original := oDevices[r.Name][id]
 				replicatedDevice := Device{
 					Device: pluginapi.Device{
 						ID:       annotatedID,
@@ -336,7 +441,14 @@ func updateDeviceMapWithReplicas(replicatedResources *spec.ReplicatedResources,
 					Index:             original.Index,
 					TotalMemory:       original.TotalMemory,
 					ComputeCapability: original.ComputeCapability,
+					PciBusID:          original.PciBusID,
+					MigProfile:        original.MigProfile,
 					Replicas:          r.Replicas,
+					// Explicit MPS limits are stored in the same way as
+					// the limits of replica classes.
+					ReplicaMemory:          limits.PinnedMemoryLimitBytes(),
+					ActiveThreadPercentage: limits.ActiveThreadPercentageFor(r.Replicas),
+					MPSUser:                r.User,
 				}
 				devices.insert(name, &replicatedDevice)
 			}
@@ -345,3 +457,62 @@ func updateDeviceMapWithReplicas(replicatedResources *spec.ReplicatedResources,
 
 	return devices, nil
 }
+
+// insertReplicaClasses splits each of the specified devices into the replica
+// classes of the replicated resource and inserts the replicas under the name of
+// their class. Replica numbers are unique per device across all classes.
+func (d DeviceMap) insertReplicaClasses(r *spec.ReplicatedResource, devices Devices) error {
+	totalReplicas := r.TotalReplicas()
+	for _, original := range devices.sorted() {
+		var requiredMemory uint64
+		for _, class := range r.Classes {
+			requiredMemory += uint64(class.Replicas) * uint64(class.Memory.Value())
+		}
+		if original.TotalMemory != 0 && requiredMemory > original.TotalMemory {
+			return fmt.Errorf("replica classes require %d bytes of memory but device %v only has %d", requiredMemory, original.ID, original.TotalMemory)
+		}
+
+		replica := 0
+		for _, class := range r.Classes {
+			memory := uint64(class.Memory.Value())
+			threadPercentage := class.ActiveThreadPercentage
+			if threadPercentage == 0 {
+				threadPercentage = defaultActiveThreadPercentage(memory, original.TotalMemory, totalReplicas)
+			}
+			for i := 0; i < class.Replicas; i++ {
+				replicatedDevice := Device{
+					Device: pluginapi.Device{
+						ID:       string(NewAnnotatedID(original.ID, replica)),
+						Health:   original.Health,
+						Topology: original.Topology,
+					},
+					Paths:                  original.Paths,
+					Index:                  original.Index,
+					TotalMemory:            original.TotalMemory,
+					ComputeCapability:      original.ComputeCapability,
+					PciBusID:               original.PciBusID,
+					MigProfile:             original.MigProfile,
+					Replicas:               totalReplicas,
+					SharedResource:         r.Name,
+					ReplicaMemory:          memory,
+					ActiveThreadPercentage: threadPercentage,
+					MPSUser:                r.User,
+				}
+				d.insert(class.Name, &replicatedDevice)
+				replica++
+			}
+		}
+	}
+	return nil
+}
+
+// defaultActiveThreadPercentage returns the active thread percentage of a
+// replica that is proportional to its share of device memory. If the device
+// memory is unknown, the threads are split evenly over all replicas.
+func defaultActiveThreadPercentage(memory uint64, totalMemory uint64, totalReplicas int) int {
+	percentage := 100 / totalReplicas
+	if totalMemory != 0 {
+		percentage = int(memory * 100 / totalMemory)
+	}
+	return max(percentage, 1)
+}
@@ -17,9 +17,16 @@
 package rm
 
 import (
+	"encoding/json"
+	"fmt"
 	"testing"
 
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvlib/pkg/nvlib/info"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
@@ -107,3 +114,335 @@ func TestDeviceMapInsert(t *testing.T) {
 		})
 	}
 }
+
+func TestGetIDsOfDevicesToReplicateByCount(t *testing.T) {
+	deviceMap := DeviceMap{
+		"nvidia.com/gpu": newOrderedTestDevices(
+			&Device{Device: pluginapi.Device{ID: "GPU-d"}, Index: "10"},
+			&Device{Device: pluginapi.Device{ID: "GPU-c"}, Index: "3"},
+			&Device{Device: pluginapi.Device{ID: "GPU-b"}, Index: "2"},
+			&Device{Device: pluginapi.Device{ID: "GPU-a"}, Index: "1"},
+		),
+	}
+	r := &spec.ReplicatedResource{
+		Name:     "nvidia.com/gpu",
+		Devices:  spec.ReplicatedDevices{Count: 2},
+		Replicas: 2,
+	}
+
+	// Repeat the check since the iteration order of a map varies.
+	for i := 0; i < 10; i++ {
+		ids, err := deviceMap.getIDsOfDevicesToReplicate(r)
+		require.NoError(t, err)
+		require.Equal(t, []string{"GPU-a", "GPU-b"}, ids)
+	}
+}
+
+func TestUpdateDeviceMapWithReplicas(t *testing.T) {
+	newDeviceMap := func() DeviceMap {
+		return DeviceMap{
+			"nvidia.com/a100": newOrderedTestDevices(
+				&Device{Device: pluginapi.Device{ID: "GPU-0"}, Index: "0"},
+				&Device{Device: pluginapi.Device{ID: "GPU-1"}, Index: "1"},
+				&Device{Device: pluginapi.Device{ID: "GPU-2"}, Index: "2"},
+				&Device{Device: pluginapi.Device{ID: "GPU-3"}, Index: "3"},
+			),
+			"nvidia.com/t4": newOrderedTestDevices(
+				&Device{Device: pluginapi.Device{ID: "GPU-4"}, Index: "4"},
+			),
+		}
+	}
+
+	testCases := []struct {
+		description         string
+		replicatedResources *spec.ReplicatedResources
+		expectedIDs         map[spec.ResourceName][]string
+		expectedUniformErr  bool
+	}{
+		{
+			description: "selected devices are shared under a new name",
+			replicatedResources: &spec.ReplicatedResources{
+				Resources: []spec.ReplicatedResource{
+					{
+						Name:     "nvidia.com/a100",
+						Rename:   "nvidia.com/a100.shared",
+						Devices:  spec.ReplicatedDevices{List: []spec.ReplicatedDeviceRef{"2", "3"}},
+						Replicas: 2,
+					},
+				},
+			},
+			expectedIDs: map[spec.ResourceName][]string{
+				"nvidia.com/a100":        {"GPU-0", "GPU-1"},
+				"nvidia.com/a100.shared": {"GPU-2::0", "GPU-2::1", "GPU-3::0", "GPU-3::1"},
+				"nvidia.com/t4":          {"GPU-4"},
+			},
+		},
+		{
+			description: "counted devices are shared without renaming",
+			replicatedResources: &spec.ReplicatedResources{
+				Resources: []spec.ReplicatedResource{
+					{
+						Name:     "nvidia.com/a100",
+						Devices:  spec.ReplicatedDevices{Count: 1},
+						Replicas: 2,
+					},
+				},
+			},
+			expectedIDs: map[spec.ResourceName][]string{
+				"nvidia.com/a100": {"GPU-0::0", "GPU-0::1", "GPU-1", "GPU-2", "GPU-3"},
+				"nvidia.com/t4":   {"GPU-4"},
+			},
+			expectedUniformErr: true,
+		},
+		{
+			description: "all devices of a renamed resource are shared",
+			replicatedResources: &spec.ReplicatedResources{
+				Resources: []spec.ReplicatedResource{
+					{
+						Name:     "nvidia.com/t4",
+						Rename:   "nvidia.com/t4.shared",
+						Devices:  spec.ReplicatedDevices{All: true},
+						Replicas: 2,
+					},
+				},
+			},
+			expectedIDs: map[spec.ResourceName][]string{
+				"nvidia.com/a100":      {"GPU-0", "GPU-1", "GPU-2", "GPU-3"},
+				"nvidia.com/t4.shared": {"GPU-4::0", "GPU-4::1"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			deviceMap, err := updateDeviceMapWithReplicas(tc.replicatedResources, newDeviceMap())
+			require.NoError(t, err)
+
+			ids := make(map[spec.ResourceName][]string)
+			for name, devices := range deviceMap {
+				ids[name] = devices.GetIDs()
+			}
+			require.Equal(t, tc.expectedIDs, ids)
+
+			err = deviceMap.assertSharingIsUniform()
+			if tc.expectedUniformErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBuildDeviceMapWithSelectors(t *testing.T) {
+	server := dgxa100.New()
+	for i, sd := range server.Devices {
+		d := sd.(*dgxa100.Device)
+		pciDeviceID := uint32(0x20B010DE)
+		if i >= 4 {
+			pciDeviceID = 0x20B210DE
+		}
+		d.GetPciInfoFunc = func() (nvml.PciInfo, nvml.Return) {
+			return nvml.PciInfo{PciDeviceId: pciDeviceID}, nvml.SUCCESS
+		}
+		d.GetGpuFabricInfoFunc = func() (nvml.GpuFabricInfo, nvml.Return) {
+			return nvml.GpuFabricInfo{}, nvml.ERROR_NOT_SUPPORTED
+		}
+	}
+
+	migStrategy := spec.MigStrategyNone
+	config := &spec.Config{
+		Flags: spec.Flags{CommandLineFlags: spec.CommandLineFlags{MigStrategy: &migStrategy}},
+	}
+	err := json.Unmarshal([]byte(`{"gpus": [
+		{"selector": {"pciDeviceIDs": ["0x20B2"]}, "name": "a100-80gb"},
+		{"selector": {"indices": [0]}, "name": "gpu-first"},
+		{"pattern": "*", "name": "gpu"}
+	]}`), &config.Resources)
+	require.NoError(t, err)
+
+	devices, err := NewDeviceMap(info.New(info.WithNvmlLib(server)), device.New(server), config)
+	require.NoError(t, err)
+
+	require.ElementsMatch(t, []string{"4", "5", "6", "7"}, devices["nvidia.com/a100-80gb"].GetIndices())
+	require.ElementsMatch(t, []string{"0"}, devices["nvidia.com/gpu-first"].GetIndices())
+	require.ElementsMatch(t, []string{"1", "2", "3"}, devices["nvidia.com/gpu"].GetIndices())
+}
+
+func TestBuildDeviceMapWithTemplates(t *testing.T) {
+	server := dgxa100.New()
+
+	migStrategy := spec.MigStrategyNone
+	config := &spec.Config{
+		Flags: spec.Flags{CommandLineFlags: spec.CommandLineFlags{MigStrategy: &migStrategy}},
+	}
+	err := json.Unmarshal([]byte(`{"gpus": [
+		{"pattern": "*", "name": "{{ .Product | lower }}-{{ .Family }}"}
+	]}`), &config.Resources)
+	require.NoError(t, err)
+
+	devices, err := NewDeviceMap(info.New(info.WithNvmlLib(server)), device.New(server), config)
+	require.NoError(t, err)
+
+	require.Len(t, devices, 1)
+	require.Len(t, devices["nvidia.com/mock-nvidia-a100-sxm4-40gb-ampere"], 8)
+}
+
+func TestUpdateDeviceMapWithMPSLimits(t *testing.T) {
+	memory := resource.MustParse("10Gi")
+	oDevices := DeviceMap{
+		"nvidia.com/gpu": newOrderedTestDevices(
+			&Device{Device: pluginapi.Device{ID: "GPU-0"}, Index: "0", TotalMemory: 40 * 1024 * 1024 * 1024},
+			&Device{Device: pluginapi.Device{ID: "GPU-1"}, Index: "1", TotalMemory: 40 * 1024 * 1024 * 1024},
+		),
+	}
+	replicatedResources := &spec.ReplicatedResources{
+		Resources: []spec.ReplicatedResource{
+			{
+				Name:     "nvidia.com/gpu",
+				Devices:  spec.ReplicatedDevices{All: true},
+				Replicas: 4,
+			},
+		},
+		Limits: []spec.MPSLimits{
+			{
+				Name:             "nvidia.com/gpu",
+				Devices:          spec.ReplicatedDevices{All: true},
+				OvercommitFactor: 1.6,
+			},
+			{
+				Name:                   "nvidia.com/gpu",
+				Devices:                spec.ReplicatedDevices{List: []spec.ReplicatedDeviceRef{"1"}},
+				PinnedMemoryLimit:      &memory,
+				ActiveThreadPercentage: 30,
+			},
+		},
+	}
+
+	devices, err := updateDeviceMapWithReplicas(replicatedResources, oDevices)
+	require.NoError(t, err)
+	require.Len(t, devices["nvidia.com/gpu"], 8)
+
+	for _, d := range devices["nvidia.com/gpu"] {
+		switch d.GetUUID() {
+		case "GPU-0":
+			require.Equal(t, uint64(0), d.ReplicaMemory)
+			require.Equal(t, 40, d.ActiveThreadPercentage)
+		case "GPU-1":
+			require.Equal(t, uint64(10*1024*1024*1024), d.ReplicaMemory)
+			require.Equal(t, 30, d.ActiveThreadPercentage)
+		}
+	}
+}
+
+func TestUpdateDeviceMapWithMPSUser(t *testing.T) {
+	oDevices := DeviceMap{
+		"nvidia.com/gpu": newOrderedTestDevices(
+			&Device{Device: pluginapi.Device{ID: "GPU-0"}, Index: "0"},
+			&Device{Device: pluginapi.Device{ID: "GPU-1"}, Index: "1"},
+		),
+	}
+	replicatedResources := &spec.ReplicatedResources{
+		Resources: []spec.ReplicatedResource{
+			{
+				Name:     "nvidia.com/gpu",
+				Rename:   "nvidia.com/gpu-user",
+				Devices:  spec.ReplicatedDevices{List: []spec.ReplicatedDeviceRef{"0"}},
+				Replicas: 2,
+				User:     &spec.MPSUser{UID: 1000, GID: 2000},
+			},
+			{
+				Name:     "nvidia.com/gpu",
+				Devices:  spec.ReplicatedDevices{List: []spec.ReplicatedDeviceRef{"1"}},
+				Replicas: 2,
+			},
+		},
+	}
+
+	devices, err := updateDeviceMapWithReplicas(replicatedResources, oDevices)
+	require.NoError(t, err)
+	require.Len(t, devices["nvidia.com/gpu-user"], 2)
+	for _, d := range devices["nvidia.com/gpu-user"] {
+		require.Equal(t, &spec.MPSUser{UID: 1000, GID: 2000}, d.MPSUser)
+	}
+	for _, d := range devices["nvidia.com/gpu"] {
+		require.Nil(t, d.MPSUser)
+	}
+}
+
+func TestBuildDeviceMapPciBusIDs(t *testing.T) {
+	server := dgxa100.New()
+	for i, sd := range server.Devices {
+		d := sd.(*dgxa100.Device)
+		d.GetPciInfoFunc = func() (nvml.PciInfo, nvml.Return) {
+			info := nvml.PciInfo{PciDeviceId: 0x20B010DE}
+			copy(info.BusId[:], fmt.Sprintf("00000000:%02X:00.0", 0x07+8*i))
+			return info, nvml.SUCCESS
+		}
+	}
+
+	migStrategy := spec.MigStrategyNone
+	config := &spec.Config{
+		Flags: spec.Flags{CommandLineFlags: spec.CommandLineFlags{MigStrategy: &migStrategy}},
+	}
+	err := json.Unmarshal([]byte(`{"gpus": [{"pattern": "*", "name": "gpu"}]}`), &config.Resources)
+	require.NoError(t, err)
+
+	devices, err := NewDeviceMap(info.New(info.WithNvmlLib(server)), device.New(server), config)
+	require.NoError(t, err)
+
+	require.Equal(t,
+		[]string{
+			"0000:07:00.0", "0000:0f:00.0", "0000:17:00.0", "0000:1f:00.0",
+			"0000:27:00.0", "0000:2f:00.0", "0000:37:00.0", "0000:3f:00.0",
+		},
+		devices["nvidia.com/gpu"].GetPciBusIDs(),
+	)
+}
+
+func TestUpdateDeviceMapWithReplicaClasses(t *testing.T) {
+	const gib = 1024 * 1024 * 1024
+	newDeviceMap := func() DeviceMap {
+		return DeviceMap{
+			"nvidia.com/gpu": newOrderedTestDevices(
+				&Device{Device: pluginapi.Device{ID: "GPU-0"}, Index: "0", TotalMemory: 80 * gib},
+				&Device{Device: pluginapi.Device{ID: "GPU-1"}, Index: "1", TotalMemory: 80 * gib},
+			),
+		}
+	}
+	newReplicatedResources := func(memory20g string) *spec.ReplicatedResources {
+		return &spec.ReplicatedResources{
+			Resources: []spec.ReplicatedResource{
+				{
+					Name:    "nvidia.com/gpu",
+					Devices: spec.ReplicatedDevices{List: []spec.ReplicatedDeviceRef{"1"}},
+					Classes: []spec.ReplicaClass{
+						{Name: "nvidia.com/gpu-20g", Replicas: 2, Memory: resource.MustParse(memory20g), ActiveThreadPercentage: 30},
+						{Name: "nvidia.com/gpu-10g", Replicas: 4, Memory: resource.MustParse("10Gi")},
+					},
+				},
+			},
+		}
+	}
+
+	devices, err := updateDeviceMapWithReplicas(newReplicatedResources("20Gi"), newDeviceMap())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"GPU-0"}, devices["nvidia.com/gpu"].GetIDs())
+	require.Equal(t, []string{"GPU-1::0", "GPU-1::1"}, devices["nvidia.com/gpu-20g"].GetIDs())
+	require.Equal(t, []string{"GPU-1::2", "GPU-1::3", "GPU-1::4", "GPU-1::5"}, devices["nvidia.com/gpu-10g"].GetIDs())
+
+	for _, d := range devices["nvidia.com/gpu-20g"] {
+		require.Equal(t, spec.ResourceName("nvidia.com/gpu"), d.SharedResource)
+		require.Equal(t, uint64(20*gib), d.ReplicaMemory)
+		require.Equal(t, 30, d.ActiveThreadPercentage)
+		require.Equal(t, 6, d.Replicas)
+	}
+	for _, d := range devices["nvidia.com/gpu-10g"] {
+		require.Equal(t, uint64(10*gib), d.ReplicaMemory)
+		require.Equal(t, 12, d.ActiveThreadPercentage)
+	}
+
+	_, err = updateDeviceMapWithReplicas(newReplicatedResources("30Gi"), newDeviceMap())
+	require.Error(t, err)
+}
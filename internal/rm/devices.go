@@ -18,11 +18,14 @@ package rm
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
 	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
 )
 
 // Device wraps pluginapi.Device with extra metadata and functions.
@@ -32,9 +35,31 @@ type Device struct {
 	Index             string
 	TotalMemory       uint64
 	ComputeCapability string
+	// PciBusID is the PCI bus ID of a GPU in the form 0000:07:00.0 and empty
+	// for MIG devices and devices that are not PCI devices.
+	PciBusID string
+	// MigProfile is the profile of a MIG device and empty for full GPUs.
+	MigProfile string
 	// Replicas stores the total number of times this device is replicated.
 	// If this is 0 or 1 then the device is not shared.
 	Replicas int
+	// SharedResource is the resource that a replica was carved from if it
+	// belongs to a replica class, and empty otherwise. The replicas of all
+	// classes of a resource are controlled by a single MPS daemon.
+	SharedResource spec.ResourceName
+	// ReplicaMemory is the memory in bytes available to a replica of a
+	// replica class or the explicit MPS pinned memory limit of a replica.
+	ReplicaMemory uint64
+	// ActiveThreadPercentage is the MPS active thread percentage of a replica
+	// of a replica class or the explicit MPS thread limit of a replica.
+	ActiveThreadPercentage int
+	// MPSUser is the user that the MPS daemon controlling a replica is
+	// started as, or nil if the daemon runs as the user of its container.
+	MPSUser *spec.MPSUser
+	// MemoryUnit indicates that the device represents ReplicaMemory bytes of
+	// the memory of a GPU. Requests for memory units are satisfied from a
+	// single GPU.
+	MemoryUnit bool
 }
 
 // deviceInfo defines the information the required to construct a Device
@@ -44,6 +69,7 @@ type deviceInfo interface {
 	GetNumaNode() (bool, int, error)
 	GetTotalMemory() (uint64, error)
 	GetComputeCapability() (string, error)
+	GetPciBusID() (string, error)
 }
 
 // Devices wraps a map[string]*Device with some functions.
@@ -82,9 +108,15 @@ func BuildDevice(index string, d deviceInfo) (*Device, error) {
 		return nil, fmt.Errorf("error getting device compute capability: %w", err)
 	}
 
+	pciBusID, err := d.GetPciBusID()
+	if err != nil {
+		return nil, fmt.Errorf("error getting device PCI bus ID: %w", err)
+	}
+
 	dev := Device{
 		TotalMemory:       totalMemory,
 		ComputeCapability: computeCapability,
+		PciBusID:          pciBusID,
 	}
 	dev.ID = uuid
 	dev.Index = index
@@ -151,20 +183,22 @@ func (ds Devices) Difference(ods Devices) Devices {
 	return res
 }
 
-// GetIDs returns the ids from all devices in the Devices
+// GetIDs returns the ids from all devices in the Devices.
+// The ids are ordered as described for sorted.
 func (ds Devices) GetIDs() []string {
 	var res []string
-	for _, d := range ds {
+	for _, d := range ds.sorted() {
 		res = append(res, d.ID)
 	}
 	return res
 }
 
 // GetUUIDs returns the uuids associated with the Device in the set.
+// The uuids are ordered by the index of the associated devices.
 func (ds Devices) GetUUIDs() []string {
 	var res []string
 	seen := make(map[string]bool)
-	for _, d := range ds {
+	for _, d := range ds.sorted() {
 		uuid := d.GetUUID()
 		if seen[uuid] {
 			continue
@@ -175,34 +209,119 @@ func (ds Devices) GetUUIDs() []string {
 	return res
 }
 
-// GetPluginDevices returns the plugin Devices from all devices in the Devices
+// GetPluginDevices returns the plugin Devices from all devices in the Devices.
+// The devices are ordered as described for sorted.
 func (ds Devices) GetPluginDevices() []*pluginapi.Device {
 	var res []*pluginapi.Device
-	for _, device := range ds {
+	for _, device := range ds.sorted() {
 		d := device
 		res = append(res, &d.Device)
 	}
 	return res
 }
 
-// GetIndices returns the Indices from all devices in the Devices
+// GetIndices returns the Indices from all devices in the Devices.
+// The indices are ordered as described for sorted.
 func (ds Devices) GetIndices() []string {
 	var res []string
-	for _, d := range ds {
+	for _, d := range ds.sorted() {
 		res = append(res, d.Index)
 	}
 	return res
 }
 
-// GetPaths returns the Paths from all devices in the Devices
+// GetPciBusIDs returns the PCI bus IDs from all devices in the Devices.
+// The bus IDs are ordered as described for sorted.
+func (ds Devices) GetPciBusIDs() []string {
+	var res []string
+	for _, d := range ds.sorted() {
+		res = append(res, d.PciBusID)
+	}
+	return res
+}
+
+// GetPaths returns the Paths from all devices in the Devices.
+// The paths are ordered by the index of the associated devices.
 func (ds Devices) GetPaths() []string {
 	var res []string
-	for _, d := range ds {
+	for _, d := range ds.sorted() {
 		res = append(res, d.Paths...)
 	}
 	return res
 }
 
+// sorted returns the devices ordered by their GPU index. MIG devices with
+// indices of the form i:j are ordered numerically by i and then j, and the
+// replicas of a device are ordered by their replica number. Devices that
+// cannot otherwise be distinguished are ordered by ID.
+func (ds Devices) sorted() []*Device {
+	type ordered struct {
+		device *Device
+		order  deviceOrder
+	}
+	devices := make([]ordered, 0, len(ds))
+	for _, d := range ds {
+		devices = append(devices, ordered{d, d.order()})
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].order.less(devices[j].order)
+	})
+
+	res := make([]*Device, 0, len(devices))
+	for _, d := range devices {
+		res = append(res, d.device)
+	}
+	return res
+}
+
+// deviceOrder holds the parsed fields of a device that define its order.
+type deviceOrder struct {
+	index   []int
+	replica int
+	id      string
+}
+
+// order returns the parsed fields used to order the device.
+func (d *Device) order() deviceOrder {
+	_, replica := AnnotatedID(d.ID).Split()
+	return deviceOrder{
+		index:   parseIndex(d.Index),
+		replica: replica,
+		id:      d.ID,
+	}
+}
+
+// less compares the numeric GPU (and MIG) indices, followed by the replica
+// number and ID.
+func (o deviceOrder) less(p deviceOrder) bool {
+	for k := 0; k < len(o.index) && k < len(p.index); k++ {
+		if o.index[k] != p.index[k] {
+			return o.index[k] < p.index[k]
+		}
+	}
+	if len(o.index) != len(p.index) {
+		return len(o.index) < len(p.index)
+	}
+	if o.replica != p.replica {
+		return o.replica < p.replica
+	}
+	return o.id < p.id
+}
+
+// parseIndex splits an index of the form i or i:j into its numeric parts.
+// Parts that are not numeric are treated as -1.
+func parseIndex(index string) []int {
+	var parts []int
+	for _, p := range strings.Split(index, ":") {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			v = -1
+		}
+		parts = append(parts, v)
+	}
+	return parts
+}
+
 // AlignedAllocationSupported checks whether all devices support an aligned allocation
 func (ds Devices) AlignedAllocationSupported() bool {
 	for _, d := range ds {
@@ -276,6 +395,16 @@ func (rs AnnotatedIDs) AnyHasAnnotations() bool {
 	return false
 }
 
+// AllHaveAnnotations checks if all IDs have annotations or not.
+func (rs AnnotatedIDs) AllHaveAnnotations() bool {
+	for _, r := range rs {
+		if !AnnotatedID(r).HasAnnotations() {
+			return false
+		}
+	}
+	return true
+}
+
 // GetIDs returns just the ID parts of the annotated IDs as a []string
 func (rs AnnotatedIDs) GetIDs() []string {
 	res := make([]string, len(rs))
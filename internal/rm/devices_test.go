@@ -0,0 +1,98 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func newOrderedTestDevices(devices ...*Device) Devices {
+	res := make(Devices)
+	for _, d := range devices {
+		res[d.ID] = d
+	}
+	return res
+}
+
+func TestDevicesOrdering(t *testing.T) {
+	testCases := []struct {
+		description     string
+		devices         Devices
+		expectedIDs     []string
+		expectedUUIDs   []string
+		expectedIndices []string
+		expectedPaths   []string
+	}{
+		{
+			description: "GPU indices are ordered numerically",
+			devices: newOrderedTestDevices(
+				&Device{Device: pluginapi.Device{ID: "GPU-c"}, Index: "10", Paths: []string{"/dev/nvidia10"}},
+				&Device{Device: pluginapi.Device{ID: "GPU-a"}, Index: "2", Paths: []string{"/dev/nvidia2"}},
+				&Device{Device: pluginapi.Device{ID: "GPU-b"}, Index: "1", Paths: []string{"/dev/nvidia1"}},
+			),
+			expectedIDs:     []string{"GPU-b", "GPU-a", "GPU-c"},
+			expectedUUIDs:   []string{"GPU-b", "GPU-a", "GPU-c"},
+			expectedIndices: []string{"1", "2", "10"},
+			expectedPaths:   []string{"/dev/nvidia1", "/dev/nvidia2", "/dev/nvidia10"},
+		},
+		{
+			description: "MIG indices are ordered numerically",
+			devices: newOrderedTestDevices(
+				&Device{Device: pluginapi.Device{ID: "MIG-a"}, Index: "1:0"},
+				&Device{Device: pluginapi.Device{ID: "MIG-b"}, Index: "0:10"},
+				&Device{Device: pluginapi.Device{ID: "MIG-c"}, Index: "0:2"},
+				&Device{Device: pluginapi.Device{ID: "MIG-d"}, Index: "10:1"},
+			),
+			expectedIDs:     []string{"MIG-c", "MIG-b", "MIG-a", "MIG-d"},
+			expectedUUIDs:   []string{"MIG-c", "MIG-b", "MIG-a", "MIG-d"},
+			expectedIndices: []string{"0:2", "0:10", "1:0", "10:1"},
+		},
+		{
+			description: "replicas are ordered by replica number",
+			devices: newOrderedTestDevices(
+				&Device{Device: pluginapi.Device{ID: "GPU-b::10"}, Index: "1"},
+				&Device{Device: pluginapi.Device{ID: "GPU-b::2"}, Index: "1"},
+				&Device{Device: pluginapi.Device{ID: "GPU-a::10"}, Index: "0"},
+				&Device{Device: pluginapi.Device{ID: "GPU-a::1"}, Index: "0"},
+			),
+			expectedIDs:     []string{"GPU-a::1", "GPU-a::10", "GPU-b::2", "GPU-b::10"},
+			expectedUUIDs:   []string{"GPU-a", "GPU-b"},
+			expectedIndices: []string{"0", "0", "1", "1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			// Repeat the checks since the iteration order of a map varies.
+			for i := 0; i < 10; i++ {
+				require.Equal(t, tc.expectedIDs, tc.devices.GetIDs())
+				require.Equal(t, tc.expectedUUIDs, tc.devices.GetUUIDs())
+				require.Equal(t, tc.expectedIndices, tc.devices.GetIndices())
+				require.Equal(t, tc.expectedPaths, tc.devices.GetPaths())
+
+				var pluginDeviceIDs []string
+				for _, d := range tc.devices.GetPluginDevices() {
+					pluginDeviceIDs = append(pluginDeviceIDs, d.ID)
+				}
+				require.Equal(t, tc.expectedIDs, pluginDeviceIDs)
+			}
+		})
+	}
+}
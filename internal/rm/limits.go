@@ -0,0 +1,46 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+// getMPSLimits returns the MPS limits that apply to the specified device of a
+// resource. Limits that list the device take precedence over limits for all
+// devices of the resource. If no limits apply, nil is returned.
+func getMPSLimits(limits []spec.MPSLimits, name spec.ResourceName, device *Device) *spec.MPSLimits {
+	var all *spec.MPSLimits
+	for i := range limits {
+		l := &limits[i]
+		if l.Name != name {
+			continue
+		}
+		if l.Devices.All {
+			if all == nil {
+				all = l
+			}
+			continue
+		}
+		for _, ref := range l.Devices.List {
+			if (ref.IsUUID() && string(ref) == device.GetUUID()) || string(ref) == device.Index {
+				return l
+			}
+		}
+	}
+	return all
+}
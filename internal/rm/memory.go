@@ -0,0 +1,137 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+// updateDeviceMapWithMemoryUnits returns an updated map of resource names to
+// devices in which the full, non-shared GPUs of the resources selected by the
+// memory resource config are replaced by one device per unit of their memory.
+// The memory units are advertised under the specified resource name.
+func updateDeviceMapWithMemoryUnits(config *spec.MemoryResource, name spec.ResourceName, oDevices DeviceMap) (DeviceMap, error) {
+	if config == nil {
+		return oDevices, nil
+	}
+	if len(oDevices[name]) > 0 {
+		return nil, fmt.Errorf("memory resource %v is also used as a GPU resource", name)
+	}
+	unit := config.UnitBytes()
+
+	var resources []spec.ResourceName
+	for r := range oDevices {
+		resources = append(resources, r)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i] < resources[j] })
+
+	devices := make(DeviceMap)
+	for _, r := range resources {
+		for _, d := range oDevices[r].sorted() {
+			if !config.Includes(r) || d.IsMigDevice() || AnnotatedID(d.ID).HasAnnotations() {
+				devices.insert(r, d)
+				continue
+			}
+			units := d.TotalMemory / unit
+			if units == 0 {
+				klog.Warningf("Not advertising memory of device %v: total memory %d is smaller than the unit %d", d.ID, d.TotalMemory, unit)
+				devices.insert(r, d)
+				continue
+			}
+			for i := 0; i < int(units); i++ {
+				memoryUnit := Device{
+					Device: pluginapi.Device{
+						ID:       string(NewAnnotatedID(d.ID, i)),
+						Health:   d.Health,
+						Topology: d.Topology,
+					},
+					Paths:             d.Paths,
+					Index:             d.Index,
+					TotalMemory:       d.TotalMemory,
+					ComputeCapability: d.ComputeCapability,
+					PciBusID:          d.PciBusID,
+					Replicas:          int(units),
+					ReplicaMemory:     unit,
+					MemoryUnit:        true,
+				}
+				devices.insert(name, &memoryUnit)
+			}
+		}
+	}
+	return devices, nil
+}
+
+// anyMemoryUnits checks whether any of the devices are memory units.
+func (ds Devices) anyMemoryUnits() bool {
+	for _, d := range ds {
+		if d != nil && d.MemoryUnit {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryUnitAlloc allocates the requested number of memory units from a single
+// GPU. If the required units determine the GPU, the remaining units are taken
+// from it. Otherwise the GPU with the fewest available units that can satisfy
+// the request is selected so that larger requests can still be satisfied.
+func memoryUnitAlloc(devices Devices, available, required []string, size int) ([]string, error) {
+	requiredUUIDs := devices.Subset(required).GetUUIDs()
+	if len(requiredUUIDs) > 1 {
+		return nil, fmt.Errorf("required memory units span %d GPUs", len(requiredUUIDs))
+	}
+
+	candidates, needed, err := getCandidates(devices, available, required, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var uuids []string
+	byGPU := make(map[string][]*Device)
+	for _, d := range candidates {
+		uuid := d.GetUUID()
+		if _, exists := byGPU[uuid]; !exists {
+			uuids = append(uuids, uuid)
+		}
+		byGPU[uuid] = append(byGPU[uuid], d)
+	}
+
+	if len(requiredUUIDs) == 1 {
+		uuids = requiredUUIDs
+	}
+
+	selected := ""
+	for _, uuid := range uuids {
+		if len(byGPU[uuid]) < needed {
+			continue
+		}
+		if selected == "" || len(byGPU[uuid]) < len(byGPU[selected]) {
+			selected = uuid
+		}
+	}
+	if selected == "" {
+		return nil, fmt.Errorf("no single GPU has %d memory units available", size)
+	}
+
+	return appendIDs(required, byGPU[selected][:needed]), nil
+}
@@ -0,0 +1,192 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
+)
+
+const gib = 1024 * 1024 * 1024
+
+func TestUpdateDeviceMapWithMemoryUnits(t *testing.T) {
+	newDeviceMap := func() DeviceMap {
+		return DeviceMap{
+			"nvidia.com/gpu": newOrderedTestDevices(
+				&Device{Device: pluginapi.Device{ID: "GPU-0"}, Index: "0", TotalMemory: 4 * gib},
+				&Device{Device: pluginapi.Device{ID: "GPU-1"}, Index: "1", TotalMemory: 2*gib + gib/2},
+			),
+			"nvidia.com/gpu.shared": newOrderedTestDevices(
+				&Device{Device: pluginapi.Device{ID: "GPU-2::0"}, Index: "2", TotalMemory: 4 * gib},
+			),
+			"nvidia.com/mig-1g.5gb": newOrderedTestDevices(
+				&Device{Device: pluginapi.Device{ID: "MIG-0"}, Index: "3:0", TotalMemory: 5 * gib},
+			),
+		}
+	}
+	twoGiB := resource.MustParse("2Gi")
+
+	testCases := []struct {
+		description   string
+		config        *spec.MemoryResource
+		expectedIDs   map[spec.ResourceName][]string
+		expectedError bool
+	}{
+		{
+			description: "no memory resource",
+			expectedIDs: map[spec.ResourceName][]string{
+				"nvidia.com/gpu":        {"GPU-0", "GPU-1"},
+				"nvidia.com/gpu.shared": {"GPU-2::0"},
+				"nvidia.com/mig-1g.5gb": {"MIG-0"},
+			},
+		},
+		{
+			description: "full GPUs are advertised in units of 1Gi by default",
+			config:      &spec.MemoryResource{},
+			expectedIDs: map[spec.ResourceName][]string{
+				"nvidia.com/gpumem":     {"GPU-0::0", "GPU-0::1", "GPU-0::2", "GPU-0::3", "GPU-1::0", "GPU-1::1"},
+				"nvidia.com/gpu.shared": {"GPU-2::0"},
+				"nvidia.com/mig-1g.5gb": {"MIG-0"},
+			},
+		},
+		{
+			description: "custom unit",
+			config:      &spec.MemoryResource{Name: "nvidia.com/memory", Unit: &twoGiB},
+			expectedIDs: map[spec.ResourceName][]string{
+				"nvidia.com/memory":     {"GPU-0::0", "GPU-0::1", "GPU-1::0"},
+				"nvidia.com/gpu.shared": {"GPU-2::0"},
+				"nvidia.com/mig-1g.5gb": {"MIG-0"},
+			},
+		},
+		{
+			description: "unselected resources are kept",
+			config:      &spec.MemoryResource{Resources: []spec.ResourceName{"nvidia.com/other"}},
+			expectedIDs: map[spec.ResourceName][]string{
+				"nvidia.com/gpu":        {"GPU-0", "GPU-1"},
+				"nvidia.com/gpu.shared": {"GPU-2::0"},
+				"nvidia.com/mig-1g.5gb": {"MIG-0"},
+			},
+		},
+		{
+			description:   "memory resource name conflicts with a GPU resource",
+			config:        &spec.MemoryResource{Name: "nvidia.com/gpu"},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			name := spec.ResourceName("")
+			if tc.config != nil {
+				name = tc.config.ResourceName("nvidia.com")
+			}
+			deviceMap, err := updateDeviceMapWithMemoryUnits(tc.config, name, newDeviceMap())
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			ids := make(map[spec.ResourceName][]string)
+			for name, devices := range deviceMap {
+				ids[name] = devices.GetIDs()
+			}
+			require.Equal(t, tc.expectedIDs, ids)
+
+			for _, d := range deviceMap[name] {
+				require.True(t, d.MemoryUnit)
+				require.Equal(t, tc.config.UnitBytes(), d.ReplicaMemory)
+			}
+		})
+	}
+}
+
+func TestMemoryUnitAlloc(t *testing.T) {
+	devices := make(Devices)
+	for _, gpu := range []struct {
+		uuid  string
+		index string
+		units int
+	}{
+		{"GPU-0", "0", 4},
+		{"GPU-1", "1", 4},
+	} {
+		for i := 0; i < gpu.units; i++ {
+			id := string(NewAnnotatedID(gpu.uuid, i))
+			devices[id] = &Device{Device: pluginapi.Device{ID: id}, Index: gpu.index, Replicas: gpu.units, MemoryUnit: true}
+		}
+	}
+
+	testCases := []struct {
+		description   string
+		available     []string
+		required      []string
+		size          int
+		expected      []string
+		expectedError bool
+	}{
+		{
+			description: "the GPU with the fewest available units that fit is used",
+			available:   []string{"GPU-0::0", "GPU-0::1", "GPU-0::2", "GPU-0::3", "GPU-1::2", "GPU-1::3"},
+			size:        2,
+			expected:    []string{"GPU-1::2", "GPU-1::3"},
+		},
+		{
+			description: "larger requests fall back to GPUs with more available units",
+			available:   []string{"GPU-0::0", "GPU-0::1", "GPU-0::2", "GPU-0::3", "GPU-1::2", "GPU-1::3"},
+			size:        3,
+			expected:    []string{"GPU-0::0", "GPU-0::1", "GPU-0::2"},
+		},
+		{
+			description: "required units select the GPU",
+			available:   []string{"GPU-0::0", "GPU-0::1", "GPU-0::2", "GPU-0::3", "GPU-1::2", "GPU-1::3"},
+			required:    []string{"GPU-0::3"},
+			size:        2,
+			expected:    []string{"GPU-0::3", "GPU-0::0"},
+		},
+		{
+			description:   "no single GPU has enough units",
+			available:     []string{"GPU-0::0", "GPU-0::1", "GPU-1::2", "GPU-1::3"},
+			size:          3,
+			expectedError: true,
+		},
+		{
+			description:   "required units span GPUs",
+			available:     []string{"GPU-0::0", "GPU-0::1", "GPU-1::2", "GPU-1::3"},
+			required:      []string{"GPU-0::0", "GPU-1::2"},
+			size:          2,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			allocated, err := memoryUnitAlloc(devices, tc.available, tc.required, tc.size)
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, allocated)
+		})
+	}
+}
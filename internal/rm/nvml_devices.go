@@ -147,15 +147,30 @@ func (d nvmlMigDevice) GetPaths() ([]string, error) {
 	return devicePaths, nil
 }
 
-// GetNumaNode returns the NUMA node associated with the GPU device
-func (d nvmlDevice) GetNumaNode() (bool, int, error) {
+// GetPciBusID returns the PCI bus ID of the GPU device in the form used by
+// sysfs, e.g. 0000:07:00.0.
+func (d nvmlDevice) GetPciBusID() (string, error) {
 	info, ret := d.GetPciInfo()
 	if ret != nvml.SUCCESS {
-		return false, 0, fmt.Errorf("error getting PCI Bus Info of device: %v", ret)
+		return "", fmt.Errorf("error getting PCI Bus Info of device: %v", ret)
 	}
 
 	// Discard leading zeros.
-	busID := strings.ToLower(strings.TrimPrefix(uint8Slice(info.BusId[:]).String(), "0000"))
+	return strings.ToLower(strings.TrimPrefix(uint8Slice(info.BusId[:]).String(), "0000")), nil
+}
+
+// GetPciBusID returns an empty PCI bus ID since MIG devices cannot be
+// addressed by the bus ID of their parent.
+func (d nvmlMigDevice) GetPciBusID() (string, error) {
+	return "", nil
+}
+
+// GetNumaNode returns the NUMA node associated with the GPU device
+func (d nvmlDevice) GetNumaNode() (bool, int, error) {
+	busID, err := d.GetPciBusID()
+	if err != nil {
+		return false, 0, err
+	}
 
 	b, err := os.ReadFile(fmt.Sprintf("/sys/bus/pci/devices/%s/numa_node", busID))
 	if err != nil {
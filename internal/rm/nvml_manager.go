@@ -30,7 +30,8 @@ import (
 
 type nvmlResourceManager struct {
 	resourceManager
-	nvml nvml.Interface
+	nvml          nvml.Interface
+	linkedDevices *linkedDevices
 }
 
 var _ ResourceManager = (*nvmlResourceManager)(nil)
@@ -66,6 +67,21 @@ func NewNVMLResourceManagers(infolib info.Interface, nvmllib nvml.Interface, dev
 			},
 			nvml: nvmllib,
 		}
+		r.linkedDevices = newLinkedDevices(devices.GetUUIDs(), r.newLinkedDevices)
+		policy, err := newAllocationPolicy(
+			config.AllocationPolicy.ForResource(resourceName),
+			r.alignedAlloc,
+			config.AllocationPolicy.ReplicaAlignmentEnabled() && (!config.Sharing.ReplicatedResources().FailRequestsGreaterThanOne || multiGPUReplicasAllowed(config)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error creating allocation policy for %v: %w", resourceName, err)
+		}
+		r.allocationPolicy = policy
+		if devices.AlignedAllocationSupported() {
+			if _, err := r.linkedDevices.get(); err != nil {
+				klog.Warningf("Failed to get device link information for %v: %v", resourceName, err)
+			}
+		}
 		rms = append(rms, r)
 	}
 
@@ -73,7 +89,7 @@ func NewNVMLResourceManagers(infolib info.Interface, nvmllib nvml.Interface, dev
 }
 
 // GetPreferredAllocation runs an allocation algorithm over the inputs.
-// The algorithm chosen is based both on the incoming set of available devices and various config settings.
+// The algorithm chosen is based on the allocation policy configured for the resource.
 func (r *nvmlResourceManager) GetPreferredAllocation(available, required []string, size int) ([]string, error) {
 	return r.getPreferredAllocation(available, required, size)
 }
@@ -95,27 +111,20 @@ func (r *nvmlResourceManager) CheckHealth(stop <-chan interface{}, unhealthy cha
 	return r.checkHealth(stop, r.devices, unhealthy)
 }
 
-// getPreferredAllocation runs an allocation algorithm over the inputs.
-// The algorithm chosen is based both on the incoming set of available devices and various config settings.
-func (r *nvmlResourceManager) getPreferredAllocation(available, required []string, size int) ([]string, error) {
-	// If all of the available devices are full GPUs without replicas, then
-	// calculate an aligned allocation across those devices.
-	if r.Devices().AlignedAllocationSupported() && !AnnotatedIDs(available).AnyHasAnnotations() {
-		return r.alignedAlloc(available, required, size)
-	}
-
-	// Otherwise, distribute them evenly across all replicated GPUs
-	return r.distributedAlloc(available, required, size)
+// newLinkedDevices enumerates all devices and the links between them.
+func (r *nvmlResourceManager) newLinkedDevices() (gpuallocator.DeviceList, error) {
+	return gpuallocator.NewDevices(
+		gpuallocator.WithNvmlLib(r.nvml),
+	)
 }
 
 // alignedAlloc shells out to the alignedAllocationPolicy that is set in
-// order to calculate the preferred allocation.
+// order to calculate the preferred allocation. The links between the devices
+// of the resource manager are only enumerated once.
 func (r *nvmlResourceManager) alignedAlloc(available, required []string, size int) ([]string, error) {
 	var devices []string
 
-	linkedDevices, err := gpuallocator.NewDevices(
-		gpuallocator.WithNvmlLib(r.nvml),
-	)
+	linkedDevices, err := r.linkedDevices.get()
 	if err != nil {
 		return nil, fmt.Errorf("unable to get device link information: %w", err)
 	}
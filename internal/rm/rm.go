@@ -34,6 +34,8 @@ type resourceManager struct {
 	config   *spec.Config
 	resource spec.ResourceName
 	devices  Devices
+	// allocationPolicy is used to calculate preferred allocations for the resource.
+	allocationPolicy AllocationPolicy
 }
 
 // ResourceManager provides an interface for listing a set of Devices and checking health on them
@@ -60,6 +62,27 @@ func (r *resourceManager) Devices() Devices {
 
 var errInvalidRequest = errors.New("invalid request")
 
+// getPreferredAllocation runs the configured allocation policy over the inputs.
+// If no policy is configured, devices are spread across GPUs. Memory units
+// are always allocated from a single GPU.
+func (r *resourceManager) getPreferredAllocation(available, required []string, size int) ([]string, error) {
+	if r.devices.anyMemoryUnits() {
+		return memoryUnitAlloc(r.devices, available, required, size)
+	}
+	if multiGPUReplicasAllowed(r.config) && AnnotatedIDs(available).AnyHasAnnotations() {
+		return distinctGPUAlloc(r.allocate, available, required, size)
+	}
+	return r.allocate(available, required, size)
+}
+
+// allocate runs the allocation policy of the resource over the inputs.
+func (r *resourceManager) allocate(available, required []string, size int) ([]string, error) {
+	if r.allocationPolicy == nil {
+		return distributedAlloc(r.devices, available, required, size)
+	}
+	return r.allocationPolicy.Allocate(r.devices, available, required, size)
+}
+
 // ValidateRequest checks the requested IDs against the resource manager configuration.
 // It asserts that all requested IDs are known to the resource manager and that the request is
 // valid for a specified sharing configuration.
@@ -71,6 +94,15 @@ func (r *resourceManager) ValidateRequest(ids AnnotatedIDs) error {
 		}
 	}
 
+	// Memory units may be requested in any number, but must all be
+	// allocated from a single GPU.
+	if r.devices.Subset(ids).anyMemoryUnits() {
+		if uuids := r.devices.Subset(ids).GetUUIDs(); len(uuids) > 1 {
+			return fmt.Errorf("%w: memory units must be allocated from a single GPU; found %d", errInvalidRequest, len(uuids))
+		}
+		return nil
+	}
+
 	// If the devices being allocated are replicas, then (conditionally)
 	// error out if more than one resource is being allocated.
 	includesReplicas := ids.AnyHasAnnotations()
@@ -85,16 +117,34 @@ func (r *resourceManager) ValidateRequest(ids AnnotatedIDs) error {
 		// value in the sharing settings.
 		// This setting was added to timeslicing after the initial release and
 		// is set to `false` to maintain backward compatibility with existing
-		// deployments. If we do extend MPS to allow multiple devices to be
-		// requested, the MPS API will be extended separately from the
-		// time-slicing API.
-		if includesReplicas && numRequestedDevices > 1 {
+		// deployments. Requests for multiple replicas are instead allowed
+		// with the separate AllowMultiGPURequests setting if each replica is
+		// on a distinct GPU.
+		if !includesReplicas || numRequestedDevices == 1 {
+			break
+		}
+		if !multiGPUReplicasAllowed(r.config) {
 			return fmt.Errorf("%w: maximum request size for shared resources is 1; found %d", errInvalidRequest, numRequestedDevices)
 		}
+		// Each MIG device is controlled by an MPS daemon of its own, meaning
+		// that replicas of different MIG devices cannot be served together.
+		requested := r.devices.Subset(ids)
+		if requested.anyMigDevices() {
+			return fmt.Errorf("%w: maximum request size for shared MIG devices is 1; found %d", errInvalidRequest, numRequestedDevices)
+		}
+		if uuids := requested.GetUUIDs(); len(uuids) != numRequestedDevices {
+			return fmt.Errorf("%w: replicas of shared resources must be on distinct GPUs; found %d replicas on %d GPUs", errInvalidRequest, numRequestedDevices, len(uuids))
+		}
 	}
 	return nil
 }
 
+// multiGPUReplicasAllowed checks whether requests for multiple replicas on
+// distinct GPUs are allowed by the sharing configuration.
+func multiGPUReplicasAllowed(config *spec.Config) bool {
+	return config.Sharing.SharingStrategy() == spec.SharingStrategyMPS && config.Sharing.MPS.AllowMultiGPURequests
+}
+
 // AddDefaultResourcesToConfig adds default resource matching rules to config.Resources
 func AddDefaultResourcesToConfig(infolib info.Interface, nvmllib nvml.Interface, devicelib device.Interface, config *spec.Config) error {
 	klog.Infof("DEBUG: AddDefaultResourcesToConfig called, config.Resources pointer: %p", &config.Resources)
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	spec "github.com/NVIDIA/k8s-device-plugin/api/config/v1"
 )
@@ -175,6 +176,93 @@ func TestValidateRequest(t *testing.T) {
 			requestDevicesIDs: []string{"device0::1", "device1::0"},
 			expectedError:     errInvalidRequest,
 		},
+		{
+			description: "MPS with two devices on distinct GPUs -- allowMultiGPURequests",
+			sharing: spec.Sharing{
+				MPS: &spec.ReplicatedResources{
+					FailRequestsGreaterThanOne: true,
+					AllowMultiGPURequests:      true,
+					Resources: []spec.ReplicatedResource{
+						{
+							Name:     "nvidia.com/gpu",
+							Replicas: 2,
+						},
+					},
+				},
+			},
+			devices: Devices{
+				"device0::0": {Device: pluginapi.Device{ID: "device0::0"}, Index: "0"},
+				"device0::1": {Device: pluginapi.Device{ID: "device0::1"}, Index: "0"},
+				"device1::0": {Device: pluginapi.Device{ID: "device1::0"}, Index: "1"},
+				"device1::1": {Device: pluginapi.Device{ID: "device1::1"}, Index: "1"},
+			},
+			requestDevicesIDs: []string{"device0::1", "device1::0"},
+		},
+		{
+			description: "MPS with two devices on the same GPU -- allowMultiGPURequests",
+			sharing: spec.Sharing{
+				MPS: &spec.ReplicatedResources{
+					FailRequestsGreaterThanOne: true,
+					AllowMultiGPURequests:      true,
+					Resources: []spec.ReplicatedResource{
+						{
+							Name:     "nvidia.com/gpu",
+							Replicas: 2,
+						},
+					},
+				},
+			},
+			devices: Devices{
+				"device0::0": {Device: pluginapi.Device{ID: "device0::0"}, Index: "0"},
+				"device0::1": {Device: pluginapi.Device{ID: "device0::1"}, Index: "0"},
+				"device1::0": {Device: pluginapi.Device{ID: "device1::0"}, Index: "1"},
+				"device1::1": {Device: pluginapi.Device{ID: "device1::1"}, Index: "1"},
+			},
+			requestDevicesIDs: []string{"device0::0", "device0::1"},
+			expectedError:     errInvalidRequest,
+		},
+		{
+			description: "MPS with two MIG devices -- allowMultiGPURequests",
+			sharing: spec.Sharing{
+				MPS: &spec.ReplicatedResources{
+					FailRequestsGreaterThanOne: true,
+					AllowMultiGPURequests:      true,
+					Resources: []spec.ReplicatedResource{
+						{
+							Name:     "nvidia.com/mig-1g.5gb",
+							Replicas: 2,
+						},
+					},
+				},
+			},
+			devices: Devices{
+				"MIG-0::0": {Device: pluginapi.Device{ID: "MIG-0::0"}, Index: "0:0"},
+				"MIG-0::1": {Device: pluginapi.Device{ID: "MIG-0::1"}, Index: "0:0"},
+				"MIG-1::0": {Device: pluginapi.Device{ID: "MIG-1::0"}, Index: "1:0"},
+				"MIG-1::1": {Device: pluginapi.Device{ID: "MIG-1::1"}, Index: "1:0"},
+			},
+			requestDevicesIDs: []string{"MIG-0::0", "MIG-1::0"},
+			expectedError:     errInvalidRequest,
+		},
+		{
+			description: "memory units from a single GPU",
+			devices: Devices{
+				"device0::0": {Device: pluginapi.Device{ID: "device0::0"}, MemoryUnit: true, Index: "0"},
+				"device0::1": {Device: pluginapi.Device{ID: "device0::1"}, MemoryUnit: true, Index: "0"},
+				"device1::0": {Device: pluginapi.Device{ID: "device1::0"}, MemoryUnit: true, Index: "1"},
+			},
+			requestDevicesIDs: []string{"device0::0", "device0::1"},
+		},
+		{
+			description: "memory units from two GPUs",
+			devices: Devices{
+				"device0::0": {Device: pluginapi.Device{ID: "device0::0"}, MemoryUnit: true, Index: "0"},
+				"device0::1": {Device: pluginapi.Device{ID: "device0::1"}, MemoryUnit: true, Index: "0"},
+				"device1::0": {Device: pluginapi.Device{ID: "device1::0"}, MemoryUnit: true, Index: "1"},
+			},
+			requestDevicesIDs: []string{"device0::1", "device1::0"},
+			expectedError:     errInvalidRequest,
+		},
 	}
 
 	for _, tc := range testCases {
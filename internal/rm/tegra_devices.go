@@ -34,9 +34,14 @@ func buildTegraDeviceMap(config *spec.Config) (DeviceMap, error) {
 	name := tegraDeviceName
 	i := 0
 	for _, resource := range config.Resources.GPUs {
-		if resource.Pattern.Matches(name) {
+		attrs := spec.DeviceAttributes{Name: name, Product: name}
+		if resource.Matches(attrs) {
+			resourceName, err := resource.Name.Evaluate(attrs)
+			if err != nil {
+				return nil, err
+			}
 			index := fmt.Sprintf("%d", i)
-			err := devices.setEntry(resource.Name, index, &tegraDevice{})
+			err = devices.setEntry(resourceName, index, &tegraDevice{})
 			if err != nil {
 				return nil, err
 			}
@@ -73,6 +78,11 @@ func (d *tegraDevice) GetTotalMemory() (uint64, error) {
 	return 0, nil
 }
 
+// GetPciBusID returns an empty PCI bus ID since a Tegra device is not a PCI device.
+func (d *tegraDevice) GetPciBusID() (string, error) {
+	return "", nil
+}
+
 // GetComputeCapability is unimplemented for a Tegra device.
 func (d *tegraDevice) GetComputeCapability() (string, error) {
 	return "0.0", nil
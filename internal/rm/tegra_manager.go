@@ -45,11 +45,16 @@ func NewTegraResourceManagers(config *spec.Config) ([]ResourceManager, error) {
 		if len(devices) == 0 {
 			continue
 		}
+		policy, err := NewAllocationPolicy(config.AllocationPolicy.ForResource(resourceName))
+		if err != nil {
+			return nil, fmt.Errorf("error creating allocation policy for %v: %w", resourceName, err)
+		}
 		r := &tegraResourceManager{
 			resourceManager: resourceManager{
-				config:   config,
-				resource: resourceName,
-				devices:  devices,
+				config:           config,
+				resource:         resourceName,
+				devices:          devices,
+				allocationPolicy: policy,
 			},
 		}
 		if len(devices) != 0 {
@@ -60,9 +65,9 @@ func NewTegraResourceManagers(config *spec.Config) ([]ResourceManager, error) {
 	return rms, nil
 }
 
-// GetPreferredAllocation returns a standard allocation for the Tegra resource manager.
+// GetPreferredAllocation runs the configured allocation policy for the Tegra resource manager.
 func (r *tegraResourceManager) GetPreferredAllocation(available, required []string, size int) ([]string, error) {
-	return r.distributedAlloc(available, required, size)
+	return r.getPreferredAllocation(available, required, size)
 }
 
 // GetDevicePaths returns an empty slice for the tegraResourceManager
@@ -0,0 +1,66 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/NVIDIA/go-gpuallocator/gpuallocator"
+)
+
+// linkedDevices caches the list of devices of a resource manager along with
+// their NVLink and P2P links as required for an aligned allocation.
+// Enumerating the links between all pairs of devices through NVML is
+// expensive. Since the devices of a resource manager are fixed for its
+// lifetime, the list is only built once and is discarded along with the
+// resource manager when the resource managers are rebuilt.
+type linkedDevices struct {
+	sync.Mutex
+	uuids   []string
+	build   func() (gpuallocator.DeviceList, error)
+	devices gpuallocator.DeviceList
+}
+
+// newLinkedDevices creates a cache of the linked devices with the specified
+// UUIDs that uses the specified function to construct the list of all
+// devices.
+func newLinkedDevices(uuids []string, build func() (gpuallocator.DeviceList, error)) *linkedDevices {
+	return &linkedDevices{uuids: uuids, build: build}
+}
+
+// get returns the cached list of linked devices. The list is built on the
+// first call and if building it failed before.
+func (l *linkedDevices) get() (gpuallocator.DeviceList, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.devices != nil {
+		return l.devices, nil
+	}
+
+	all, err := l.build()
+	if err != nil {
+		return nil, err
+	}
+	devices, err := all.Filter(l.uuids)
+	if err != nil {
+		return nil, fmt.Errorf("error filtering linked devices: %w", err)
+	}
+	l.devices = devices
+	return l.devices, nil
+}
@@ -0,0 +1,115 @@
+/**
+# Copyright (c) NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+**/
+
+package rm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/NVIDIA/go-gpuallocator/gpuallocator"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/stretchr/testify/require"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// countingNVML is an NVML library that only counts how often it is
+// initialized. Any other call panics.
+type countingNVML struct {
+	nvml.Interface
+	inits int
+}
+
+func (n *countingNVML) Init() nvml.Return {
+	n.inits++
+	return nvml.ERROR_UNINITIALIZED
+}
+
+// newDeviceList returns a list of linked devices with the specified UUIDs.
+func newDeviceList(uuids ...string) gpuallocator.DeviceList {
+	var devices gpuallocator.DeviceList
+	for i, uuid := range uuids {
+		d := &gpuallocator.Device{Index: i, Links: make(map[int][]gpuallocator.P2PLink)}
+		d.UUID = uuid
+		devices = append(devices, d)
+	}
+	return devices
+}
+
+func TestLinkedDevicesCache(t *testing.T) {
+	var builds int
+	var buildErr error
+	all := newDeviceList("GPU-0", "GPU-1", "GPU-2")
+	l := newLinkedDevices([]string{"GPU-0", "GPU-2"}, func() (gpuallocator.DeviceList, error) {
+		builds++
+		if buildErr != nil {
+			return nil, buildErr
+		}
+		return all, nil
+	})
+
+	// A failed build is retried on the next call.
+	buildErr = errors.New("failed")
+	_, err := l.get()
+	require.Error(t, err)
+	buildErr = nil
+
+	for i := 0; i < 3; i++ {
+		d, err := l.get()
+		require.NoError(t, err)
+		require.Equal(t, gpuallocator.DeviceList{all[0], all[2]}, d)
+	}
+	require.Equal(t, 2, builds)
+}
+
+func TestAlignedAllocUsesCachedDevices(t *testing.T) {
+	nvmllib := &countingNVML{}
+	devices := make(Devices)
+	var uuids []string
+	for i := 0; i < 4; i++ {
+		uuid := fmt.Sprintf("GPU-%d", i)
+		uuids = append(uuids, uuid)
+		devices[uuid] = &Device{
+			Device: pluginapi.Device{ID: uuid},
+			Index:  fmt.Sprintf("%d", i),
+		}
+	}
+	all := newDeviceList(uuids...)
+
+	r := &nvmlResourceManager{
+		resourceManager: resourceManager{devices: devices},
+		nvml:            nvmllib,
+	}
+	r.linkedDevices = newLinkedDevices(devices.GetUUIDs(), func() (gpuallocator.DeviceList, error) {
+		return all, nil
+	})
+
+	available := []string{"GPU-0", "GPU-1", "GPU-3"}
+	availableDevices, err := all.Filter(available)
+	require.NoError(t, err)
+	var expected []string
+	for _, d := range gpuallocator.NewBestEffortPolicy().Allocate(availableDevices, nil, 2) {
+		expected = append(expected, d.UUID)
+	}
+
+	for i := 0; i < 3; i++ {
+		allocated, err := r.alignedAlloc(available, nil, 2)
+		require.NoError(t, err)
+		require.Equal(t, expected, allocated)
+	}
+	require.Equal(t, 0, nvmllib.inits)
+}
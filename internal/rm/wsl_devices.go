@@ -44,3 +44,8 @@ func (d wslDevice) GetTotalMemory() (uint64, error) {
 func (d wslDevice) GetComputeCapability() (string, error) {
 	return nvmlDevice(d).GetComputeCapability()
 }
+
+// GetPciBusID returns the PCI bus ID of the device.
+func (d wslDevice) GetPciBusID() (string, error) {
+	return nvmlDevice(d).GetPciBusID()
+}
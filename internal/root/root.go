@@ -14,7 +14,9 @@
 # limitations under the License.
 **/
 
-package main
+// Package root provides helpers for locating files, such as the NVML library and
+// device nodes, in a driver root.
+package root
 
 import (
 	"fmt"
@@ -22,15 +24,17 @@ import (
 	"path/filepath"
 )
 
-type root string
+// Root represents the path to a driver installation, e.g. the path at which the
+// driver root is mounted in a container.
+type Root string
 
-func (r root) join(parts ...string) string {
+func (r Root) join(parts ...string) string {
 	return filepath.Join(append([]string{string(r)}, parts...)...)
 }
 
-// getDevRoot returns the dev root associated with the root.
+// GetDevRoot returns the dev root associated with the root.
 // If the root is not a dev root, this defaults to "/".
-func (r root) getDevRoot() string {
+func (r Root) GetDevRoot() string {
 	if r.isDevRoot() {
 		return string(r)
 	}
@@ -39,7 +43,7 @@ func (r root) getDevRoot() string {
 
 // isDevRoot checks whether the specified root is a dev root.
 // A dev root is defined as a root containing a /dev folder.
-func (r root) isDevRoot() bool {
+func (r Root) isDevRoot() bool {
 	stat, err := os.Stat(filepath.Join(string(r), "dev"))
 	if err != nil {
 		return false
@@ -47,7 +51,10 @@ func (r root) isDevRoot() bool {
 	return stat.IsDir()
 }
 
-func (r root) tryResolveLibrary(libraryName string) string {
+// TryResolveLibrary returns the resolved path of the specified library in the
+// root. The library name is returned unchanged if the root is "/" or if the
+// library is not found in the root.
+func (r Root) TryResolveLibrary(libraryName string) string {
 	if r == "" || r == "/" {
 		return libraryName
 	}